@@ -1,12 +1,15 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/somaz94/env-output-setter/internal/config"
 	"github.com/somaz94/env-output-setter/internal/printer"
+	"github.com/somaz94/env-output-setter/internal/summary"
 	"github.com/somaz94/env-output-setter/internal/writer"
 )
 
@@ -14,6 +17,8 @@ import (
 const (
 	outputSetEnvCount    = "set_env_count"
 	outputSetOutputCount = "set_output_count"
+	outputSetPathCount   = "set_path_count"
+	outputSetStateCount  = "set_state_count"
 	outputActionStatus   = "action_status"
 	outputErrorMessage   = "error_message"
 	statusSuccess        = "success"
@@ -31,22 +36,57 @@ func run() int {
 
 	logAdvancedFeatures(cfg)
 
+	// Collect what was set for the job summary if requested
+	var report *summary.Report
+	if cfg.StepSummary {
+		report = summary.NewReport()
+	}
+
+	// Collect the env and output values written if they are exported
+	var exported *writer.ExportValues
+	if strings.TrimSpace(cfg.ExportFile) != "" {
+		exported = writer.NewExportValues()
+	}
+
+	// Reject invalid configuration before anything is processed
+	step, err := writer.CheckConfig(cfg)
+	if err != nil {
+		return fail(cfg, report, "Invalid configuration", err, 0, 0, 0, 0)
+	}
+	step.Record(report, exported)
+
+	// Check cross-key expressions against every value the step will set
+	if err := step.CheckExpressions(); err != nil {
+		return fail(cfg, report, "Error validating expressions", err, 0, 0, 0, 0)
+	}
+
 	// Set environment variables
-	envCount, err := writer.SetEnv(cfg)
+	envCount, err := step.SetEnv()
 	if err != nil {
-		errorMsg := fmt.Sprintf("Error setting environment variables: %v", err)
-		printer.PrintError(errorMsg)
-		writeOutputs(0, 0, statusFailure, errorMsg)
-		return 1
+		return fail(cfg, report, "Error setting environment variables", err, 0, 0, 0, 0)
 	}
 
 	// Set output variables
-	outputCount, err := writer.SetOutput(cfg)
+	outputCount, err := step.SetOutput()
 	if err != nil {
-		errorMsg := fmt.Sprintf("Error setting output variables: %v", err)
-		printer.PrintError(errorMsg)
-		writeOutputs(envCount, outputCount, statusFailure, errorMsg)
-		return 1
+		return fail(cfg, report, "Error setting output variables", err, envCount, outputCount, 0, 0)
+	}
+
+	// Write the final env and output values to export_file
+	if _, err := step.ExportFile(); err != nil {
+		return fail(cfg, report, "Error writing export file", err, envCount, outputCount, 0, 0)
+	}
+
+	// Add PATH entries
+	pathCount, err := step.SetPath()
+	if err != nil {
+		return fail(cfg, report, "Error setting PATH entries", err, envCount, outputCount, 0, 0)
+	}
+
+	// Save state for post-step actions
+	stateCount, err := step.SetState()
+	if err != nil {
+		return fail(cfg, report, "Error setting state values", err, envCount, outputCount, pathCount, 0)
 	}
 
 	// Print final status
@@ -57,7 +97,8 @@ func run() int {
 		printer.PrintInfo("Mode: GitHub Actions")
 	}
 
-	writeOutputs(envCount, outputCount, statusSuccess, "")
+	writeOutputs(envCount, outputCount, pathCount, stateCount, statusSuccess, "")
+	writeStepSummary(cfg, report, "")
 	return 0
 }
 
@@ -76,12 +117,51 @@ func logAdvancedFeatures(cfg *config.Config) {
 	if cfg.ExportAsEnv {
 		printer.PrintInfo("  * Export Output as Env: Enabled")
 	}
+	if cfg.StepSummary {
+		printer.PrintInfo("  * Step Summary: Enabled")
+	}
+	if cfg.ExportFile != "" {
+		printer.PrintInfo(fmt.Sprintf("  * Export File: %s (%s)", cfg.ExportFile, cfg.ExportFormat))
+	}
+}
+
+// writeStepSummary appends the report to $GITHUB_STEP_SUMMARY when step_summary
+// is enabled. errorMsg, if set, is rendered as a failure section. Like
+// writeOutputs, failures are reported to stderr and never change the exit code.
+func writeStepSummary(cfg *config.Config, report *summary.Report, errorMsg string) {
+	if report == nil || cfg.GithubStepSummary == "" {
+		return
+	}
+	if err := report.Append(cfg.GithubStepSummary, errorMsg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing to GITHUB_STEP_SUMMARY: %v\n", err)
+	}
+}
+
+// fail reports an error of the step named by context: it prints the error,
+// writes the failure outputs and step summary, and returns the exit code.
+func fail(cfg *config.Config, report *summary.Report, context string, err error, envCount, outputCount, pathCount, stateCount int) int {
+	errorMsg := fmt.Sprintf("%s: %v", context, err)
+	printer.PrintError(errorMsg)
+	writeOutputs(envCount, outputCount, pathCount, stateCount, statusFailure, errorOutput(context, err, errorMsg))
+	writeStepSummary(cfg, report, errorMsg)
+	return 1
+}
+
+// errorOutput returns the error_message output for err. Collected validation
+// errors are rendered as JSON so later steps can parse them; any other error
+// uses errorMsg as is.
+func errorOutput(context string, err error, errorMsg string) string {
+	var collected *writer.ValidationErrors
+	if errors.As(err, &collected) {
+		return collected.JSON(context)
+	}
+	return errorMsg
 }
 
 // writeOutputs writes action result outputs to the GITHUB_OUTPUT file.
 // Failures are reported to stderr (not stdout) so they do not pollute the
 // action's regular log stream.
-func writeOutputs(envCount, outputCount int, status, errorMsg string) {
+func writeOutputs(envCount, outputCount, pathCount, stateCount int, status, errorMsg string) {
 	outputFile := os.Getenv(config.GithubOutputVar)
 	if outputFile == "" {
 		return
@@ -90,6 +170,8 @@ func writeOutputs(envCount, outputCount int, status, errorMsg string) {
 	outputs := map[string]string{
 		outputSetEnvCount:    strconv.Itoa(envCount),
 		outputSetOutputCount: strconv.Itoa(outputCount),
+		outputSetPathCount:   strconv.Itoa(pathCount),
+		outputSetStateCount:  strconv.Itoa(stateCount),
 		outputActionStatus:   status,
 		outputErrorMessage:   errorMsg,
 	}
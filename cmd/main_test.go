@@ -62,7 +62,7 @@ func TestWriteOutputs(t *testing.T) {
 		tmpFile := filepath.Join(t.TempDir(), "github_output")
 		t.Setenv("GITHUB_OUTPUT", tmpFile)
 
-		writeOutputs(3, 2, "success", "")
+		writeOutputs(3, 2, 4, 1, "success", "")
 
 		data, err := os.ReadFile(tmpFile)
 		if err != nil {
@@ -76,6 +76,12 @@ func TestWriteOutputs(t *testing.T) {
 		if !strings.Contains(content, "set_output_count=2") {
 			t.Errorf("expected 'set_output_count=2' in output, got %q", content)
 		}
+		if !strings.Contains(content, "set_path_count=4") {
+			t.Errorf("expected 'set_path_count=4' in output, got %q", content)
+		}
+		if !strings.Contains(content, "set_state_count=1") {
+			t.Errorf("expected 'set_state_count=1' in output, got %q", content)
+		}
 		if !strings.Contains(content, "action_status=success") {
 			t.Errorf("expected 'action_status=success' in output, got %q", content)
 		}
@@ -85,14 +91,14 @@ func TestWriteOutputs(t *testing.T) {
 		t.Setenv("GITHUB_OUTPUT", "")
 
 		// Should not panic or error
-		writeOutputs(1, 1, "success", "")
+		writeOutputs(1, 1, 0, 0, "success", "")
 	})
 
 	t.Run("writes failure status with error message", func(t *testing.T) {
 		tmpFile := filepath.Join(t.TempDir(), "github_output")
 		t.Setenv("GITHUB_OUTPUT", tmpFile)
 
-		writeOutputs(0, 0, "failure", "something went wrong")
+		writeOutputs(0, 0, 0, 0, "failure", "something went wrong")
 
 		data, err := os.ReadFile(tmpFile)
 		if err != nil {
@@ -112,7 +118,7 @@ func TestWriteOutputs(t *testing.T) {
 		t.Setenv("GITHUB_OUTPUT", "/nonexistent/dir/output")
 
 		// Should not panic - just prints error
-		writeOutputs(1, 1, "success", "")
+		writeOutputs(1, 1, 0, 0, "success", "")
 	})
 }
 
@@ -187,6 +193,43 @@ func TestRun(t *testing.T) {
 		}
 	})
 
+	t.Run("writes the export file", func(t *testing.T) {
+		exportFile := filepath.Join(t.TempDir(), "config.sh")
+		t.Setenv("GITHUB_ENV", "")
+		t.Setenv("GITHUB_OUTPUT", "")
+		t.Setenv("INPUT_ENV_KEY", "ENV_KEY")
+		t.Setenv("INPUT_ENV_VALUE", "env value")
+		t.Setenv("INPUT_OUTPUT_KEY", "OUT_KEY")
+		t.Setenv("INPUT_OUTPUT_VALUE", "it's")
+		t.Setenv("INPUT_DELIMITER", ",")
+		t.Setenv("INPUT_EXPORT_FILE", exportFile)
+		t.Setenv("INPUT_EXPORT_FORMAT", "shell")
+
+		if exitCode := run(); exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d", exitCode)
+		}
+		data, err := os.ReadFile(exportFile)
+		if err != nil {
+			t.Fatalf("failed to read export file: %v", err)
+		}
+		if want := "export ENV_KEY='env value'\nexport OUT_KEY='it'\\''s'\n"; string(data) != want {
+			t.Errorf("export file = %q, want %q", data, want)
+		}
+	})
+
+	t.Run("returns 1 on invalid export format", func(t *testing.T) {
+		t.Setenv("GITHUB_ENV", "")
+		t.Setenv("GITHUB_OUTPUT", "")
+		t.Setenv("INPUT_ENV_KEY", "KEY")
+		t.Setenv("INPUT_ENV_VALUE", "val")
+		t.Setenv("INPUT_EXPORT_FILE", filepath.Join(t.TempDir(), "config"))
+		t.Setenv("INPUT_EXPORT_FORMAT", "ini")
+
+		if exitCode := run(); exitCode != 1 {
+			t.Errorf("expected exit code 1, got %d", exitCode)
+		}
+	})
+
 	t.Run("returns 1 on env write error", func(t *testing.T) {
 		t.Setenv("GITHUB_ENV", "/nonexistent/path/env")
 		t.Setenv("GITHUB_OUTPUT", "")
@@ -217,4 +260,222 @@ func TestRun(t *testing.T) {
 			t.Errorf("expected exit code 1, got %d", exitCode)
 		}
 	})
+
+	t.Run("writes step summary", func(t *testing.T) {
+		dir := t.TempDir()
+		tmpSummary := filepath.Join(dir, "step_summary")
+		t.Setenv("GITHUB_ENV", filepath.Join(dir, "github_env"))
+		t.Setenv("GITHUB_OUTPUT", filepath.Join(dir, "github_output"))
+		t.Setenv("GITHUB_STEP_SUMMARY", tmpSummary)
+		t.Setenv("INPUT_ENV_KEY", "REGION|CONFIG")
+		t.Setenv("INPUT_ENV_VALUE", `us-east-1|{"port":8080}`)
+		t.Setenv("INPUT_OUTPUT_KEY", "REGION")
+		t.Setenv("INPUT_OUTPUT_VALUE", "us-east-1")
+		t.Setenv("INPUT_DELIMITER", "|")
+		t.Setenv("INPUT_JSON_SUPPORT", "true")
+		t.Setenv("INPUT_TO_UPPER", "true")
+		t.Setenv("INPUT_STEP_SUMMARY", "true")
+
+		if exitCode := run(); exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d", exitCode)
+		}
+
+		data, err := os.ReadFile(tmpSummary)
+		if err != nil {
+			t.Fatalf("failed to read step summary: %v", err)
+		}
+		for _, want := range []string{
+			"| REGION | both | literal | to_upper | US-EAST-1 |",
+			"| CONFIG_port | env | JSON-flattened | - | 8080 |",
+		} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("step summary missing %q:\n%s", want, data)
+			}
+		}
+		if strings.Contains(string(data), "Failed") {
+			t.Errorf("step summary unexpectedly contains a failure section:\n%s", data)
+		}
+	})
+
+	t.Run("writes failure to step summary", func(t *testing.T) {
+		dir := t.TempDir()
+		tmpSummary := filepath.Join(dir, "step_summary")
+		t.Setenv("GITHUB_ENV", filepath.Join(dir, "github_env"))
+		t.Setenv("GITHUB_OUTPUT", filepath.Join(dir, "github_output"))
+		t.Setenv("GITHUB_STEP_SUMMARY", tmpSummary)
+		t.Setenv("INPUT_ENV_KEY", "KEY")
+		t.Setenv("INPUT_ENV_VALUE", "val")
+		t.Setenv("INPUT_OUTPUT_KEY", "OUT1,OUT2")
+		t.Setenv("INPUT_OUTPUT_VALUE", "only_one")
+		t.Setenv("INPUT_DELIMITER", ",")
+		t.Setenv("INPUT_STEP_SUMMARY", "true")
+
+		if exitCode := run(); exitCode != 1 {
+			t.Fatalf("expected exit code 1, got %d", exitCode)
+		}
+
+		data, err := os.ReadFile(tmpSummary)
+		if err != nil {
+			t.Fatalf("failed to read step summary: %v", err)
+		}
+		for _, want := range []string{"| KEY | env | literal | - | val |", "### :x: Failed", "Error setting output variables"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("step summary missing %q:\n%s", want, data)
+			}
+		}
+	})
+
+	t.Run("skips step summary when disabled", func(t *testing.T) {
+		dir := t.TempDir()
+		tmpSummary := filepath.Join(dir, "step_summary")
+		t.Setenv("GITHUB_ENV", "")
+		t.Setenv("GITHUB_OUTPUT", "")
+		t.Setenv("GITHUB_STEP_SUMMARY", tmpSummary)
+		t.Setenv("INPUT_ENV_KEY", "KEY")
+		t.Setenv("INPUT_ENV_VALUE", "val")
+		t.Setenv("INPUT_DELIMITER", ",")
+		t.Setenv("INPUT_STEP_SUMMARY", "false")
+
+		if exitCode := run(); exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d", exitCode)
+		}
+		if _, err := os.Stat(tmpSummary); !os.IsNotExist(err) {
+			t.Errorf("expected no step summary file, stat error = %v", err)
+		}
+	})
+
+	t.Run("writes path entries and state", func(t *testing.T) {
+		dir := t.TempDir()
+		binDir := filepath.Join(dir, "bin")
+		if err := os.Mkdir(binDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		tmpPath := filepath.Join(dir, "github_path")
+		tmpState := filepath.Join(dir, "github_state")
+		tmpOutput := filepath.Join(dir, "github_output")
+		t.Setenv("GITHUB_ENV", "")
+		t.Setenv("GITHUB_OUTPUT", tmpOutput)
+		t.Setenv("GITHUB_PATH", tmpPath)
+		t.Setenv("GITHUB_STATE", tmpState)
+		t.Setenv("INPUT_ENV_KEY", "")
+		t.Setenv("INPUT_ENV_VALUE", "")
+		t.Setenv("INPUT_OUTPUT_KEY", "")
+		t.Setenv("INPUT_OUTPUT_VALUE", "")
+		t.Setenv("INPUT_DELIMITER", ",")
+		t.Setenv("INPUT_PATH_ENTRIES", binDir)
+		t.Setenv("INPUT_PATH_CHECK", "fail")
+		t.Setenv("INPUT_STATE_KEY", "CACHE_KEY")
+		t.Setenv("INPUT_STATE_VALUE", "deps-123")
+
+		if exitCode := run(); exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d", exitCode)
+		}
+
+		pathData, err := os.ReadFile(tmpPath)
+		if err != nil || string(pathData) != binDir+"\n" {
+			t.Errorf("GITHUB_PATH = %q (%v), want %q", pathData, err, binDir+"\n")
+		}
+		stateData, err := os.ReadFile(tmpState)
+		if err != nil || !strings.Contains(string(stateData), "CACHE_KEY") {
+			t.Errorf("GITHUB_STATE = %q (%v), want CACHE_KEY", stateData, err)
+		}
+		outData, _ := os.ReadFile(tmpOutput)
+		for _, want := range []string{"set_path_count=1", "set_state_count=1"} {
+			if !strings.Contains(string(outData), want) {
+				t.Errorf("expected %q in outputs, got %q", want, outData)
+			}
+		}
+	})
+
+	t.Run("returns 1 on missing path entry", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("GITHUB_ENV", "")
+		t.Setenv("GITHUB_OUTPUT", "")
+		t.Setenv("GITHUB_PATH", filepath.Join(dir, "github_path"))
+		t.Setenv("INPUT_ENV_KEY", "")
+		t.Setenv("INPUT_ENV_VALUE", "")
+		t.Setenv("INPUT_DELIMITER", ",")
+		t.Setenv("INPUT_PATH_ENTRIES", filepath.Join(dir, "missing"))
+		t.Setenv("INPUT_PATH_CHECK", "fail")
+
+		if exitCode := run(); exitCode != 1 {
+			t.Errorf("expected exit code 1, got %d", exitCode)
+		}
+	})
+
+	t.Run("writes collected validation errors as JSON", func(t *testing.T) {
+		tmpOutput := filepath.Join(t.TempDir(), "github_output")
+		t.Setenv("GITHUB_ENV", "")
+		t.Setenv("GITHUB_OUTPUT", tmpOutput)
+		t.Setenv("INPUT_ENV_KEY", "PORT,NAME,PORT")
+		t.Setenv("INPUT_ENV_VALUE", "abc,x,1")
+		t.Setenv("INPUT_DELIMITER", ",")
+		t.Setenv("INPUT_VALIDATION_RULES", `{"PORT":{"pattern":"^[0-9]+$"}}`)
+		t.Setenv("INPUT_VALIDATION_MODE", "collect")
+
+		if exitCode := run(); exitCode != 1 {
+			t.Fatalf("expected exit code 1, got %d", exitCode)
+		}
+
+		data, err := os.ReadFile(tmpOutput)
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+		want := `error_message={"message":"Error setting environment variables","count":2,"errors":[`
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in outputs, got %q", want, data)
+		}
+	})
+
+	t.Run("rejects invalid expressions before writing", func(t *testing.T) {
+		dir := t.TempDir()
+		tmpEnv := filepath.Join(dir, "github_env")
+		tmpOutput := filepath.Join(dir, "github_output")
+		t.Setenv("GITHUB_ENV", tmpEnv)
+		t.Setenv("GITHUB_OUTPUT", tmpOutput)
+		t.Setenv("INPUT_ENV_KEY", "DEPLOY_ENV")
+		t.Setenv("INPUT_ENV_VALUE", "prod")
+		t.Setenv("INPUT_VALIDATION_EXPRESSIONS", `DEPLOY_ENV == "prod" &&`)
+
+		if exitCode := run(); exitCode != 1 {
+			t.Fatalf("expected exit code 1, got %d", exitCode)
+		}
+		if _, err := os.Stat(tmpEnv); !os.IsNotExist(err) {
+			t.Errorf("expected no env file to be written, stat error: %v", err)
+		}
+		data, err := os.ReadFile(tmpOutput)
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+		if !strings.Contains(string(data), "error_message=Invalid configuration: validation_expressions[0]: invalid expression") {
+			t.Errorf("expected configuration error in outputs, got %q", data)
+		}
+	})
+
+	t.Run("fails on cross-key expression before writing", func(t *testing.T) {
+		dir := t.TempDir()
+		tmpEnv := filepath.Join(dir, "github_env")
+		tmpOutput := filepath.Join(dir, "github_output")
+		t.Setenv("GITHUB_ENV", tmpEnv)
+		t.Setenv("GITHUB_OUTPUT", tmpOutput)
+		t.Setenv("INPUT_ENV_KEY", "DEPLOY_ENV")
+		t.Setenv("INPUT_ENV_VALUE", "prod")
+		t.Setenv("INPUT_OUTPUT_KEY", "REPLICAS")
+		t.Setenv("INPUT_OUTPUT_VALUE", "2")
+		t.Setenv("INPUT_VALIDATION_EXPRESSIONS", `[{"expr":"DEPLOY_ENV != \"prod\" || REPLICAS >= 3","message":"prod needs 3 replicas"}]`)
+
+		if exitCode := run(); exitCode != 1 {
+			t.Fatalf("expected exit code 1, got %d", exitCode)
+		}
+		if _, err := os.Stat(tmpEnv); !os.IsNotExist(err) {
+			t.Errorf("expected no env file to be written, stat error: %v", err)
+		}
+		data, err := os.ReadFile(tmpOutput)
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+		if !strings.Contains(string(data), "error_message=Error validating expressions: prod needs 3 replicas") {
+			t.Errorf("expected expression failure in outputs, got %q", data)
+		}
+	})
 }
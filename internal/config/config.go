@@ -12,6 +12,9 @@ const (
 	EnvValueInput            = "INPUT_ENV_VALUE"
 	OutputKeyInput           = "INPUT_OUTPUT_KEY"
 	OutputValueInput         = "INPUT_OUTPUT_VALUE"
+	EnvFileInput             = "INPUT_ENV_FILE"
+	EnvPairsInput            = "INPUT_ENV_PAIRS"
+	OutputPairsInput         = "INPUT_OUTPUT_PAIRS"
 	DelimiterInput           = "INPUT_DELIMITER"
 	FailOnEmptyInput         = "INPUT_FAIL_ON_EMPTY"
 	TrimWhitespaceInput      = "INPUT_TRIM_WHITESPACE"
@@ -19,25 +22,60 @@ const (
 	ErrorOnDuplicateInput    = "INPUT_ERROR_ON_DUPLICATE"
 	MaskSecretsInput         = "INPUT_MASK_SECRETS"
 	MaskPatternInput         = "INPUT_MASK_PATTERN"
+	MaskAllInput             = "INPUT_MASK_ALL"
 	ToUpperInput             = "INPUT_TO_UPPER"
 	ToLowerInput             = "INPUT_TO_LOWER"
 	EncodeURLInput           = "INPUT_ENCODE_URL"
 	EscapeNewlinesInput      = "INPUT_ESCAPE_NEWLINES"
 	MaxLengthInput           = "INPUT_MAX_LENGTH"
+	TransformsInput          = "INPUT_TRANSFORMS"
+	GlobalTransformsInput    = "INPUT_GLOBAL_TRANSFORMS"
 	AllowEmptyInput          = "INPUT_ALLOW_EMPTY"
 	DebugModeInput           = "INPUT_DEBUG_MODE"
 	GroupPrefixInput         = "INPUT_GROUP_PREFIX"
 	JsonSupportInput         = "INPUT_JSON_SUPPORT"
+	StructuredFormatInput    = "INPUT_STRUCTURED_FORMAT"
+	JsonKeyOrderInput        = "INPUT_JSON_KEY_ORDER"
+	JsonSeparatorInput       = "INPUT_JSON_SEPARATOR"
+	JsonMaxDepthInput        = "INPUT_JSON_MAX_DEPTH"
+	JsonLeafOnlyInput        = "INPUT_JSON_LEAF_ONLY"
+	JsonArrayLengthInput     = "INPUT_JSON_ARRAY_LENGTH"
+	JsonKeyStyleInput        = "INPUT_JSON_KEY_STYLE"
+	JsonNullValueInput       = "INPUT_JSON_NULL_VALUE"
+	JsonSelectInput          = "INPUT_JSON_SELECT"
+	JsonSchemaInput          = "INPUT_JSON_SCHEMA"
 	ExportAsEnvInput         = "INPUT_EXPORT_AS_ENV"
 	EnableInterpolationInput = "INPUT_ENABLE_INTERPOLATION"
+	EnableTemplatesInput     = "INPUT_ENABLE_TEMPLATES"
+	InterpolateEnvKeysInput  = "INPUT_INTERPOLATE_ENV_KEYS"
+	InterpolationStrictInput = "INPUT_INTERPOLATION_STRICT"
+	InterpolationAllowInput  = "INPUT_INTERPOLATION_ALLOW"
+	InterpolationDenyInput   = "INPUT_INTERPOLATION_DENY"
+	ExportFileInput          = "INPUT_EXPORT_FILE"
+	ExportFormatInput        = "INPUT_EXPORT_FORMAT"
+	ExportFileModeInput      = "INPUT_EXPORT_FILE_MODE"
 	FileEncodingInput        = "INPUT_FILE_ENCODING"
 	ValidationRulesInput     = "INPUT_VALIDATION_RULES"
+	ValidationModeInput      = "INPUT_VALIDATION_MODE"
+	ValidationExprInput      = "INPUT_VALIDATION_EXPRESSIONS"
+	KeyPolicyInput           = "INPUT_KEY_POLICY"
+	KeyDenylistInput         = "INPUT_KEY_DENYLIST"
+	KeyAutofixInput          = "INPUT_KEY_AUTOFIX"
+	StepSummaryInput         = "INPUT_STEP_SUMMARY"
+	PathEntriesInput         = "INPUT_PATH_ENTRIES"
+	PathCheckInput           = "INPUT_PATH_CHECK"
+	SizeLimitPolicyInput     = "INPUT_SIZE_LIMIT_POLICY"
+	StateKeyInput            = "INPUT_STATE_KEY"
+	StateValueInput          = "INPUT_STATE_VALUE"
 )
 
 // GitHub environment variables
 const (
-	GithubEnvVar    = "GITHUB_ENV"
-	GithubOutputVar = "GITHUB_OUTPUT"
+	GithubEnvVar         = "GITHUB_ENV"
+	GithubOutputVar      = "GITHUB_OUTPUT"
+	GithubStepSummaryVar = "GITHUB_STEP_SUMMARY"
+	GithubPathVar        = "GITHUB_PATH"
+	GithubStateVar       = "GITHUB_STATE"
 )
 
 // Default values for configuration parameters
@@ -49,19 +87,48 @@ const (
 	DefaultErrorOnDuplicate    = true
 	DefaultMaskSecrets         = false
 	DefaultMaskPattern         = ""
+	DefaultMaskAll             = false
 	DefaultToUpper             = false
 	DefaultToLower             = false
 	DefaultEncodeURL           = false
 	DefaultEscapeNewlines      = true
 	DefaultMaxLength           = 0
+	DefaultTransforms          = ""
+	DefaultGlobalTransforms    = ""
 	DefaultAllowEmpty          = false
 	DefaultDebugMode           = false
 	DefaultGroupPrefix         = ""
 	DefaultJsonSupport         = false
+	DefaultStructuredFormat    = "json"
+	DefaultJsonKeyOrder        = "source"
+	DefaultJsonSeparator       = "_"
+	DefaultJsonMaxDepth        = 0
+	DefaultJsonLeafOnly        = false
+	DefaultJsonArrayLength     = false
+	DefaultJsonKeyStyle        = "preserve"
+	DefaultJsonNullValue       = "null"
+	DefaultJsonSelect          = ""
+	DefaultJsonSchema          = ""
 	DefaultExportAsEnv         = false
 	DefaultEnableInterpolation = false
+	DefaultEnableTemplates     = false
+	DefaultInterpolateEnvKeys  = false
+	DefaultInterpolationStrict = false
+	DefaultInterpolationAllow  = ""
+	DefaultInterpolationDeny   = ""
+	DefaultExportFile          = ""
+	DefaultExportFormat        = "dotenv"
+	DefaultExportFileMode      = ""
 	DefaultFileEncoding        = "raw"
 	DefaultValidationRules     = ""
+	DefaultValidationMode      = "fail_fast"
+	DefaultValidationExpr      = ""
+	DefaultKeyPolicy           = "relaxed"
+	DefaultKeyDenylist         = ""
+	DefaultKeyAutofix          = false
+	DefaultStepSummary         = false
+	DefaultPathCheck           = "warn"
+	DefaultSizeLimitPolicy     = "fail"
 )
 
 // Config holds the application configuration settings loaded from environment variables.
@@ -73,10 +140,19 @@ type Config struct {
 	EnvValues    string // Environment variable values to process
 	OutputKeys   string // Output keys for GitHub Actions
 	OutputValues string // Output values for GitHub Actions
+	EnvFile      string // Path to a dotenv file merged into the environment variables
+	EnvPairs     string // Environment variables as KEY=value lines
+	OutputPairs  string // Outputs as KEY=value lines
+	PathEntries  string // Directories to prepend to PATH
+	StateKeys    string // Keys saved to GITHUB_STATE for post-step consumption
+	StateValues  string // Values saved to GITHUB_STATE
 
 	// GitHub File Paths
-	GithubEnv    string // Path to GITHUB_ENV file
-	GithubOutput string // Path to GITHUB_OUTPUT file
+	GithubEnv         string // Path to GITHUB_ENV file
+	GithubOutput      string // Path to GITHUB_OUTPUT file
+	GithubStepSummary string // Path to GITHUB_STEP_SUMMARY file
+	GithubPath        string // Path to GITHUB_PATH file
+	GithubState       string // Path to GITHUB_STATE file
 
 	// Input Processing Options
 	Delimiter        string // Delimiter for splitting multiple keys/values
@@ -85,28 +161,57 @@ type Config struct {
 	CaseSensitive    bool   // Whether key comparisons are case sensitive
 	ErrorOnDuplicate bool   // Whether to error on duplicate keys
 	AllowEmpty       bool   // Whether empty values are allowed in the output
+	PathCheck        string // How missing path_entries directories are handled (warn, fail, off)
+	SizeLimitPolicy  string // How values over the runner's size limits are handled (fail, warn, truncate, spill)
 
 	// Value Transformation Options
-	ToUpper        bool // Convert values to uppercase
-	ToLower        bool // Convert values to lowercase
-	EncodeURL      bool // URL-encode values
-	EscapeNewlines bool // Escape newlines in values
-	MaxLength      int  // Maximum length for values (0 = no limit)
+	ToUpper          bool   // Convert values to uppercase
+	ToLower          bool   // Convert values to lowercase
+	EncodeURL        bool   // URL-encode values
+	EscapeNewlines   bool   // Escape newlines in values
+	MaxLength        int    // Maximum length for values (0 = no limit)
+	Transforms       string // Per-key pipelines as "KEY: step | step" lines
+	GlobalTransforms string // Pipeline applied to every value, as "step | step"
 
 	// Security Options
 	MaskSecrets bool   // Whether to mask secret values in logs
 	MaskPattern string // Regex pattern for identifying values to mask
+	MaskAll     bool   // Whether to register every value with the runner's log masker
 
 	// Debug Options
-	DebugMode bool // Enable debug mode for verbose logging
+	DebugMode   bool // Enable debug mode for verbose logging
+	StepSummary bool // Append a report of the keys set to the job summary
 
 	// Advanced Options
 	GroupPrefix         string // Prefix for grouping related outputs
 	JsonSupport         bool   // Support for JSON values
+	StructuredFormat    string // Structured value format to flatten (json, yaml, toml, auto)
+	JsonKeyOrder        string // Order of flattened keys (source, sorted)
+	JsonSeparator       string // Separator between flattened key segments
+	JsonMaxDepth        int    // Maximum flattening depth (0 = no limit)
+	JsonLeafOnly        bool   // Whether to omit the raw JSON of flattened parents
+	JsonArrayLength     bool   // Whether to emit a KEY_length entry for arrays
+	JsonKeyStyle        string // Style of flattened property names (preserve, sanitize, upper_snake, lower_snake)
+	JsonNullValue       string // Representation of null leaves (empty, null, skip)
+	JsonSelect          string // TARGET=SOURCE.path lines selecting fields from structured values
+	JsonSchema          string // JSON object mapping keys to the JSON Schemas their values must match
 	ExportAsEnv         bool   // Whether to export values as environment variables
 	EnableInterpolation bool   // Enable ${VAR:-default} variable interpolation
+	EnableTemplates     bool   // Render values containing {{ }} as Go templates
+	InterpolateEnvKeys  bool   // Let output values refer to the env keys set by the same step
+	InterpolationStrict bool   // Fail on references to unset variables that have no default
+	InterpolationAllow  string // Comma or newline separated globs of the environment variables values may refer to
+	InterpolationDeny   string // Comma or newline separated globs of the environment variables values must not refer to
+	ExportFile          string // Path of a file to write the final env and output values to
+	ExportFormat        string // Format of export_file (dotenv, shell, json, yaml, docker-env, properties)
+	ExportFileMode      string // Octal permissions of export_file, e.g. 0600
 	FileEncoding        string // Encoding for file input values (raw, base64)
 	ValidationRules     string // JSON validation rules for output values
+	ValidationMode      string // Stop at the first validation error or collect them all (fail_fast, collect)
+	ValidationExpr      string // Cross-key expressions that must hold for the final values
+	KeyPolicy           string // Rules key names must follow (posix, github, relaxed)
+	KeyDenylist         string // Comma or newline separated globs of key names that must not be set
+	KeyAutofix          bool   // Whether to rewrite key names that break key_policy instead of failing
 }
 
 // Load creates a new Config instance with values loaded from environment variables.
@@ -118,10 +223,19 @@ func Load() *Config {
 		EnvValues:    os.Getenv(EnvValueInput),
 		OutputKeys:   os.Getenv(OutputKeyInput),
 		OutputValues: os.Getenv(OutputValueInput),
+		EnvFile:      os.Getenv(EnvFileInput),
+		EnvPairs:     os.Getenv(EnvPairsInput),
+		OutputPairs:  os.Getenv(OutputPairsInput),
+		PathEntries:  os.Getenv(PathEntriesInput),
+		StateKeys:    os.Getenv(StateKeyInput),
+		StateValues:  os.Getenv(StateValueInput),
 
 		// GitHub File Paths
-		GithubEnv:    os.Getenv(GithubEnvVar),
-		GithubOutput: os.Getenv(GithubOutputVar),
+		GithubEnv:         os.Getenv(GithubEnvVar),
+		GithubOutput:      os.Getenv(GithubOutputVar),
+		GithubStepSummary: os.Getenv(GithubStepSummaryVar),
+		GithubPath:        os.Getenv(GithubPathVar),
+		GithubState:       os.Getenv(GithubStateVar),
 
 		// Input Processing Options
 		Delimiter:        getEnvWithDefault(DelimiterInput, DefaultDelimiter),
@@ -130,28 +244,57 @@ func Load() *Config {
 		CaseSensitive:    getBoolEnv(CaseSensitiveInput, DefaultCaseSensitive),
 		ErrorOnDuplicate: getBoolEnv(ErrorOnDuplicateInput, DefaultErrorOnDuplicate),
 		AllowEmpty:       getBoolEnv(AllowEmptyInput, DefaultAllowEmpty),
+		PathCheck:        getEnvWithDefault(PathCheckInput, DefaultPathCheck),
+		SizeLimitPolicy:  getEnvWithDefault(SizeLimitPolicyInput, DefaultSizeLimitPolicy),
 
 		// Value Transformation Options
-		ToUpper:        getBoolEnv(ToUpperInput, DefaultToUpper),
-		ToLower:        getBoolEnv(ToLowerInput, DefaultToLower),
-		EncodeURL:      getBoolEnv(EncodeURLInput, DefaultEncodeURL),
-		EscapeNewlines: getBoolEnv(EscapeNewlinesInput, DefaultEscapeNewlines),
-		MaxLength:      getIntEnv(MaxLengthInput, DefaultMaxLength),
+		ToUpper:          getBoolEnv(ToUpperInput, DefaultToUpper),
+		ToLower:          getBoolEnv(ToLowerInput, DefaultToLower),
+		EncodeURL:        getBoolEnv(EncodeURLInput, DefaultEncodeURL),
+		EscapeNewlines:   getBoolEnv(EscapeNewlinesInput, DefaultEscapeNewlines),
+		MaxLength:        getIntEnv(MaxLengthInput, DefaultMaxLength),
+		Transforms:       getEnvWithDefault(TransformsInput, DefaultTransforms),
+		GlobalTransforms: getEnvWithDefault(GlobalTransformsInput, DefaultGlobalTransforms),
 
 		// Security Options
 		MaskSecrets: getBoolEnv(MaskSecretsInput, DefaultMaskSecrets),
 		MaskPattern: getEnvWithDefault(MaskPatternInput, DefaultMaskPattern),
+		MaskAll:     getBoolEnv(MaskAllInput, DefaultMaskAll),
 
 		// Debug Options
-		DebugMode: getBoolEnv(DebugModeInput, DefaultDebugMode),
+		DebugMode:   getBoolEnv(DebugModeInput, DefaultDebugMode),
+		StepSummary: getBoolEnv(StepSummaryInput, DefaultStepSummary),
 
 		// Advanced Options
 		GroupPrefix:         getEnvWithDefault(GroupPrefixInput, DefaultGroupPrefix),
 		JsonSupport:         getBoolEnv(JsonSupportInput, DefaultJsonSupport),
+		StructuredFormat:    getEnvWithDefault(StructuredFormatInput, DefaultStructuredFormat),
+		JsonKeyOrder:        getEnvWithDefault(JsonKeyOrderInput, DefaultJsonKeyOrder),
+		JsonSeparator:       getEnvWithDefault(JsonSeparatorInput, DefaultJsonSeparator),
+		JsonMaxDepth:        getIntEnv(JsonMaxDepthInput, DefaultJsonMaxDepth),
+		JsonLeafOnly:        getBoolEnv(JsonLeafOnlyInput, DefaultJsonLeafOnly),
+		JsonArrayLength:     getBoolEnv(JsonArrayLengthInput, DefaultJsonArrayLength),
+		JsonKeyStyle:        getEnvWithDefault(JsonKeyStyleInput, DefaultJsonKeyStyle),
+		JsonNullValue:       getEnvWithDefault(JsonNullValueInput, DefaultJsonNullValue),
+		JsonSelect:          getEnvWithDefault(JsonSelectInput, DefaultJsonSelect),
+		JsonSchema:          getEnvWithDefault(JsonSchemaInput, DefaultJsonSchema),
 		ExportAsEnv:         getBoolEnv(ExportAsEnvInput, DefaultExportAsEnv),
 		EnableInterpolation: getBoolEnv(EnableInterpolationInput, DefaultEnableInterpolation),
+		EnableTemplates:     getBoolEnv(EnableTemplatesInput, DefaultEnableTemplates),
+		InterpolateEnvKeys:  getBoolEnv(InterpolateEnvKeysInput, DefaultInterpolateEnvKeys),
+		InterpolationStrict: getBoolEnv(InterpolationStrictInput, DefaultInterpolationStrict),
+		InterpolationAllow:  getEnvWithDefault(InterpolationAllowInput, DefaultInterpolationAllow),
+		InterpolationDeny:   getEnvWithDefault(InterpolationDenyInput, DefaultInterpolationDeny),
+		ExportFile:          getEnvWithDefault(ExportFileInput, DefaultExportFile),
+		ExportFormat:        getEnvWithDefault(ExportFormatInput, DefaultExportFormat),
+		ExportFileMode:      getEnvWithDefault(ExportFileModeInput, DefaultExportFileMode),
 		FileEncoding:        getEnvWithDefault(FileEncodingInput, DefaultFileEncoding),
 		ValidationRules:     getEnvWithDefault(ValidationRulesInput, DefaultValidationRules),
+		ValidationMode:      getEnvWithDefault(ValidationModeInput, DefaultValidationMode),
+		ValidationExpr:      getEnvWithDefault(ValidationExprInput, DefaultValidationExpr),
+		KeyPolicy:           getEnvWithDefault(KeyPolicyInput, DefaultKeyPolicy),
+		KeyDenylist:         getEnvWithDefault(KeyDenylistInput, DefaultKeyDenylist),
+		KeyAutofix:          getBoolEnv(KeyAutofixInput, DefaultKeyAutofix),
 	}
 }
 
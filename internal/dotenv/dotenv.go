@@ -0,0 +1,304 @@
+package dotenv
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/interpolator"
+)
+
+// exportPrefix is the optional shell keyword allowed before a key.
+const exportPrefix = "export"
+
+// keyPattern matches the key names accepted in a dotenv file.
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// Entry is a single KEY=value assignment, kept in file order.
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// Options controls the ${VAR} expansion of unquoted and double-quoted values.
+type Options struct {
+	Expand bool     // Expand ${VAR} references; without it they are kept as written
+	Strict bool     // A reference to an unset variable without a default is an error
+	Allow  []string // Environment variables references may read, as interpolator.Options.Allow
+	Deny   []string // Environment variables references may not read, as interpolator.Options.Deny
+}
+
+// ParseFile reads and parses the dotenv file at path.
+func ParseFile(path string, opts Options) ([]Entry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+
+	entries, err := Parse(string(content), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse env file %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Parse parses dotenv content. Supported syntax:
+//   - blank lines and lines starting with # are ignored
+//   - an optional "export " prefix before the key
+//   - unquoted values, where " #" starts an inline comment
+//   - 'single quoted' values, taken literally (no escapes, no expansion)
+//   - "double quoted" values, which may span lines and support \n, \r, \t,
+//     \", \\ and \$ escapes
+//
+// With opts.Expand, ${VAR} references in unquoted and double-quoted values are
+// expanded with the interpolator package, resolving keys defined earlier in the
+// content first and the process environment second. Environment variables
+// follow opts.Allow and opts.Deny, and never include the runner credentials.
+func Parse(content string, opts Options) ([]Entry, error) {
+	p := &parser{
+		src:     strings.ReplaceAll(content, "\r\n", "\n"),
+		line:    1,
+		defined: make(map[string]string),
+	}
+	if opts.Expand {
+		p.ip = interpolator.NewWithOptions(interpolator.Options{
+			Variables: p.defined,
+			Strict:    opts.Strict,
+			Allow:     opts.Allow,
+			Deny:      opts.Deny,
+		})
+	}
+	return p.parse()
+}
+
+// parser holds the scanning state for a single Parse call.
+type parser struct {
+	src     string
+	pos     int
+	line    int
+	defined map[string]string
+	ip      *interpolator.Interpolator // nil when references are not expanded
+}
+
+func (p *parser) parse() ([]Entry, error) {
+	var entries []Entry
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return entries, nil
+		}
+
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		startLine := p.line
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, p.errorf(startLine, "%v", err)
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, p.errorf(startLine, "key %s: %v", key, err)
+		}
+
+		p.defined[key] = value
+		entries = append(entries, Entry{Key: key, Value: value})
+	}
+}
+
+// parseKey reads an optional export prefix and the key up to the '=' sign.
+func (p *parser) parseKey() (string, error) {
+	end := strings.IndexAny(p.src[p.pos:], "=\n")
+	if end < 0 || p.src[p.pos+end] != '=' {
+		return "", fmt.Errorf("expected KEY=value, got %q", strings.TrimSpace(p.restOfLine()))
+	}
+
+	key := strings.TrimSpace(p.src[p.pos : p.pos+end])
+	if rest, ok := strings.CutPrefix(key, exportPrefix); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+		key = strings.TrimSpace(rest)
+	}
+	if !keyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	p.pos += end + 1
+	return key, nil
+}
+
+// parseValue reads the value following '=' and consumes the rest of the line.
+func (p *parser) parseValue() (string, error) {
+	p.skipSpaces()
+	if p.eof() {
+		return "", nil
+	}
+
+	switch p.peek() {
+	case '\'':
+		raw, err := p.readQuoted('\'')
+		if err != nil {
+			return "", err
+		}
+		return raw, p.finishLine()
+	case '"':
+		raw, err := p.readQuoted('"')
+		if err != nil {
+			return "", err
+		}
+		value, err := p.expand(raw, true)
+		if err != nil {
+			return "", err
+		}
+		return value, p.finishLine()
+	default:
+		raw := p.restOfLine()
+		p.pos += len(raw)
+		return p.expand(strings.TrimRight(stripInlineComment(raw), " \t"), false)
+	}
+}
+
+// readQuoted reads a quoted value (which may span lines) and returns its raw
+// content without the surrounding quotes. Inside double quotes a backslash
+// escapes the following character so \" does not terminate the value.
+func (p *parser) readQuoted(quote byte) (string, error) {
+	p.pos++ // opening quote
+	start := p.pos
+	for !p.eof() {
+		ch := p.src[p.pos]
+		switch {
+		case ch == '\\' && quote == '"' && p.pos+1 < len(p.src):
+			if p.src[p.pos+1] == '\n' {
+				p.line++
+			}
+			p.pos += 2
+			continue
+		case ch == quote:
+			raw := p.src[start:p.pos]
+			p.pos++
+			return raw, nil
+		case ch == '\n':
+			p.line++
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated %c-quoted value", quote)
+}
+
+// finishLine ensures only whitespace or a comment follows a closing quote.
+func (p *parser) finishLine() error {
+	rest := p.restOfLine()
+	p.pos += len(rest)
+	trimmed := strings.TrimSpace(rest)
+	if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+		return fmt.Errorf("unexpected characters after closing quote: %q", trimmed)
+	}
+	return nil
+}
+
+// expand processes escapes (double-quoted values only) and, if enabled, ${VAR}
+// references.
+func (p *parser) expand(raw string, escapes bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+
+		if escapes && ch == '\\' && i+1 < len(raw) {
+			i++
+			switch raw[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(raw[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(raw[i])
+			}
+			continue
+		}
+
+		if ch == '$' && p.ip != nil {
+			// Hand the whole expansion, nested defaults included, to the interpolator
+			if length := interpolator.ExpansionLength(raw[i:]); length > 0 {
+				resolved, err := p.ip.Interpolate(raw[i : i+length])
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(resolved)
+				i += length - 1
+				continue
+			}
+		}
+
+		b.WriteByte(ch)
+	}
+	return b.String(), nil
+}
+
+// stripInlineComment removes a trailing comment introduced by whitespace + '#'.
+// A '#' at the very start of the value starts a comment too (KEY= # note).
+func stripInlineComment(value string) string {
+	if strings.HasPrefix(value, "#") {
+		return ""
+	}
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return value[:i]
+		}
+	}
+	return value
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	return p.src[p.pos]
+}
+
+// restOfLine returns the text from the current position up to (not including) the newline.
+func (p *parser) restOfLine() string {
+	rest := p.src[p.pos:]
+	if idx := strings.IndexByte(rest, '\n'); idx >= 0 {
+		return rest[:idx]
+	}
+	return rest
+}
+
+// skipBlank skips whitespace including newlines.
+func (p *parser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case '\n':
+			p.line++
+		case ' ', '\t', '\r':
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+// skipSpaces skips spaces and tabs on the current line.
+func (p *parser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine advances past the current line.
+func (p *parser) skipLine() {
+	p.pos += len(p.restOfLine())
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,286 @@
+package dotenv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		envVars   map[string]string
+		expected  []Entry
+		wantError bool
+		errMsg    string
+	}{
+		{
+			name:     "Simple assignments",
+			content:  "KEY1=value1\nKEY2=value2\n",
+			expected: []Entry{{"KEY1", "value1"}, {"KEY2", "value2"}},
+		},
+		{
+			name:     "Comments and blank lines",
+			content:  "# comment\n\n  # indented comment\nKEY=value\n",
+			expected: []Entry{{"KEY", "value"}},
+		},
+		{
+			name:     "Export prefix",
+			content:  "export KEY=value\nexport\tOTHER=x",
+			expected: []Entry{{"KEY", "value"}, {"OTHER", "x"}},
+		},
+		{
+			name:     "Key starting with export",
+			content:  "exported=yes",
+			expected: []Entry{{"exported", "yes"}},
+		},
+		{
+			name:     "Whitespace around key and value",
+			content:  "  KEY  =   value  ",
+			expected: []Entry{{"KEY", "value"}},
+		},
+		{
+			name:     "Inline comment on unquoted value",
+			content:  "KEY=value # trailing comment\nURL=http://host/#anchor",
+			expected: []Entry{{"KEY", "value"}, {"URL", "http://host/#anchor"}},
+		},
+		{
+			name:     "Empty values",
+			content:  "EMPTY=\nCOMMENT_ONLY= # note\nQUOTED=\"\"",
+			expected: []Entry{{"EMPTY", ""}, {"COMMENT_ONLY", ""}, {"QUOTED", ""}},
+		},
+		{
+			name:     "Single quotes are literal",
+			content:  `KEY='a\nb ${HOME} # not a comment'`,
+			expected: []Entry{{"KEY", `a\nb ${HOME} # not a comment`}},
+		},
+		{
+			name:     "Double quote escapes",
+			content:  `KEY="line1\nline2\ttab \"quoted\" back\\slash \$literal"`,
+			expected: []Entry{{"KEY", "line1\nline2\ttab \"quoted\" back\\slash $literal"}},
+		},
+		{
+			name:     "Multiline double-quoted value",
+			content:  "CERT=\"-----BEGIN-----\nabc\n-----END-----\"\nNEXT=1",
+			expected: []Entry{{"CERT", "-----BEGIN-----\nabc\n-----END-----"}, {"NEXT", "1"}},
+		},
+		{
+			name:     "Comment after closing quote",
+			content:  `KEY="value" # comment`,
+			expected: []Entry{{"KEY", "value"}},
+		},
+		{
+			name:     "Expansion from environment",
+			content:  "URL=https://${HOST}:${PORT:-443}/",
+			envVars:  map[string]string{"HOST": "example.com"},
+			expected: []Entry{{"URL", "https://example.com:443/"}},
+		},
+		{
+			name:     "Expansion from earlier keys wins over environment",
+			content:  "REGISTRY=ghcr.io\nIMAGE=\"${REGISTRY}/app\"",
+			envVars:  map[string]string{"REGISTRY": "docker.io"},
+			expected: []Entry{{"REGISTRY", "ghcr.io"}, {"IMAGE", "ghcr.io/app"}},
+		},
+		{
+			name:     "Nested default",
+			content:  "B=bee\nK=${A_DOTENV_UNSET:-${B:-c}}\nQ=\"${A_DOTENV_UNSET:-${C_DOTENV_UNSET:-c}}/x\"",
+			expected: []Entry{{"B", "bee"}, {"K", "bee"}, {"Q", "c/x"}},
+		},
+		{
+			name:     "Unterminated expansion stays literal",
+			content:  "B=bee\nK=${A:-${B}",
+			expected: []Entry{{"B", "bee"}, {"K", "${A:-bee"}},
+		},
+		{
+			name:     "Escaped dollar is not expanded",
+			content:  `KEY="\${HOST}"`,
+			envVars:  map[string]string{"HOST": "example.com"},
+			expected: []Entry{{"KEY", "${HOST}"}},
+		},
+		{
+			name:     "CRLF line endings",
+			content:  "KEY1=a\r\nKEY2=b\r\n",
+			expected: []Entry{{"KEY1", "a"}, {"KEY2", "b"}},
+		},
+		{
+			name:      "Missing equals sign",
+			content:   "KEY=ok\nBROKEN\n",
+			wantError: true,
+			errMsg:    "line 2",
+		},
+		{
+			name:      "Invalid key",
+			content:   "1KEY=value",
+			wantError: true,
+			errMsg:    "invalid key",
+		},
+		{
+			name:      "Unterminated double quote",
+			content:   "KEY=\"value\nOTHER=1",
+			wantError: true,
+			errMsg:    "unterminated",
+		},
+		{
+			name:      "Garbage after closing quote",
+			content:   `KEY="value"extra`,
+			wantError: true,
+			errMsg:    "after closing quote",
+		},
+		{
+			name:      "Required variable missing",
+			content:   "KEY=${MISSING_DOTENV_VAR:?must be set}",
+			wantError: true,
+			errMsg:    "must be set",
+		},
+		{
+			name:      "Runner credentials are blocked",
+			content:   "TOKEN=${ACTIONS_RUNTIME_TOKEN}",
+			envVars:   map[string]string{"ACTIONS_RUNTIME_TOKEN": "secret"},
+			wantError: true,
+			errMsg:    "variable ACTIONS_RUNTIME_TOKEN is blocked by the interpolation denylist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			entries, err := Parse(tt.content, Options{Expand: true})
+
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("Parse() expected error, got entries %v", entries)
+				}
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Parse() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("Parse() = %v, want %v", entries, tt.expected)
+			}
+			for i, e := range entries {
+				if e != tt.expected[i] {
+					t.Errorf("Parse()[%d] = %+v, want %+v", i, e, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "s3cr3t")
+	t.Setenv("APP_REGION", "eu-west-1")
+
+	tests := []struct {
+		name     string
+		content  string
+		opts     Options
+		expected []Entry
+		errMsg   string
+	}{
+		{
+			name:     "Expansion disabled keeps references",
+			content:  "A=1\nK=${A}-${APP_REGION}\nQ=\"\\${A}\"",
+			opts:     Options{},
+			expected: []Entry{{"A", "1"}, {"K", "${A}-${APP_REGION}"}, {"Q", "${A}"}},
+		},
+		{
+			name:     "Allowed variable resolves",
+			content:  "K=${APP_REGION}",
+			opts:     Options{Expand: true, Allow: []string{"APP_*"}},
+			expected: []Entry{{"K", "eu-west-1"}},
+		},
+		{
+			name:    "Variable outside the allowlist is blocked",
+			content: "K=${AWS_SECRET_ACCESS_KEY}",
+			opts:    Options{Expand: true, Allow: []string{"APP_*"}},
+			errMsg:  "variable AWS_SECRET_ACCESS_KEY is not in the interpolation allowlist",
+		},
+		{
+			name:    "Denied variable is blocked",
+			content: "K=${AWS_SECRET_ACCESS_KEY:-none}",
+			opts:    Options{Expand: true, Deny: []string{"AWS_*"}},
+			errMsg:  "variable AWS_SECRET_ACCESS_KEY is blocked by the interpolation denylist (pattern AWS_*)",
+		},
+		{
+			name:     "Earlier keys are not checked",
+			content:  "AWS_PROFILE=dev\nK=${AWS_PROFILE}",
+			opts:     Options{Expand: true, Deny: []string{"AWS_*"}},
+			expected: []Entry{{"AWS_PROFILE", "dev"}, {"K", "dev"}},
+		},
+		{
+			name:    "Strict mode rejects unset variables",
+			content: "K=${DOTENV_UNSET_VAR}",
+			opts:    Options{Expand: true, Strict: true},
+			errMsg:  "variable DOTENV_UNSET_VAR is not set and has no default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Parse(tt.content, tt.opts)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Parse() error = %v, want containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("Parse() = %v, want %v", entries, tt.expected)
+			}
+			for i, e := range entries {
+				if e != tt.expected[i] {
+					t.Errorf("Parse()[%d] = %+v, want %+v", i, e, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	t.Run("reads file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		if err := os.WriteFile(path, []byte("A=1\nB=2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := ParseFile(path, Options{})
+		if err != nil {
+			t.Fatalf("ParseFile() unexpected error: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Errorf("ParseFile() returned %d entries, want 2", len(entries))
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ParseFile(filepath.Join(t.TempDir(), "missing.env"), Options{})
+		if err == nil || !strings.Contains(err.Error(), "failed to read env file") {
+			t.Errorf("ParseFile() error = %v, want read error", err)
+		}
+	})
+
+	t.Run("parse error names the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bad.env")
+		if err := os.WriteFile(path, []byte("NOPE\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := ParseFile(path, Options{})
+		if err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("ParseFile() error = %v, want it to name %s", err, path)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+// Package export serializes key/value sets into configuration files (dotenv,
+// shell, JSON, YAML, Docker env files and Java properties) and replaces files
+// atomically, so readers never see a partly written file.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Supported export formats
+const (
+	FormatDotenv     = "dotenv"     // KEY=value, double-quoted with escapes when needed
+	FormatShell      = "shell"      // export KEY='value', for sourcing in a POSIX shell
+	FormatJSON       = "json"       // A JSON object of strings, in key order
+	FormatYAML       = "yaml"       // A YAML mapping of double-quoted strings
+	FormatDockerEnv  = "docker-env" // KEY=value lines for docker run --env-file, taken literally
+	FormatProperties = "properties" // Java .properties with backslash escapes
+)
+
+// DefaultFileMode is the permission of an exported file when none is given.
+const DefaultFileMode os.FileMode = 0644
+
+// Error messages
+const (
+	errUnknownFormat = "unsupported export_format %q (expected dotenv, shell, json, yaml, docker-env or properties)"
+	errInvalidMode   = "invalid export_file_mode %q (expected an octal permission such as 0600)"
+	errMultiline     = "%s format cannot hold the multiline value of %s"
+	errInvalidKey    = "%s format cannot hold the key %q"
+	errWriteExport   = "failed to write export file %s: %w"
+)
+
+var (
+	// shellNamePattern matches the names a shell can export
+	shellNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// dotenvKeyPattern matches the keys dotenv parsers accept
+	dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	// plainDotenvValue matches values that need no quotes in a dotenv file
+	plainDotenvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+)
+
+// Formats returns the supported formats.
+func Formats() []string {
+	return []string{FormatDotenv, FormatShell, FormatJSON, FormatYAML, FormatDockerEnv, FormatProperties}
+}
+
+// NormalizeFormat returns format in lower case, dotenv if it is empty, or an
+// error if it is not supported.
+func NormalizeFormat(format string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(format))
+	if normalized == "" {
+		return FormatDotenv, nil
+	}
+	for _, f := range Formats() {
+		if normalized == f {
+			return normalized, nil
+		}
+	}
+	return "", fmt.Errorf(errUnknownFormat, format)
+}
+
+// ParseFileMode parses an octal permission such as "0600" or "640", or
+// returns DefaultFileMode if mode is empty.
+func ParseFileMode(mode string) (os.FileMode, error) {
+	mode = strings.TrimSpace(mode)
+	if mode == "" {
+		return DefaultFileMode, nil
+	}
+	parsed, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || parsed > 0777 {
+		return 0, fmt.Errorf(errInvalidMode, mode)
+	}
+	return os.FileMode(parsed), nil
+}
+
+// Serialize renders keys and values, where values[i] is the value of keys[i],
+// in format. Keys keep their order and are expected to be unique. Keys a
+// format cannot name and, in docker-env files, multiline values are an error.
+func Serialize(format string, keys, values []string) ([]byte, error) {
+	format, err := NormalizeFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatJSON {
+		return serializeJSON(keys, values), nil
+	}
+
+	var buf bytes.Buffer
+	for i, key := range keys {
+		value := values[i]
+		switch format {
+		case FormatDotenv:
+			if !dotenvKeyPattern.MatchString(key) {
+				return nil, fmt.Errorf(errInvalidKey, format, key)
+			}
+			fmt.Fprintf(&buf, "%s=%s\n", key, dotenvQuote(value))
+		case FormatShell:
+			if !shellNamePattern.MatchString(key) {
+				return nil, fmt.Errorf(errInvalidKey, format, key)
+			}
+			fmt.Fprintf(&buf, "export %s=%s\n", key, shellQuote(value))
+		case FormatYAML:
+			// Keys are quoted too, so on, no, null or ~ stay strings
+			fmt.Fprintf(&buf, "%s: %s\n", jsonString(key), jsonString(value))
+		case FormatDockerEnv:
+			if key == "" || strings.ContainsAny(key, "= \t\r\n") {
+				return nil, fmt.Errorf(errInvalidKey, format, key)
+			}
+			if strings.ContainsAny(value, "\r\n") {
+				return nil, fmt.Errorf(errMultiline, format, key)
+			}
+			fmt.Fprintf(&buf, "%s=%s\n", key, value)
+		case FormatProperties:
+			fmt.Fprintf(&buf, "%s=%s\n", propertiesEscape(key, true), propertiesEscape(value, false))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// serializeJSON renders a JSON object with the keys in order, indented by two
+// spaces and without escaping <, > and &.
+func serializeJSON(keys, values []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, "\n  %s: %s", jsonString(key), jsonString(values[i]))
+	}
+	if len(keys) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// jsonString returns s as a JSON string literal, which is also a valid YAML
+// double-quoted scalar.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // Encoding a string cannot fail
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// dotenvQuote leaves plain values as they are and double-quotes the others,
+// escaping backslashes, quotes, dollar signs and line breaks.
+func dotenvQuote(value string) string {
+	if plainDotenvValue.MatchString(value) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// shellQuote wraps value in single quotes. A single quote inside closes the
+// quotes, is escaped with a backslash and opens them again.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// propertiesEscape escapes s for a .properties file: backslashes, line breaks,
+// tabs and form feeds, a leading space (every space in a key), the separators
+// '=' and ':' and the comment characters '#' and '!'. Characters outside
+// printable ASCII become \uXXXX escapes, since readers assume ISO 8859-1.
+func propertiesEscape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case r == '=' || r == ':' || r == '#' || r == '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04X`, unit)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// WriteFile replaces the file at path with data atomically: data is written
+// and synced to a temporary file in the same directory, which is then renamed
+// over path. Missing parent directories are created.
+func WriteFile(path string, data []byte, mode os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf(errWriteExport, path, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf(errWriteExport, path, err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf(errWriteExport, path, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return fmt.Errorf(errWriteExport, path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf(errWriteExport, path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf(errWriteExport, path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf(errWriteExport, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/dotenv"
+)
+
+func TestNormalizeFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+		wantErr  bool
+	}{
+		{"", FormatDotenv, false},
+		{" JSON ", FormatJSON, false},
+		{"docker-env", FormatDockerEnv, false},
+		{"toml", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := NormalizeFormat(tt.format)
+			if (err != nil) != tt.wantErr || got != tt.expected {
+				t.Errorf("NormalizeFormat() = %q, %v, want %q (error %v)", got, err, tt.expected, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected os.FileMode
+		wantErr  bool
+	}{
+		{"", DefaultFileMode, false},
+		{"0600", 0600, false},
+		{"640", 0640, false},
+		{"0o600", 0, true},
+		{"0800", 0, true},
+		{"1777", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := ParseFileMode(tt.mode)
+			if (err != nil) != tt.wantErr || got != tt.expected {
+				t.Errorf("ParseFileMode() = %o, %v, want %o (error %v)", got, err, tt.expected, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	keys := []string{"PLAIN", "SPACED", "QUOTES", "MULTI", "EMPTY"}
+	values := []string{"ghcr.io/acme/app:v1", " a b ", `it's "$HOME" \ <b>`, "line1\nline2", ""}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: FormatDotenv,
+			expected: "PLAIN=ghcr.io/acme/app:v1\n" +
+				"SPACED=\" a b \"\n" +
+				"QUOTES=\"it's \\\"\\$HOME\\\" \\\\ <b>\"\n" +
+				"MULTI=\"line1\\nline2\"\n" +
+				"EMPTY=\n",
+		},
+		{
+			format: FormatShell,
+			expected: "export PLAIN='ghcr.io/acme/app:v1'\n" +
+				"export SPACED=' a b '\n" +
+				"export QUOTES='it'\\''s \"$HOME\" \\ <b>'\n" +
+				"export MULTI='line1\nline2'\n" +
+				"export EMPTY=''\n",
+		},
+		{
+			format: FormatJSON,
+			expected: "{\n" +
+				"  \"PLAIN\": \"ghcr.io/acme/app:v1\",\n" +
+				"  \"SPACED\": \" a b \",\n" +
+				"  \"QUOTES\": \"it's \\\"$HOME\\\" \\\\ <b>\",\n" +
+				"  \"MULTI\": \"line1\\nline2\",\n" +
+				"  \"EMPTY\": \"\"\n" +
+				"}\n",
+		},
+		{
+			format: FormatYAML,
+			expected: "\"PLAIN\": \"ghcr.io/acme/app:v1\"\n" +
+				"\"SPACED\": \" a b \"\n" +
+				"\"QUOTES\": \"it's \\\"$HOME\\\" \\\\ <b>\"\n" +
+				"\"MULTI\": \"line1\\nline2\"\n" +
+				"\"EMPTY\": \"\"\n",
+		},
+		{
+			format: FormatProperties,
+			expected: "PLAIN=ghcr.io/acme/app\\:v1\n" +
+				"SPACED=\\ a b \n" +
+				"QUOTES=it's \"$HOME\" \\\\ <b>\n" +
+				"MULTI=line1\\nline2\n" +
+				"EMPTY=\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := Serialize(tt.format, keys, values)
+			if err != nil {
+				t.Fatalf("Serialize() unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Serialize() =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("docker-env", func(t *testing.T) {
+		got, err := Serialize(FormatDockerEnv, []string{"A", "B"}, []string{`"quoted" $X`, " b "})
+		if err != nil || string(got) != "A=\"quoted\" $X\nB= b \n" {
+			t.Errorf("Serialize() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("yaml reserved keys", func(t *testing.T) {
+		got, err := Serialize(FormatYAML, []string{"true", "on", "no", "null", "~", "a: b"}, []string{"1", "2", "3", "4", "5", "6"})
+		want := "\"true\": \"1\"\n\"on\": \"2\"\n\"no\": \"3\"\n\"null\": \"4\"\n\"~\": \"5\"\n\"a: b\": \"6\"\n"
+		if err != nil || string(got) != want {
+			t.Errorf("Serialize() = %q, %v, want %q", got, err, want)
+		}
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		if got, _ := Serialize(FormatJSON, nil, nil); string(got) != "{}\n" {
+			t.Errorf("Serialize() = %q, want %q", got, "{}\n")
+		}
+	})
+
+	t.Run("properties escapes", func(t *testing.T) {
+		got, _ := Serialize(FormatProperties, []string{"a key=b"}, []string{"#x: é😀"})
+		if want := "a\\ key\\=b=\\#x\\: \\u00E9\\uD83D\\uDE00\n"; string(got) != want {
+			t.Errorf("Serialize() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestSerializeErrors(t *testing.T) {
+	tests := []struct {
+		format  string
+		key     string
+		value   string
+		errPart string
+	}{
+		{FormatShell, "my-key", "v", `shell format cannot hold the key "my-key"`},
+		{FormatDotenv, "1KEY", "v", `dotenv format cannot hold the key "1KEY"`},
+		{FormatDockerEnv, "A B", "v", `docker-env format cannot hold the key "A B"`},
+		{FormatDockerEnv, "CERT", "a\nb", "docker-env format cannot hold the multiline value of CERT"},
+		{"xml", "A", "v", `unsupported export_format "xml"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.key, func(t *testing.T) {
+			_, err := Serialize(tt.format, []string{tt.key}, []string{tt.value})
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Serialize() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestSerializeDotenvRoundTrip(t *testing.T) {
+	keys := []string{"A", "B", "C", "D", "E"}
+	values := []string{"plain", "with space # not a comment", "quote \" and \\ and $HOME", "tab\tnew\nline\r", "${NOT_EXPANDED}"}
+
+	data, err := Serialize(FormatDotenv, keys, values)
+	if err != nil {
+		t.Fatalf("Serialize() unexpected error: %v", err)
+	}
+	entries, err := dotenv.Parse(string(data), dotenv.Options{Expand: true})
+	if err != nil {
+		t.Fatalf("dotenv.Parse() unexpected error: %v", err)
+	}
+	var gotKeys, gotValues []string
+	for _, e := range entries {
+		gotKeys = append(gotKeys, e.Key)
+		gotValues = append(gotValues, e.Value)
+	}
+	if !reflect.DeepEqual(gotKeys, keys) || !reflect.DeepEqual(gotValues, values) {
+		t.Errorf("round trip = %q %q, want %q %q", gotKeys, gotValues, keys, values)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "app.env")
+
+	if err := WriteFile(path, []byte("A=1\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if err := WriteFile(path, []byte("A=2\n"), 0640); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "A=2\n" {
+		t.Errorf("file = %q, %v, want %q", data, err, "A=2\n")
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, %v, want 0640", info.Mode().Perm(), err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the file (no temporary files left)", len(entries))
+	}
+
+	t.Run("Failure leaves the file untouched", func(t *testing.T) {
+		target := filepath.Join(dir, "target")
+		if err := os.Mkdir(target, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(target, "keep"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := WriteFile(target, []byte("x"), 0644)
+		if err == nil || !strings.Contains(err.Error(), "failed to write export file") {
+			t.Errorf("WriteFile() error = %v, want a write failure", err)
+		}
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if strings.Contains(e.Name(), ".tmp-") {
+				t.Errorf("temporary file %s left behind", e.Name())
+			}
+		}
+	})
+}
@@ -0,0 +1,465 @@
+// Package expr implements the expression language of validation_expressions.
+// Expressions only read the key values handed to Eval: there are no
+// assignments, loops, or access to files, the environment or the network,
+// and regular expressions use RE2, so evaluation always finishes in time
+// linear in the expression and its inputs.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Error messages
+const (
+	errSyntax    = "invalid expression %q: %s at offset %d"
+	errUndefined = "key %s is not set (check it with defined(%s) first)"
+	errNotBool   = "%s is not true or false"
+	errNotNumber = "%s is not a number"
+	errNotString = "%s is not a string"
+	errResult    = "expression does not evaluate to true or false"
+)
+
+// maxDepth limits how deeply expressions may nest.
+const maxDepth = 64
+
+// Expr is a parsed expression. The grammar, from lowest to highest precedence:
+//
+//	a || b                   logical or (short-circuit)
+//	a && b                   logical and (short-circuit)
+//	a == b, !=, <, <=, >, >= comparisons
+//	a =~ "re", a !~ "re"     regular expression match
+//	a in ["x", "y"]          list membership
+//	!a, -a                   negation
+//	KEY, `odd-key`           key values (backquotes for names that are not identifiers)
+//	"text", 'text', 3, 1.5   literals, plus true and false
+//	fn(args)                 functions, see functions
+//
+// Values of keys are strings. A string compared with a number is read as a
+// number, and two strings that are both numbers are ordered numerically.
+type Expr struct {
+	src  string
+	root node
+	keys []string
+}
+
+// Parse parses an expression, reporting syntax errors, unknown functions and
+// invalid regular expressions.
+func Parse(src string) (*Expr, error) {
+	p := &parser{expr: strings.TrimSpace(src)}
+	if p.expr == "" {
+		return nil, p.errorf("empty expression")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.expr) {
+		return nil, p.errorf("unexpected %q", p.expr[p.pos])
+	}
+	return &Expr{src: p.expr, root: root, keys: p.keys}, nil
+}
+
+// String returns the expression as written.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Keys returns the keys the expression refers to, in order of first use.
+func (e *Expr) Keys() []string {
+	return e.keys
+}
+
+// Eval evaluates the expression. lookup returns the value of a key and
+// whether the key is set. The result must be a boolean, or a string that
+// reads as one.
+func (e *Expr) Eval(lookup func(key string) (string, bool)) (bool, error) {
+	v, err := e.root.eval(lookup)
+	if err != nil {
+		return false, err
+	}
+	b, ok := boolOf(v)
+	if !ok {
+		return false, fmt.Errorf(errResult)
+	}
+	return b, nil
+}
+
+// kind is the type of a value.
+type kind int
+
+const (
+	kindString kind = iota
+	kindNumber
+	kindBool
+	kindList
+)
+
+// value is the result of evaluating a node.
+type value struct {
+	kind kind
+	str  string
+	num  float64
+	b    bool
+	list []value
+}
+
+func stringValue(s string) value  { return value{kind: kindString, str: s} }
+func numberValue(n float64) value { return value{kind: kindNumber, num: n} }
+func boolValue(b bool) value      { return value{kind: kindBool, b: b} }
+
+// numberOf reads v as a finite number.
+func numberOf(v value) (float64, bool) {
+	switch v.kind {
+	case kindNumber:
+		return v.num, true
+	case kindString:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v.str), 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return 0, false
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// boolOf reads v as a boolean; strings must be true or false in any case.
+func boolOf(v value) (bool, bool) {
+	switch v.kind {
+	case kindBool:
+		return v.b, true
+	case kindString:
+		switch strings.ToLower(strings.TrimSpace(v.str)) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// stringOf renders a scalar value as a string.
+func stringOf(v value) (string, bool) {
+	switch v.kind {
+	case kindString:
+		return v.str, true
+	case kindNumber:
+		return strconv.FormatFloat(v.num, 'f', -1, 64), true
+	case kindBool:
+		return strconv.FormatBool(v.b), true
+	}
+	return "", false
+}
+
+// equal compares two values for == and in. A number on either side makes the
+// comparison numeric, a boolean makes it boolean; values that cannot be read
+// that way are unequal.
+func equal(a, b value) bool {
+	switch {
+	case a.kind == kindList || b.kind == kindList:
+		return false
+	case a.kind == kindNumber || b.kind == kindNumber:
+		x, okx := numberOf(a)
+		y, oky := numberOf(b)
+		return okx && oky && x == y
+	case a.kind == kindBool || b.kind == kindBool:
+		x, okx := boolOf(a)
+		y, oky := boolOf(b)
+		return okx && oky && x == y
+	}
+	return a.str == b.str
+}
+
+// node is an expression tree node.
+type node interface {
+	eval(lookup func(string) (string, bool)) (value, error)
+	source() string
+}
+
+// span holds the source text of a node, used to name it in error messages
+// without repeating any key value.
+type span string
+
+func (s span) source() string { return string(s) }
+
+type literalNode struct {
+	span
+	v value
+}
+
+type keyNode struct {
+	span
+	name string
+}
+
+type listNode struct {
+	span
+	items []node
+}
+
+type notNode struct {
+	span
+	inner node
+}
+
+type negNode struct {
+	span
+	inner node
+}
+
+type logicalNode struct {
+	span
+	op          string
+	left, right node
+}
+
+type compareNode struct {
+	span
+	op          string
+	left, right node
+}
+
+type matchNode struct {
+	span
+	negate bool
+	left   node
+	re     *regexp.Regexp
+}
+
+type inNode struct {
+	span
+	left node
+	list node
+}
+
+type definedNode struct {
+	span
+	name string
+}
+
+type ifNode struct {
+	span
+	cond, then, otherwise node
+}
+
+type callNode struct {
+	span
+	fn   function
+	args []node
+}
+
+func (n literalNode) eval(func(string) (string, bool)) (value, error) { return n.v, nil }
+
+func (n keyNode) eval(lookup func(string) (string, bool)) (value, error) {
+	v, ok := lookup(n.name)
+	if !ok {
+		return value{}, fmt.Errorf(errUndefined, n.name, n.name)
+	}
+	return stringValue(v), nil
+}
+
+func (n listNode) eval(lookup func(string) (string, bool)) (value, error) {
+	items := make([]value, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(lookup)
+		if err != nil {
+			return value{}, err
+		}
+		items[i] = v
+	}
+	return value{kind: kindList, list: items}, nil
+}
+
+func (n notNode) eval(lookup func(string) (string, bool)) (value, error) {
+	b, err := evalBool(n.inner, lookup)
+	if err != nil {
+		return value{}, err
+	}
+	return boolValue(!b), nil
+}
+
+func (n negNode) eval(lookup func(string) (string, bool)) (value, error) {
+	f, err := evalNumber(n.inner, lookup)
+	if err != nil {
+		return value{}, err
+	}
+	return numberValue(-f), nil
+}
+
+func (n logicalNode) eval(lookup func(string) (string, bool)) (value, error) {
+	left, err := evalBool(n.left, lookup)
+	if err != nil {
+		return value{}, err
+	}
+	if (n.op == "||" && left) || (n.op == "&&" && !left) {
+		return boolValue(left), nil
+	}
+	right, err := evalBool(n.right, lookup)
+	if err != nil {
+		return value{}, err
+	}
+	return boolValue(right), nil
+}
+
+func (n compareNode) eval(lookup func(string) (string, bool)) (value, error) {
+	left, err := n.left.eval(lookup)
+	if err != nil {
+		return value{}, err
+	}
+	right, err := n.right.eval(lookup)
+	if err != nil {
+		return value{}, err
+	}
+
+	switch n.op {
+	case "==":
+		return boolValue(equal(left, right)), nil
+	case "!=":
+		return boolValue(!equal(left, right)), nil
+	}
+
+	// Ordering: two strings compare numerically when both are numbers and
+	// lexically otherwise; anything else must be a number.
+	var c int
+	if left.kind == kindString && right.kind == kindString {
+		x, okx := numberOf(left)
+		y, oky := numberOf(right)
+		if okx && oky {
+			c = compareFloats(x, y)
+		} else {
+			c = strings.Compare(left.str, right.str)
+		}
+	} else {
+		x, ok := numberOf(left)
+		if !ok {
+			return value{}, fmt.Errorf(errNotNumber, n.left.source())
+		}
+		y, ok := numberOf(right)
+		if !ok {
+			return value{}, fmt.Errorf(errNotNumber, n.right.source())
+		}
+		c = compareFloats(x, y)
+	}
+
+	switch n.op {
+	case "<":
+		return boolValue(c < 0), nil
+	case "<=":
+		return boolValue(c <= 0), nil
+	case ">":
+		return boolValue(c > 0), nil
+	default:
+		return boolValue(c >= 0), nil
+	}
+}
+
+// compareFloats returns -1, 0 or 1.
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func (n matchNode) eval(lookup func(string) (string, bool)) (value, error) {
+	s, err := evalString(n.left, lookup)
+	if err != nil {
+		return value{}, err
+	}
+	return boolValue(n.re.MatchString(s) != n.negate), nil
+}
+
+func (n inNode) eval(lookup func(string) (string, bool)) (value, error) {
+	left, err := n.left.eval(lookup)
+	if err != nil {
+		return value{}, err
+	}
+	list, err := n.list.eval(lookup)
+	if err != nil {
+		return value{}, err
+	}
+	for _, item := range list.list {
+		if equal(left, item) {
+			return boolValue(true), nil
+		}
+	}
+	return boolValue(false), nil
+}
+
+func (n definedNode) eval(lookup func(string) (string, bool)) (value, error) {
+	_, ok := lookup(n.name)
+	return boolValue(ok), nil
+}
+
+func (n ifNode) eval(lookup func(string) (string, bool)) (value, error) {
+	cond, err := evalBool(n.cond, lookup)
+	if err != nil {
+		return value{}, err
+	}
+	if cond {
+		return n.then.eval(lookup)
+	}
+	return n.otherwise.eval(lookup)
+}
+
+func (n callNode) eval(lookup func(string) (string, bool)) (value, error) {
+	args := make([]value, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(lookup)
+		if err != nil {
+			return value{}, err
+		}
+		if v.kind == kindList {
+			return value{}, fmt.Errorf(errNotString, arg.source())
+		}
+		args[i] = v
+	}
+	return n.fn.call(args, n.args)
+}
+
+// evalBool evaluates n as a boolean.
+func evalBool(n node, lookup func(string) (string, bool)) (bool, error) {
+	v, err := n.eval(lookup)
+	if err != nil {
+		return false, err
+	}
+	b, ok := boolOf(v)
+	if !ok {
+		return false, fmt.Errorf(errNotBool, n.source())
+	}
+	return b, nil
+}
+
+// evalNumber evaluates n as a number.
+func evalNumber(n node, lookup func(string) (string, bool)) (float64, error) {
+	v, err := n.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := numberOf(v)
+	if !ok {
+		return 0, fmt.Errorf(errNotNumber, n.source())
+	}
+	return f, nil
+}
+
+// evalString evaluates n as a string.
+func evalString(n node, lookup func(string) (string, bool)) (string, error) {
+	v, err := n.eval(lookup)
+	if err != nil {
+		return "", err
+	}
+	s, ok := stringOf(v)
+	if !ok {
+		return "", fmt.Errorf(errNotString, n.source())
+	}
+	return s, nil
+}
@@ -0,0 +1,136 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func lookupIn(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func TestEval(t *testing.T) {
+	values := map[string]string{
+		"DEPLOY_ENV":   "prod",
+		"REPLICAS":     "5",
+		"MIN":          "10",
+		"IMAGE_TAG":    "v1.2.3",
+		"DEBUG":        "TRUE",
+		"NAME":         "  Web App ",
+		"app-name":     "web",
+		"EMPTY":        "",
+		"VERSION_NOTE": `said "hi"`,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`DEPLOY_ENV == "prod"`, true},
+		{`DEPLOY_ENV != 'prod'`, false},
+		{`DEPLOY_ENV != "prod" || REPLICAS >= 3`, true},
+		{`if(DEPLOY_ENV == "prod", REPLICAS >= 6, true)`, false},
+		{`REPLICAS < MIN`, true},
+		{`REPLICAS == 5.0`, true},
+		{`REPLICAS == "5.0"`, false},
+		{`REPLICAS > -1`, true},
+		{`-REPLICAS < 0`, true},
+		{`"b" > "a"`, true},
+		{`defined(IMAGE_TAG) != defined(IMAGE_DIGEST)`, true},
+		{`count(defined(IMAGE_TAG), defined(IMAGE_DIGEST)) == 1`, true},
+		{`!defined(IMAGE_DIGEST) && defined(IMAGE_TAG)`, true},
+		{`defined(IMAGE_DIGEST) && IMAGE_DIGEST =~ "^sha256:"`, false},
+		{`IMAGE_TAG =~ "^v\d+\.\d+\.\d+$"`, true},
+		{`IMAGE_TAG !~ "-rc"`, true},
+		{`matches(lower(DEPLOY_ENV), '^(dev|prod)$')`, true},
+		{`DEPLOY_ENV in ["staging", "prod"]`, true},
+		{`REPLICAS in [1, 3, 5]`, true},
+		{`DEBUG`, true},
+		{`DEBUG == true`, true},
+		{`len(trim(NAME)) == 7`, true},
+		{`upper(trim(NAME)) == "WEB APP"`, true},
+		{`contains(NAME, "App") && starts_with(IMAGE_TAG, "v") && ends_with(IMAGE_TAG, ".3")`, true},
+		{"`app-name` == \"web\"", true},
+		{`len(EMPTY) == 0`, true},
+		{`VERSION_NOTE == "said \"hi\""`, true},
+		{`number(REPLICAS) == 5`, true},
+		{"DEPLOY_ENV == \"prod\" &&\n  REPLICAS >= 3", true},
+		{`(true || false) && !(false)`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			got, err := e.Eval(lookupIn(values))
+			if err != nil {
+				t.Fatalf("Eval() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	values := map[string]string{"REPLICAS": "many", "NAME": "web", "SECRET": "hunter2"}
+
+	tests := []struct {
+		expr    string
+		errPart string
+	}{
+		{`IMAGE_TAG == "x"`, "key IMAGE_TAG is not set (check it with defined(IMAGE_TAG) first)"},
+		{`REPLICAS >= 3`, "REPLICAS is not a number"},
+		{`NAME && true`, "NAME is not true or false"},
+		{`NAME`, "expression does not evaluate to true or false"},
+		{`number(SECRET) > 1`, "SECRET is not a number"},
+		{`len(["a"]) == 1`, `["a"] is not a string`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			_, err = e.Eval(lookupIn(values))
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("Eval() error = %v, want containing %q", err, tt.errPart)
+			}
+			if strings.Contains(err.Error(), "hunter2") || strings.Contains(err.Error(), "many") {
+				t.Errorf("Eval() error %q leaks a value", err)
+			}
+		})
+	}
+}
+
+func TestEvalShortCircuit(t *testing.T) {
+	e, err := Parse(`defined(TAG) && TAG == "x" || if(defined(DIGEST), DIGEST != "", true)`)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	got, err := e.Eval(lookupIn(nil))
+	if err != nil || !got {
+		t.Errorf("Eval() = %v, %v, want true without error", got, err)
+	}
+}
+
+func TestExprKeysAndString(t *testing.T) {
+	e, err := Parse("  DEPLOY_ENV != \"prod\" || (defined(REPLICAS) && REPLICAS >= 3 && `odd-key` == DEPLOY_ENV)  ")
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if want := []string{"DEPLOY_ENV", "REPLICAS", "odd-key"}; !reflect.DeepEqual(e.Keys(), want) {
+		t.Errorf("Keys() = %q, want %q", e.Keys(), want)
+	}
+	if !strings.HasPrefix(e.String(), "DEPLOY_ENV") || strings.HasSuffix(e.String(), " ") {
+		t.Errorf("String() = %q, want trimmed source", e.String())
+	}
+}
@@ -0,0 +1,102 @@
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// function is a built-in function. args are evaluated before call; nodes are
+// the argument expressions, used to name arguments in error messages.
+type function struct {
+	minArgs, maxArgs int // maxArgs < 0 allows any number of arguments
+	call             func(args []value, nodes []node) (value, error)
+}
+
+// functions holds the built-in functions. defined(KEY), if(cond, a, b) and
+// matches(s, "re") are handled by the parser because their arguments are not
+// plain values.
+var functions = map[string]function{
+	"len": {1, 1, func(args []value, nodes []node) (value, error) {
+		s, err := argString(args, nodes, 0)
+		if err != nil {
+			return value{}, err
+		}
+		return numberValue(float64(utf8.RuneCountInString(s))), nil
+	}},
+	"lower":       stringFunction(strings.ToLower),
+	"upper":       stringFunction(strings.ToUpper),
+	"trim":        stringFunction(strings.TrimSpace),
+	"contains":    predicateFunction(strings.Contains),
+	"starts_with": predicateFunction(strings.HasPrefix),
+	"ends_with":   predicateFunction(strings.HasSuffix),
+	"number": {1, 1, func(args []value, nodes []node) (value, error) {
+		n, ok := numberOf(args[0])
+		if !ok {
+			return value{}, fmt.Errorf(errNotNumber, nodes[0].source())
+		}
+		return numberValue(n), nil
+	}},
+	"count": {1, -1, func(args []value, nodes []node) (value, error) {
+		count := 0
+		for i, arg := range args {
+			b, ok := boolOf(arg)
+			if !ok {
+				return value{}, fmt.Errorf(errNotBool, nodes[i].source())
+			}
+			if b {
+				count++
+			}
+		}
+		return numberValue(float64(count)), nil
+	}},
+}
+
+// specialForms are parsed by the parser itself; see parser.parseCall.
+var specialForms = []string{"defined", "if", "matches"}
+
+// functionNames lists every function name in sorted order, for error messages.
+func functionNames() string {
+	names := append([]string(nil), specialForms...)
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// stringFunction wraps a string-to-string function of one argument.
+func stringFunction(f func(string) string) function {
+	return function{1, 1, func(args []value, nodes []node) (value, error) {
+		s, err := argString(args, nodes, 0)
+		if err != nil {
+			return value{}, err
+		}
+		return stringValue(f(s)), nil
+	}}
+}
+
+// predicateFunction wraps a string predicate of two arguments.
+func predicateFunction(f func(s, sub string) bool) function {
+	return function{2, 2, func(args []value, nodes []node) (value, error) {
+		s, err := argString(args, nodes, 0)
+		if err != nil {
+			return value{}, err
+		}
+		sub, err := argString(args, nodes, 1)
+		if err != nil {
+			return value{}, err
+		}
+		return boolValue(f(s, sub)), nil
+	}}
+}
+
+// argString returns argument i as a string.
+func argString(args []value, nodes []node, i int) (string, error) {
+	s, ok := stringOf(args[i])
+	if !ok {
+		return "", fmt.Errorf(errNotString, nodes[i].source())
+	}
+	return s, nil
+}
@@ -0,0 +1,73 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFunctions(t *testing.T) {
+	values := map[string]string{"NAME": "  Grüße  ", "PORT": "8080", "FLAG": "false"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`len(NAME) == 9`, true},
+		{`len(trim(NAME)) == 5`, true},
+		{`lower(trim(NAME)) == "grüße"`, true},
+		{`upper(trim(NAME)) == "GRÜßE"`, true},
+		{`contains(PORT, "80")`, true},
+		{`starts_with(PORT, "80") && ends_with(PORT, "80")`, true},
+		{`contains(PORT, 808)`, true},
+		{`number(PORT) >= 1024`, true},
+		{`count(true, FLAG, PORT == 8080) == 2`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			got, err := e.Eval(lookupIn(values))
+			if err != nil {
+				t.Fatalf("Eval() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFunctionErrors(t *testing.T) {
+	values := map[string]string{"PORT": "http"}
+
+	tests := []struct {
+		expr    string
+		errPart string
+	}{
+		{`number(PORT) > 1`, "PORT is not a number"},
+		{`count(PORT) == 1`, "PORT is not true or false"},
+		{`contains(["a"], "a")`, `["a"] is not a string`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if _, err := e.Eval(lookupIn(values)); err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Eval() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	want := "contains, count, defined, ends_with, if, len, lower, matches, number, starts_with, trim, upper"
+	if got := functionNames(); got != want {
+		t.Errorf("functionNames() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,444 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parser is a recursive-descent parser over an expression.
+type parser struct {
+	expr  string
+	pos   int
+	depth int
+	keys  []string
+}
+
+// errorf builds a syntax error pointing at the current offset.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(errSyntax, p.expr, fmt.Sprintf(format, args...), p.pos)
+}
+
+// peek returns the current byte, or 0 at the end of input.
+func (p *parser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+	return 0
+}
+
+// skipSpaces advances past blanks and line breaks.
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.expr) && strings.IndexByte(" \t\r\n", p.expr[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// consume skips blanks and consumes token if it comes next.
+func (p *parser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.expr[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// consumeWord consumes the keyword word if it comes next as a whole word.
+func (p *parser) consumeWord(word string) bool {
+	p.skipSpaces()
+	end := p.pos + len(word)
+	if strings.HasPrefix(p.expr[p.pos:], word) && (end == len(p.expr) || !isNameChar(p.expr[end])) {
+		p.pos = end
+		return true
+	}
+	return false
+}
+
+// expect consumes token or fails.
+func (p *parser) expect(token string) error {
+	if p.consume(token) {
+		return nil
+	}
+	if p.pos >= len(p.expr) {
+		return p.errorf("expected %q, got end of expression", token)
+	}
+	return p.errorf("expected %q, got %q", token, p.expr[p.pos])
+}
+
+// enter guards against unbounded nesting; leave must be deferred.
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return p.errorf("expression nested more than %d levels deep", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() { p.depth-- }
+
+// spanFrom returns the source text from start to the current offset.
+func (p *parser) spanFrom(start int) span {
+	return span(strings.TrimSpace(p.expr[start:p.pos]))
+}
+
+// addKey records a key the expression refers to.
+func (p *parser) addKey(name string) {
+	for _, key := range p.keys {
+		if key == name {
+			return
+		}
+	}
+	p.keys = append(p.keys, name)
+}
+
+// parseOr parses "a || b", the lowest-precedence operator.
+func (p *parser) parseOr() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	p.skipSpaces()
+	start := p.pos
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{span: p.spanFrom(start), op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses "a && b".
+func (p *parser) parseAnd() (node, error) {
+	p.skipSpaces()
+	start := p.pos
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{span: p.spanFrom(start), op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+// comparisonOperators lists the comparison operators, longest first.
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseComparison parses "a op b", "a =~ re", "a in [...]" or a single operand.
+func (p *parser) parseComparison() (node, error) {
+	p.skipSpaces()
+	start := p.pos
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.consume("=~") {
+		return p.parseMatch(start, left, false)
+	}
+	if p.consume("!~") {
+		return p.parseMatch(start, left, true)
+	}
+	if p.consumeWord("in") {
+		p.skipSpaces()
+		if p.peek() != '[' {
+			return nil, p.errorf("expected a list such as [\"a\", \"b\"] after in")
+		}
+		list, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return inNode{span: p.spanFrom(start), left: left, list: list}, nil
+	}
+	for _, op := range comparisonOperators {
+		if p.consume(op) {
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return compareNode{span: p.spanFrom(start), op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+// parseMatch parses the regular expression literal after =~ or !~.
+func (p *parser) parseMatch(start int, left node, negate bool) (node, error) {
+	re, err := p.parseRegexp()
+	if err != nil {
+		return nil, err
+	}
+	return matchNode{span: p.spanFrom(start), negate: negate, left: left, re: re}, nil
+}
+
+// parseRegexp parses a quoted regular expression and compiles it.
+func (p *parser) parseRegexp() (*regexp.Regexp, error) {
+	p.skipSpaces()
+	if c := p.peek(); c != '"' && c != '\'' {
+		return nil, p.errorf("expected a quoted regular expression")
+	}
+	start := p.pos
+	pattern, err := p.readQuoted(true)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid regular expression %q: %v", pattern, err)
+	}
+	return re, nil
+}
+
+// parseUnary parses "!a", "-a" or a primary expression.
+func (p *parser) parseUnary() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	p.skipSpaces()
+	start := p.pos
+	switch {
+	case p.peek() == '!' && !strings.HasPrefix(p.expr[p.pos:], "!=") && !strings.HasPrefix(p.expr[p.pos:], "!~"):
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{span: p.spanFrom(start), inner: inner}, nil
+	case p.peek() == '-':
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := inner.(literalNode); ok && lit.v.kind == kindNumber {
+			return literalNode{span: p.spanFrom(start), v: numberValue(-lit.v.num)}, nil
+		}
+		return negNode{span: p.spanFrom(start), inner: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses literals, keys, lists, calls and parenthesized expressions.
+func (p *parser) parsePrimary() (node, error) {
+	p.skipSpaces()
+	start := p.pos
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, p.errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case c == '[':
+		p.pos++
+		items, err := p.parseArgs("]")
+		if err != nil {
+			return nil, err
+		}
+		return listNode{span: p.spanFrom(start), items: items}, nil
+	case c == '"' || c == '\'':
+		s, err := p.readQuoted(false)
+		if err != nil {
+			return nil, err
+		}
+		return literalNode{span: p.spanFrom(start), v: stringValue(s)}, nil
+	case c == '`':
+		p.pos++
+		end := strings.IndexByte(p.expr[p.pos:], '`')
+		if end <= 0 {
+			return nil, p.errorf("expected a key name closed by '`'")
+		}
+		name := p.expr[p.pos : p.pos+end]
+		p.pos += end + 1
+		p.addKey(name)
+		return keyNode{span: p.spanFrom(start), name: name}, nil
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.expr) && strings.IndexByte("0123456789.eE", p.expr[p.pos]) >= 0 {
+			p.pos++
+		}
+		text := p.expr[start:p.pos]
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("invalid number %q", text)
+		}
+		return literalNode{span: p.spanFrom(start), v: numberValue(n)}, nil
+	case isNameStart(c):
+		for p.pos < len(p.expr) && isNameChar(p.expr[p.pos]) {
+			p.pos++
+		}
+		name := p.expr[start:p.pos]
+		switch name {
+		case "true", "false":
+			return literalNode{span: p.spanFrom(start), v: boolValue(name == "true")}, nil
+		}
+		p.skipSpaces()
+		if p.peek() == '(' {
+			p.pos++
+			return p.parseCall(start, name)
+		}
+		p.addKey(name)
+		return keyNode{span: p.spanFrom(start), name: name}, nil
+	}
+	return nil, p.errorf("unexpected %q", c)
+}
+
+// parseCall parses the arguments of a call to name; the '(' is consumed.
+func (p *parser) parseCall(start int, name string) (node, error) {
+	switch name {
+	case "defined":
+		p.skipSpaces()
+		keyStart := p.pos
+		key, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		keyRef, ok := key.(keyNode)
+		if !ok {
+			p.pos = keyStart
+			return nil, p.errorf("defined expects a key name")
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return definedNode{span: p.spanFrom(start), name: keyRef.name}, nil
+
+	case "matches":
+		s, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		re, err := p.parseRegexp()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return matchNode{span: p.spanFrom(start), left: s, re: re}, nil
+	}
+
+	args, err := p.parseArgs(")")
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "if" {
+		if len(args) != 3 {
+			return nil, p.errorf("if expects 3 arguments, got %d", len(args))
+		}
+		return ifNode{span: p.spanFrom(start), cond: args[0], then: args[1], otherwise: args[2]}, nil
+	}
+
+	fn, ok := functions[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %q (expected one of %s)", name, functionNames())
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		want := strconv.Itoa(fn.minArgs)
+		switch {
+		case fn.maxArgs < 0:
+			want = "at least " + want
+		case fn.maxArgs != fn.minArgs:
+			want = fmt.Sprintf("%d to %d", fn.minArgs, fn.maxArgs)
+		}
+		return nil, p.errorf("%s expects %s argument(s), got %d", name, want, len(args))
+	}
+	return callNode{span: p.spanFrom(start), fn: fn, args: args}, nil
+}
+
+// parseArgs parses comma-separated expressions up to the closing token.
+func (p *parser) parseArgs(closing string) ([]node, error) {
+	var args []node
+	if p.consume(closing) {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.consume(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readQuoted reads a single- or double-quoted string with backslash escapes
+// (\n, \t and an escaped quote or backslash). In raw mode, used for regular
+// expressions, only an escaped quote is unescaped so \d and \. reach the
+// regular expression intact.
+func (p *parser) readQuoted(raw bool) (string, error) {
+	quote := p.expr[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.expr) && raw:
+			if p.expr[p.pos+1] != quote {
+				b.WriteByte(c)
+			}
+			b.WriteByte(p.expr[p.pos+1])
+			p.pos += 2
+		case c == '\\' && p.pos+1 < len(p.expr):
+			switch next := p.expr[p.pos+1]; next {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(next)
+			}
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// isNameStart reports whether c may start a key or function name.
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isNameChar reports whether c may appear in a key or function name.
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
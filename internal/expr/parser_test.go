@@ -0,0 +1,66 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		errPart string
+	}{
+		{``, "empty expression"},
+		{`A ==`, "unexpected end of expression"},
+		{`A = "x"`, `unexpected '='`},
+		{`(A == "x"`, `expected ")", got end of expression`},
+		{`"unterminated`, "unterminated string"},
+		{`lenght(A) > 1`, `unknown function "lenght" (expected one of contains, count, defined, ends_with, if, len, lower, matches, number, starts_with, trim, upper)`},
+		{`len(A, B) > 1`, "len expects 1 argument(s), got 2"},
+		{`count() > 1`, "count expects at least 1 argument(s), got 0"},
+		{`if(A, B)`, "if expects 3 arguments, got 2"},
+		{`defined("A")`, "defined expects a key name"},
+		{`A =~ "("`, `invalid regular expression "("`},
+		{`A =~ B`, "expected a quoted regular expression"},
+		{`A in B`, "expected a list"},
+		{`1.2.3 == A`, `invalid number "1.2.3"`},
+		{"`` == A", "expected a key name closed by '`'"},
+		{`A == "x" B`, `unexpected 'B'`},
+		{strings.Repeat("(", 100) + "A" + strings.Repeat(")", 100), "nested more than 64 levels deep"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Parse() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestReadQuotedRaw(t *testing.T) {
+	tests := []struct {
+		src  string
+		raw  bool
+		want string
+	}{
+		{`"a\"b"`, false, `a"b`},
+		{`"a\nb"`, false, "a\nb"},
+		{`'\d+\.x'`, false, `d+.x`},
+		{`'\d+\.x'`, true, `\d+\.x`},
+		{`"\"quoted\""`, true, `"quoted"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			p := &parser{expr: tt.src}
+			got, err := p.readQuoted(tt.raw)
+			if err != nil {
+				t.Fatalf("readQuoted() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readQuoted() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
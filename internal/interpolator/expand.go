@@ -0,0 +1,201 @@
+package interpolator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// resolveFunc resolves a variable, reporting where its value came from and
+// whether it is set, or an error if it may not be read.
+type resolveFunc func(name string) (value, source string, ok bool, err error)
+
+// evaluator expands a parsed value. Variables set with = or := are kept in
+// assigned for the rest of the value, without touching the lookup. record is
+// told where every reference was resolved from.
+type evaluator struct {
+	resolve  resolveFunc
+	assigned map[string]string
+	strict   bool
+	record   func(name, source string)
+}
+
+// get resolves name, preferring a value assigned earlier in the same value.
+func (ev *evaluator) get(name string) (string, string, bool, error) {
+	if value, ok := ev.assigned[name]; ok {
+		return value, SourceDefault, true, nil
+	}
+	return ev.resolve(name)
+}
+
+// expandWord expands every expansion in w and joins the result.
+func (ev *evaluator) expandWord(w word) (string, error) {
+	var b strings.Builder
+	for _, p := range w {
+		switch p := p.(type) {
+		case literal:
+			b.WriteString(string(p))
+		case *expansion:
+			expanded, err := ev.expand(p)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(expanded)
+		}
+	}
+	return b.String(), nil
+}
+
+// expand evaluates one expansion. Its words are only expanded when the
+// operator uses them.
+func (ev *evaluator) expand(e *expansion) (string, error) {
+	value, source, set, err := ev.get(e.name)
+	if err != nil {
+		return "", err
+	}
+	// With ':' the operators treat an empty value like an unset one
+	present := set && (value != "" || !strings.HasPrefix(e.op, ":"))
+
+	if err := ev.note(e, source, set, present); err != nil {
+		return "", err
+	}
+
+	if e.length {
+		return strconv.Itoa(utf8.RuneCountInString(value)), nil
+	}
+
+	switch e.op {
+	case opDefault, opDefaultEmpty:
+		if present {
+			return value, nil
+		}
+		return ev.expandWord(e.word)
+	case opAssign, opAssignEmpty:
+		if present {
+			return value, nil
+		}
+		assigned, err := ev.expandWord(e.word)
+		if err != nil {
+			return "", err
+		}
+		ev.assigned[e.name] = assigned
+		return assigned, nil
+	case opError, opErrorEmpty:
+		if present {
+			return value, nil
+		}
+		msg, err := ev.expandWord(e.word)
+		if err != nil {
+			return "", err
+		}
+		if msg == "" {
+			msg = fmt.Sprintf(errNotSet, e.name)
+		}
+		return "", fmt.Errorf(errRequired, msg)
+	case opAlt, opAltEmpty:
+		if present {
+			return ev.expandWord(e.word)
+		}
+		return "", nil
+	case opSubstring:
+		return substring(value, e.offset, e.count, e.hasCount), nil
+	case opTrimPrefix, opTrimPrefixMax, opTrimSuffix, opTrimSuffixMax:
+		p, err := ev.pattern(e.word)
+		if err != nil {
+			return "", err
+		}
+		if e.op == opTrimPrefix || e.op == opTrimPrefixMax {
+			return p.trimPrefix(value, e.op == opTrimPrefixMax), nil
+		}
+		return p.trimSuffix(value, e.op == opTrimSuffixMax), nil
+	case opReplace, opReplaceAll, opReplacePrefix, opReplaceSuffix:
+		p, err := ev.pattern(e.word)
+		if err != nil {
+			return "", err
+		}
+		repl, err := ev.expandWord(e.repl)
+		if err != nil {
+			return "", err
+		}
+		var anchor byte
+		if e.op == opReplacePrefix || e.op == opReplaceSuffix {
+			anchor = e.op[1]
+		}
+		return p.replace(value, repl, e.op == opReplaceAll, anchor), nil
+	default:
+		return value, nil
+	}
+}
+
+// note records where e was resolved from: its source when the value is used,
+// SourceDefault when the default word replaces it and SourceUndefined when the
+// variable is not set. In strict mode an unset variable is an error unless the
+// operator provides for it.
+func (ev *evaluator) note(e *expansion, source string, set, present bool) error {
+	switch {
+	case present || (set && !hasDefault(e.op)):
+		ev.record(e.name, source)
+	case hasDefault(e.op):
+		ev.record(e.name, SourceDefault)
+	default:
+		ev.record(e.name, SourceUndefined)
+		if ev.strict && !isConditional(e.op) {
+			return fmt.Errorf(errUndefined, e.name, e.name, e.name)
+		}
+	}
+	return nil
+}
+
+// hasDefault reports whether op substitutes a default word for an unset value.
+func hasDefault(op string) bool {
+	return op == opDefault || op == opDefaultEmpty || op == opAssign || op == opAssignEmpty
+}
+
+// isConditional reports whether op itself handles an unset value, with an
+// alternative or an error of its own.
+func isConditional(op string) bool {
+	return op == opAlt || op == opAltEmpty || op == opError || op == opErrorEmpty
+}
+
+// pattern expands w and compiles it as a shell pattern.
+func (ev *evaluator) pattern(w word) (*pattern, error) {
+	src, err := ev.expandWord(w)
+	if err != nil {
+		return nil, err
+	}
+	return compilePattern(src)
+}
+
+// substring returns count characters of value from offset, or the rest of it
+// without hasCount. A negative offset counts from the end, as does a negative
+// count for where the substring ends; out of range parts are empty.
+func substring(value string, offset, count int, hasCount bool) string {
+	runes := []rune(value)
+	n := len(runes)
+	if offset < 0 {
+		offset += n
+		if offset < 0 {
+			return ""
+		}
+	}
+	if offset > n {
+		return ""
+	}
+
+	end := n
+	if hasCount {
+		if count < 0 {
+			end = n + count
+		} else {
+			end = offset + count
+		}
+		if end > n {
+			end = n
+		}
+		if end < offset {
+			return ""
+		}
+	}
+	return string(runes[offset:end])
+}
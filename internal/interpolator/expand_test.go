@@ -0,0 +1,66 @@
+package interpolator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"TAG": "v1.2.3", "EMPTY": "", "NAME": "héllo"}
+	ip := NewWithLookup(func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	})
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"${NAME:1:3}", "éll"},
+		{"${NAME: -2}", "lo"},
+		{"${NAME:1:-1}", "éll"},
+		{"${NAME:9}", ""},
+		{"${NAME: -9}", ""},
+		{"${NAME:3:-3}", ""},
+		{"${#NAME}${#UNSET}", "50"},
+		{"${TAG#v}", "1.2.3"},
+		{"${TAG%.*}", "v1.2"},
+		{"${TAG/./-}", "v1-2.3"},
+		{"${TAG//./-}", "v1-2-3"},
+		{"${TAG/#v/V}", "V1.2.3"},
+		{"${TAG/%3/4}", "v1.2.4"},
+		{"${TAG//[0-9]}", "v.."},
+		{"${TAG#${UNSET:-v1}}", ".2.3"},
+		{"${EMPTY:=x}${EMPTY}", "xx"},
+		{"${EMPTY=x}${EMPTY}", ""},
+		{"${UNSET-}|${UNSET:+alt}", "|"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ip.Interpolate(tt.value)
+			if err != nil {
+				t.Fatalf("Interpolate() unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Interpolate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("Assignment stays in the value", func(t *testing.T) {
+		if _, err := ip.Interpolate("${UNSET:=x}"); err != nil {
+			t.Fatalf("Interpolate() unexpected error: %v", err)
+		}
+		if got, _ := ip.Interpolate("${UNSET}"); got != "" {
+			t.Errorf("Interpolate() = %q, want %q", got, "")
+		}
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		_, err := ip.Interpolate("${TAG#[z-a]}")
+		if err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+			t.Errorf("Interpolate() error = %v, want invalid pattern", err)
+		}
+	})
+}
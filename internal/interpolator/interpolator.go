@@ -3,78 +3,208 @@ package interpolator
 import (
 	"fmt"
 	"os"
-	"regexp"
 	"strings"
 )
 
-// Pattern matches ${VAR}, ${VAR:-default}, ${VAR:?error}
-var interpolationPattern = regexp.MustCompile(`\$\{([^}:]+)(?:(:[-?])([^}]*))?\}`)
+// Error messages
+const (
+	errRequired   = "interpolation error: %s"
+	errNotSet     = "variable %s is not set"
+	errUndefined  = "variable %s is not set and has no default (use ${%s:-default} or ${%s-})"
+	errCycle      = "variables refer to each other in a cycle: %s"
+	errPairFailed = "interpolation failed for %s: %w"
+)
+
+// Where a reference was resolved from, as reported in a Resolution
+const (
+	SourceKey       = "key"       // Another key of InterpolatePairs
+	SourceVariable  = "variable"  // Options.Variables
+	SourceEnv       = "env"       // The lookup, by default the process environment
+	SourceDefault   = "default"   // The default word of -, :-, = or :=
+	SourceUndefined = "undefined" // Nothing: the variable is not set
+)
+
+// LookupFunc resolves a variable name to its value, reporting whether it is set.
+type LookupFunc func(name string) (string, bool)
+
+// Options configures an Interpolator.
+type Options struct {
+	Lookup LookupFunc // Resolves variables; nil means the process environment
+	Strict bool       // Fail on a reference to an unset variable that has no default
+
+	// Variables are resolved before Lookup and, like the keys of
+	// InterpolatePairs, are not subject to Allow and Deny
+	Variables map[string]string
+
+	// Allow and Deny are globs of the names Lookup may resolve (see
+	// ParseNamePatterns). A name must match Allow, if it is not empty, and
+	// must not match Deny or the built-in denylist of runner credentials
+	Allow, Deny []string
+}
+
+// Resolution records how a variable reference was resolved.
+type Resolution struct {
+	Key    string // The key whose value has the reference; empty outside InterpolatePairs
+	Name   string // The variable referred to
+	Source string // SourceKey, SourceVariable, SourceEnv, SourceDefault or SourceUndefined
+}
 
 // Interpolator handles variable interpolation in values.
-type Interpolator struct{}
+type Interpolator struct {
+	lookup      LookupFunc
+	variables   map[string]string
+	allow, deny []string
+	strict      bool
+	resolutions []Resolution
+	recorded    map[Resolution]bool
+}
 
-// New creates a new Interpolator instance.
+// New creates a new Interpolator instance that resolves variables from the
+// process environment.
 func New() *Interpolator {
-	return &Interpolator{}
+	return NewWithOptions(Options{})
 }
 
-// Interpolate processes a string and replaces variable references with their values.
-// Supported syntax:
-//   - ${VAR} - replaced with env var value, empty string if not set
-//   - ${VAR:-default} - replaced with env var value, or default if not set
-//   - ${VAR:?error} - replaced with env var value, or returns error if not set
-func (ip *Interpolator) Interpolate(value string) (string, error) {
-	if !strings.Contains(value, "${") {
-		return value, nil
+// NewWithLookup creates a new Interpolator instance that resolves variables
+// through lookup instead of the process environment.
+func NewWithLookup(lookup LookupFunc) *Interpolator {
+	return NewWithOptions(Options{Lookup: lookup})
+}
+
+// NewWithOptions creates a new Interpolator instance configured by opts.
+func NewWithOptions(opts Options) *Interpolator {
+	lookup := opts.Lookup
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return &Interpolator{
+		lookup:    lookup,
+		variables: opts.Variables,
+		allow:     opts.Allow,
+		deny:      opts.Deny,
+		strict:    opts.Strict,
+		recorded:  make(map[Resolution]bool),
 	}
+}
 
-	var interpolationErr error
+// resolve resolves name from the variables, then through the lookup if the
+// allowlist and denylists let it.
+func (ip *Interpolator) resolve(name string) (string, string, bool, error) {
+	if value, ok := ip.variables[name]; ok {
+		return value, SourceVariable, true, nil
+	}
+	if err := CheckAllowed(name, ip.allow, ip.deny); err != nil {
+		return "", "", false, err
+	}
+	value, ok := ip.lookup(name)
+	return value, SourceEnv, ok, nil
+}
 
-	result := interpolationPattern.ReplaceAllStringFunc(value, func(match string) string {
-		if interpolationErr != nil {
-			return match
-		}
+// Resolutions returns every variable reference resolved so far, once per key,
+// variable and source, in the order they were resolved.
+func (ip *Interpolator) Resolutions() []Resolution {
+	return append([]Resolution(nil), ip.resolutions...)
+}
 
-		submatch := interpolationPattern.FindStringSubmatch(match)
-		if len(submatch) < 2 {
-			return match
+// Undefined returns the variables that were referred to while not set, in the
+// order they were first found.
+func (ip *Interpolator) Undefined() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, r := range ip.resolutions {
+		if r.Source == SourceUndefined && !seen[r.Name] {
+			seen[r.Name] = true
+			names = append(names, r.Name)
 		}
+	}
+	return names
+}
+
+// record adds a Resolution unless the same one was recorded already.
+func (ip *Interpolator) record(key, name, source string) {
+	r := Resolution{Key: key, Name: name, Source: source}
+	if !ip.recorded[r] {
+		ip.recorded[r] = true
+		ip.resolutions = append(ip.resolutions, r)
+	}
+}
 
-		varName := strings.TrimSpace(submatch[1])
-		operator := submatch[2]
-		operand := submatch[3]
+// Interpolate processes a string and replaces variable references with their
+// values, following POSIX shell parameter expansion:
+//   - $VAR, ${VAR} - the value, empty if not set; $$ is a literal $
+//   - ${VAR-word}, ${VAR:-word} - word if VAR is not set (or, with ':', empty)
+//   - ${VAR=word}, ${VAR:=word} - the same, and VAR is word for the rest of the value
+//   - ${VAR?msg}, ${VAR:?msg} - an error with msg if VAR is not set (or empty)
+//   - ${VAR+word}, ${VAR:+word} - word if VAR is set (and, with ':', not empty)
+//   - ${VAR:offset}, ${VAR:offset:length} - a substring, counted in characters
+//   - ${#VAR} - the length of the value in characters
+//   - ${VAR#pattern}, ${VAR##pattern} - without the shortest (longest) matching prefix
+//   - ${VAR%pattern}, ${VAR%%pattern} - without the shortest (longest) matching suffix
+//   - ${VAR/pattern/string}, ${VAR//pattern/string} - the first (every) match replaced;
+//     /# and /% only replace a match at the start or end
+//
+// Words may contain further expansions, as in ${A:-${B:-c}}, and are only
+// expanded when they are used. A "${" without a closing "}" is left as it is.
+// In strict mode, a reference to an unset variable without a default (-, :-,
+// =, :=, + or :+) is an error instead of an empty string. A reference to a
+// variable the allowlist or denylists block is always an error naming it.
+func (ip *Interpolator) Interpolate(value string) (string, error) {
+	return ip.interpolate("", value, ip.resolve)
+}
+
+// interpolate expands value, the value of key, resolving variables through
+// resolve.
+func (ip *Interpolator) interpolate(key, value string, resolve resolveFunc) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	w, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	ev := &evaluator{
+		resolve:  resolve,
+		assigned: make(map[string]string),
+		strict:   ip.strict,
+		record: func(name, source string) {
+			ip.record(key, name, source)
+		},
+	}
+	return ev.expandWord(w)
+}
 
-		envValue := os.Getenv(varName)
+// References returns the names of the variables value refers to, including
+// those in defaults and other words, in the order they first appear. A value
+// that does not parse refers to nothing.
+func References(value string) []string {
+	if !strings.Contains(value, "$") {
+		return nil
+	}
+	w, err := parse(value)
+	if err != nil {
+		return nil
+	}
 
-		switch operator {
-		case ":-":
-			// Default value: use operand if env var is empty
-			if envValue == "" {
-				return operand
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(w word)
+	walk = func(w word) {
+		for _, p := range w {
+			e, ok := p.(*expansion)
+			if !ok {
+				continue
 			}
-			return envValue
-		case ":?":
-			// Error if empty: return error with operand as message
-			if envValue == "" {
-				msg := operand
-				if msg == "" {
-					msg = fmt.Sprintf("variable %s is not set", varName)
-				}
-				interpolationErr = fmt.Errorf("interpolation error: %s", msg)
-				return match
+			if !seen[e.name] {
+				seen[e.name] = true
+				names = append(names, e.name)
 			}
-			return envValue
-		default:
-			// Simple substitution: ${VAR}
-			return envValue
+			walk(e.word)
+			walk(e.repl)
 		}
-	})
-
-	if interpolationErr != nil {
-		return "", interpolationErr
 	}
-
-	return result, nil
+	walk(w)
+	return names
 }
 
 // InterpolateList processes a list of strings and interpolates variables in each.
@@ -89,3 +219,90 @@ func (ip *Interpolator) InterpolateList(values []string) ([]string, error) {
 	}
 	return result, nil
 }
+
+// InterpolatePairs interpolates values, where values[i] is the value of
+// keys[i] and a reference may name another key of the set: ${REGISTRY}/app
+// resolves REGISTRY to its final value in the set before falling back to
+// the Interpolator's lookup. Values are resolved in dependency order, so the
+// order of the keys does not matter, and values that refer to each other are
+// an error naming the cycle. A value referring to its own key, as in
+// PATH=${PATH}:/opt/bin, reads the lookup instead. When a key is set more
+// than once, its first value is the one other values see.
+func (ip *Interpolator) InterpolatePairs(keys, values []string) ([]string, error) {
+	result := append([]string(nil), values...)
+
+	first := make(map[string]int)
+	for i, key := range keys {
+		if i >= len(values) {
+			break
+		}
+		if _, ok := first[key]; !ok {
+			first[key] = i
+		}
+	}
+
+	resolved := make(map[string]string)
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[int]int)
+	var path []int
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			var cycle []string
+			for j := len(path) - 1; j >= 0; j-- {
+				cycle = append([]string{keys[path[j]]}, cycle...)
+				if path[j] == i {
+					break
+				}
+			}
+			return fmt.Errorf(errCycle, strings.Join(append(cycle, keys[i]), " -> "))
+		}
+
+		state[i] = visiting
+		path = append(path, i)
+		for _, name := range References(values[i]) {
+			if j, ok := first[name]; ok && name != keys[i] {
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+
+		self := keys[i]
+		interpolated, err := ip.interpolate(self, values[i], func(name string) (string, string, bool, error) {
+			if name != self {
+				if value, ok := resolved[name]; ok {
+					return value, SourceKey, true, nil
+				}
+			}
+			return ip.resolve(name)
+		})
+		if err != nil {
+			return fmt.Errorf(errPairFailed, keys[i], err)
+		}
+		result[i] = interpolated
+		if first[keys[i]] == i {
+			resolved[keys[i]] = interpolated
+		}
+		state[i] = done
+		return nil
+	}
+
+	for i := range keys {
+		if i >= len(values) {
+			break
+		}
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
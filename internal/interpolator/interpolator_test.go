@@ -1,6 +1,8 @@
 package interpolator
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -85,6 +87,60 @@ func TestInterpolate(t *testing.T) {
 			value:    "${incomplete",
 			expected: "${incomplete",
 		},
+		{
+			name:     "Bare variable and escaped dollar",
+			value:    "$HOST:$$PORT $ 5$",
+			envVars:  map[string]string{"HOST": "localhost"},
+			expected: "localhost:$PORT $ 5$",
+		},
+		{
+			name:     "Default only when unset",
+			value:    "[${EMPTY_VAR-unset}][${EMPTY_VAR:-empty}][${UNSET_VAR-unset}]",
+			envVars:  map[string]string{"EMPTY_VAR": ""},
+			expected: "[][empty][unset]",
+		},
+		{
+			name:     "Alternative value",
+			value:    "${MY_VAR:+--flag=$MY_VAR}${EMPTY_VAR+set}${EMPTY_VAR:+set-and-not-empty}${UNSET_VAR+x}",
+			envVars:  map[string]string{"MY_VAR": "on", "EMPTY_VAR": ""},
+			expected: "--flag=onset",
+		},
+		{
+			name:     "Assignment lasts for the rest of the value",
+			value:    "${UNSET_VAR:=v1}-${UNSET_VAR}",
+			expected: "v1-v1",
+		},
+		{
+			name:     "Nested defaults",
+			value:    "${UNSET_VAR:-${OTHER_UNSET:-${MY_VAR}}}",
+			envVars:  map[string]string{"MY_VAR": "deep"},
+			expected: "deep",
+		},
+		{
+			name:     "Substring, length, trimming and replacing",
+			value:    `${SHA:0:7} ${#SHA} ${REF#refs/heads/} ${FILE%.*} ${FILE%%.*} ${FILE##*.} ${REF//\//-}`,
+			envVars:  map[string]string{"SHA": "0123456789abcdef", "REF": "refs/heads/feature/x", "FILE": "app.tar.gz"},
+			expected: "0123456 16 feature/x app.tar app gz refs-heads-feature-x",
+		},
+		{
+			name:     "Unused words are not expanded",
+			value:    "${MY_VAR:-${UNSET_VAR:?not evaluated}}",
+			envVars:  map[string]string{"MY_VAR": "set"},
+			expected: "set",
+		},
+		{
+			name:      "Required only when unset",
+			value:     "${EMPTY_VAR?}${UNSET_VAR?gone}",
+			envVars:   map[string]string{"EMPTY_VAR": ""},
+			wantError: true,
+			errMsg:    "interpolation error: gone",
+		},
+		{
+			name:      "Bad substitution",
+			value:     "ok ${MY.VAR}",
+			wantError: true,
+			errMsg:    "bad substitution at offset 7: unexpected '.' after ${MY",
+		},
 	}
 
 	for _, tt := range tests {
@@ -170,6 +226,191 @@ func TestInterpolateList(t *testing.T) {
 	}
 }
 
+func TestNewWithLookup(t *testing.T) {
+	vars := map[string]string{"REGISTRY": "ghcr.io"}
+	ip := NewWithLookup(func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	})
+
+	t.Setenv("REGISTRY", "docker.io")
+	result, err := ip.Interpolate("${REGISTRY}/app:${TAG:-latest}")
+	if err != nil {
+		t.Fatalf("Interpolate() unexpected error: %v", err)
+	}
+	if result != "ghcr.io/app:latest" {
+		t.Errorf("Interpolate() = %q, want %q", result, "ghcr.io/app:latest")
+	}
+
+	t.Run("nil lookup falls back to environment", func(t *testing.T) {
+		t.Setenv("FALLBACK_VAR", "env")
+		result, err := NewWithLookup(nil).Interpolate("${FALLBACK_VAR}")
+		if err != nil || result != "env" {
+			t.Errorf("Interpolate() = %q, %v, want %q", result, err, "env")
+		}
+	})
+}
+
+func TestReferences(t *testing.T) {
+	got := References("${A}/${B:-x}/${A}/${ C :?msg}/$D/$$E/${F:-${G}}/${H//x/$I}")
+	want := []string{"A", "B", "C", "D", "F", "G", "H", "I"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("References() = %q, want %q", got, want)
+	}
+}
+
+func TestInterpolatePairs(t *testing.T) {
+	env := map[string]string{"PATH": "/usr/bin", "REGISTRY": "docker.io", "SHA": "abc123"}
+	ip := NewWithLookup(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	})
+
+	tests := []struct {
+		name     string
+		keys     []string
+		values   []string
+		expected []string
+	}{
+		{
+			name:     "Earlier key",
+			keys:     []string{"REGISTRY", "IMAGE"},
+			values:   []string{"ghcr.io/acme", "${REGISTRY}/app:${SHA}"},
+			expected: []string{"ghcr.io/acme", "ghcr.io/acme/app:abc123"},
+		},
+		{
+			name:     "Later key and chain",
+			keys:     []string{"URL", "HOST", "DOMAIN"},
+			values:   []string{"https://${HOST}/", "app.${DOMAIN}", "example.com"},
+			expected: []string{"https://app.example.com/", "app.example.com", "example.com"},
+		},
+		{
+			name:     "Own key reads the lookup",
+			keys:     []string{"PATH"},
+			values:   []string{"${PATH}:/opt/bin"},
+			expected: []string{"/usr/bin:/opt/bin"},
+		},
+		{
+			name:     "Defaults see keys",
+			keys:     []string{"EMPTY", "A"},
+			values:   []string{"", "${EMPTY:-fallback}-${MISSING:-none}"},
+			expected: []string{"", "fallback-none"},
+		},
+		{
+			name:     "First of repeated keys wins",
+			keys:     []string{"A", "A", "B"},
+			values:   []string{"one", "two", "${A}"},
+			expected: []string{"one", "two", "one"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ip.InterpolatePairs(tt.keys, tt.values)
+			if err != nil {
+				t.Fatalf("InterpolatePairs() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("InterpolatePairs() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInterpolatePairsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		values  []string
+		errPart string
+	}{
+		{"Cycle", []string{"A", "B", "C"}, []string{"${B}", "${C}", "x${A}"}, "variables refer to each other in a cycle: A -> B -> C -> A"},
+		{"Cycle among later keys", []string{"X", "A", "B"}, []string{"${A}", "${B}", "${A}"}, "cycle: A -> B -> A"},
+		{"Required value", []string{"A", "B"}, []string{"", "${A:?A must be set}"}, "interpolation failed for B: interpolation error: A must be set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWithLookup(func(string) (string, bool) { return "", false }).InterpolatePairs(tt.keys, tt.values)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("InterpolatePairs() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	vars := map[string]string{"SET": "v", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		value    string
+		expected string
+		errPart  string
+	}{
+		{value: "${SET}-$EMPTY", expected: "v-"},
+		{value: "${TYPO}", errPart: "variable TYPO is not set and has no default (use ${TYPO:-default} or ${TYPO-})"},
+		{value: "$TYPO", errPart: "variable TYPO is not set"},
+		{value: "${#TYPO}", errPart: "variable TYPO is not set"},
+		{value: "${TYPO#x}", errPart: "variable TYPO is not set"},
+		{value: "${TYPO:-${OTHER}}", errPart: "variable OTHER is not set"},
+		{value: "${TYPO-}${TYPO:=a}${TYPO}${MISSING:+alt}", expected: "aa"},
+		{value: "${MISSING?needed}", errPart: "interpolation error: needed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := NewWithOptions(Options{Lookup: lookup, Strict: true}).Interpolate(tt.value)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("Interpolate() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("Interpolate() = %q, %v, want %q", got, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolutions(t *testing.T) {
+	ip := NewWithLookup(func(name string) (string, bool) {
+		if name == "SHA" {
+			return "abc", true
+		}
+		return "", false
+	})
+
+	if _, err := ip.InterpolatePairs(
+		[]string{"IMAGE", "REGISTRY"},
+		[]string{"${REGISTRY}/app:${SHA}-${SHA}${SUFFIX:-}${TYPO}", "ghcr.io"},
+	); err != nil {
+		t.Fatalf("InterpolatePairs() unexpected error: %v", err)
+	}
+	if _, err := ip.Interpolate("${TYPO:+x}${OTHER}"); err != nil {
+		t.Fatalf("Interpolate() unexpected error: %v", err)
+	}
+
+	expected := []Resolution{
+		{"IMAGE", "REGISTRY", SourceKey},
+		{"IMAGE", "SHA", SourceEnv},
+		{"IMAGE", "SUFFIX", SourceDefault},
+		{"IMAGE", "TYPO", SourceUndefined},
+		{"", "TYPO", SourceUndefined},
+		{"", "OTHER", SourceUndefined},
+	}
+	if got := ip.Resolutions(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Resolutions() = %+v, want %+v", got, expected)
+	}
+	if got := ip.Undefined(); !reflect.DeepEqual(got, []string{"TYPO", "OTHER"}) {
+		t.Errorf("Undefined() = %q, want %q", got, []string{"TYPO", "OTHER"})
+	}
+}
+
 func BenchmarkInterpolate(b *testing.B) {
 	b.Setenv("HOST", "localhost")
 	b.Setenv("PORT", "8080")
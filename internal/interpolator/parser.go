@@ -0,0 +1,324 @@
+package interpolator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errBadSubstitution reports a ${...} expansion that does not parse.
+const errBadSubstitution = "bad substitution at offset %d: %s"
+
+// Expansion operators, as written after the variable name
+const (
+	opValue         = ""   // ${VAR} or $VAR
+	opLength        = "#"  // ${#VAR}, written before the name
+	opDefault       = "-"  // ${VAR-word}: word if VAR is unset
+	opDefaultEmpty  = ":-" // ${VAR:-word}: word if VAR is unset or empty
+	opAssign        = "="  // ${VAR=word}: like -, and VAR is word for the rest of the value
+	opAssignEmpty   = ":=" // ${VAR:=word}: like :-, and VAR is word for the rest of the value
+	opError         = "?"  // ${VAR?word}: fail with word if VAR is unset
+	opErrorEmpty    = ":?" // ${VAR:?word}: fail with word if VAR is unset or empty
+	opAlt           = "+"  // ${VAR+word}: word if VAR is set
+	opAltEmpty      = ":+" // ${VAR:+word}: word if VAR is set and not empty
+	opSubstring     = ":"  // ${VAR:offset} or ${VAR:offset:length}
+	opTrimPrefix    = "#"  // ${VAR#pattern}: remove the shortest matching prefix
+	opTrimPrefixMax = "##" // ${VAR##pattern}: remove the longest matching prefix
+	opTrimSuffix    = "%"  // ${VAR%pattern}: remove the shortest matching suffix
+	opTrimSuffixMax = "%%" // ${VAR%%pattern}: remove the longest matching suffix
+	opReplace       = "/"  // ${VAR/pattern/string}: replace the first match
+	opReplaceAll    = "//" // ${VAR//pattern/string}: replace every match
+	opReplacePrefix = "/#" // ${VAR/#pattern/string}: replace a match at the start
+	opReplaceSuffix = "/%" // ${VAR/%pattern/string}: replace a match at the end
+)
+
+// word is a sequence of literal text and expansions.
+type word []part
+
+// part is a literal or an *expansion.
+type part interface{}
+
+// literal is text copied as it is.
+type literal string
+
+// expansion is one $VAR or ${...} reference. ${#VAR} has op opLength and
+// length set, since "#" also names the prefix removal operator.
+type expansion struct {
+	name     string
+	op       string
+	length   bool
+	word     word // Default, alternative, error message or pattern
+	repl     word // Replacement string of the / operators
+	offset   int  // Substring offset
+	count    int  // Substring length, if hasCount
+	hasCount bool
+}
+
+// parser reads a value into a word.
+type parser struct {
+	src string
+	pos int
+}
+
+// parse parses value. Backslashes are literal at the top level; an unterminated
+// "${" is kept as literal text.
+func parse(value string) (word, error) {
+	p := &parser{src: value}
+	return p.parseWord("", true)
+}
+
+// parseWord reads up to the first unnested byte in terminators, or to the end
+// of the input at the top level. Inside an expansion a backslash makes the
+// next '}', '/', '$' or '\' literal.
+func (p *parser) parseWord(terminators string, top bool) (word, error) {
+	var w word
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			w = append(w, literal(text.String()))
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case !top && strings.IndexByte(terminators, c) >= 0:
+			flush()
+			return w, nil
+		case !top && c == '\\' && p.pos+1 < len(p.src) && strings.IndexByte(`}/$\`, p.src[p.pos+1]) >= 0:
+			text.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == '$':
+			e, lit, err := p.parseDollar(top)
+			if err != nil {
+				return nil, err
+			}
+			if e == nil {
+				text.WriteString(lit)
+				continue
+			}
+			flush()
+			w = append(w, e)
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	if !top {
+		return nil, p.errorf(len(p.src), "missing '}'")
+	}
+	flush()
+	return w, nil
+}
+
+// parseDollar reads what follows a '$': "$$" is a literal '$', "$NAME" and
+// "${...}" are expansions and any other '$' is literal. It returns either the
+// expansion or the literal text.
+func (p *parser) parseDollar(top bool) (*expansion, string, error) {
+	start := p.pos
+	p.pos++ // '$'
+	switch next := p.peek(); {
+	case next == '$':
+		p.pos++
+		return nil, "$", nil
+	case isNameStart(next):
+		return &expansion{name: p.readName()}, "", nil
+	case next != '{':
+		return nil, "$", nil
+	}
+
+	p.pos++ // '{'
+	e, err := p.parseBraced()
+	if err == nil {
+		return e, "", nil
+	}
+	// An unterminated ${ at the top level stays literal, as it always has
+	if top && !p.closes(start) {
+		p.pos = start + 1
+		return nil, "$", nil
+	}
+	return nil, "", err
+}
+
+// closes reports whether the "${" at start has a matching '}'.
+func (p *parser) closes(start int) bool {
+	return ExpansionLength(p.src[start:]) >= 0
+}
+
+// ExpansionLength returns the length of the ${...} expansion s starts with, up
+// to and including its closing '}', or -1 if s does not start with "${" or the
+// expansion is not closed. Nested expansions, such as the default in
+// ${A:-${B}}, are skipped whole, as are "$$" and backslash escapes.
+func ExpansionLength(s string) int {
+	if !strings.HasPrefix(s, "${") {
+		return -1
+	}
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '$':
+			i++
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// parseBraced reads the inside of ${...}, after the '{', up to and
+// including the closing '}'.
+func (p *parser) parseBraced() (*expansion, error) {
+	e := &expansion{}
+	if p.peek() == '#' && p.pos+1 < len(p.src) && isNameStart(p.src[p.pos+1]) {
+		p.pos++
+		e.length = true
+		e.op = opLength
+	}
+
+	p.skipSpace()
+	if !isNameStart(p.peek()) {
+		return nil, p.errorf(p.pos, "expected a variable name")
+	}
+	e.name = p.readName()
+	p.skipSpace()
+
+	if e.length {
+		if p.peek() != '}' {
+			return nil, p.errorf(p.pos, "expected '}' after ${#"+e.name)
+		}
+		p.pos++
+		return e, nil
+	}
+
+	var err error
+	switch c := p.peek(); c {
+	case '}':
+		p.pos++
+		return e, nil
+	case ':':
+		p.pos++
+		switch next := p.peek(); next {
+		case '-', '=', '?', '+':
+			p.pos++
+			e.op = ":" + string(next)
+			e.word, err = p.parseOperand("}")
+		default:
+			err = p.parseSubstring(e)
+		}
+	case '-', '=', '?', '+':
+		p.pos++
+		e.op = string(c)
+		e.word, err = p.parseOperand("}")
+	case '#', '%':
+		p.pos++
+		e.op = string(c)
+		if p.peek() == c {
+			p.pos++
+			e.op += string(c)
+		}
+		e.word, err = p.parseOperand("}")
+	case '/':
+		p.pos++
+		e.op = opReplace
+		if next := p.peek(); next == '/' || next == '#' || next == '%' {
+			p.pos++
+			e.op += string(next)
+		}
+		if e.word, err = p.parseWord("/}", false); err != nil {
+			return nil, err
+		}
+		if p.peek() == '/' {
+			p.pos++
+			e.repl, err = p.parseOperand("}")
+		} else {
+			p.pos++ // '}'
+		}
+	case 0:
+		return nil, p.errorf(p.pos, "missing '}'")
+	default:
+		return nil, p.errorf(p.pos, fmt.Sprintf("unexpected %q after ${%s", c, e.name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseOperand reads a word up to and including the closing '}'.
+func (p *parser) parseOperand(terminators string) (word, error) {
+	w, err := p.parseWord(terminators, false)
+	if err != nil {
+		return nil, err
+	}
+	p.pos++ // '}'
+	return w, nil
+}
+
+// parseSubstring reads "offset}" or "offset:length}" after ${VAR:.
+func (p *parser) parseSubstring(e *expansion) error {
+	end := strings.IndexByte(p.src[p.pos:], '}')
+	if end < 0 {
+		return p.errorf(len(p.src), "missing '}'")
+	}
+	spec := p.src[p.pos : p.pos+end]
+	offset, count, hasCount := spec, "", false
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		offset, count, hasCount = spec[:i], spec[i+1:], true
+	}
+
+	var err error
+	if e.offset, err = strconv.Atoi(strings.TrimSpace(offset)); err != nil {
+		return p.errorf(p.pos, fmt.Sprintf("substring offset %q is not a whole number", strings.TrimSpace(offset)))
+	}
+	if hasCount {
+		if e.count, err = strconv.Atoi(strings.TrimSpace(count)); err != nil {
+			return p.errorf(p.pos, fmt.Sprintf("substring length %q is not a whole number", strings.TrimSpace(count)))
+		}
+	}
+	e.op = opSubstring
+	e.hasCount = hasCount
+	p.pos += end + 1
+	return nil
+}
+
+// errorf reports a syntax error at offset.
+func (p *parser) errorf(offset int, msg string) error {
+	return fmt.Errorf(errBadSubstitution, offset, msg)
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// readName reads a variable name: a letter or '_' followed by letters,
+// digits and underscores.
+func (p *parser) readName() string {
+	start := p.pos
+	for p.pos < len(p.src) && (isNameStart(p.src[p.pos]) || (p.src[p.pos] >= '0' && p.src[p.pos] <= '9')) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
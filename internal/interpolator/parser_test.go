@@ -0,0 +1,132 @@
+package interpolator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected word
+	}{
+		{
+			name:     "Literal",
+			value:    `plain \${x} text`,
+			expected: word{literal(`plain \`), &expansion{name: "x"}, literal(" text")},
+		},
+		{
+			name:     "Bare names and dollars",
+			value:    "$A-$_b1 $$ $1 $",
+			expected: word{&expansion{name: "A"}, literal("-"), &expansion{name: "_b1"}, literal(" $ $1 $")},
+		},
+		{
+			name:     "Length",
+			value:    "${#A}",
+			expected: word{&expansion{name: "A", op: opLength, length: true}},
+		},
+		{
+			name:  "Nested default",
+			value: "${ A :-${B:-c}}",
+			expected: word{&expansion{name: "A", op: opDefaultEmpty, word: word{
+				&expansion{name: "B", op: opDefaultEmpty, word: word{literal("c")}},
+			}}},
+		},
+		{
+			name:     "Escapes in words",
+			value:    `${A-\}\$\\x}`,
+			expected: word{&expansion{name: "A", op: opDefault, word: word{literal(`}$\x`)}}},
+		},
+		{
+			name:     "Substring",
+			value:    "${A:1}${A: -3:2}",
+			expected: word{&expansion{name: "A", op: opSubstring, offset: 1}, &expansion{name: "A", op: opSubstring, offset: -3, count: 2, hasCount: true}},
+		},
+		{
+			name:     "Trim",
+			value:    "${A##*/}",
+			expected: word{&expansion{name: "A", op: opTrimPrefixMax, word: word{literal("*/")}}},
+		},
+		{
+			name:  "Replace",
+			value: "${A/#x}${A//a\\/b/$B}",
+			expected: word{
+				&expansion{name: "A", op: opReplacePrefix, word: word{literal("x")}},
+				&expansion{name: "A", op: opReplaceAll, word: word{literal("a/b")}, repl: word{&expansion{name: "B"}}},
+			},
+		},
+		{
+			name:     "Unterminated",
+			value:    "${A and ${B}",
+			expected: word{literal("${A and "), &expansion{name: "B"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(tt.value)
+			if err != nil {
+				t.Fatalf("parse() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parse() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		value   string
+		errPart string
+	}{
+		{"${}", "offset 2: expected a variable name"},
+		{"${1}", "offset 2: expected a variable name"},
+		{"${A!}", "offset 3: unexpected '!' after ${A"},
+		{"${#A:-x}", "offset 4: expected '}' after ${#A"},
+		{"${A:x}", `substring offset "x" is not a whole number`},
+		{"${A:1:y}", `substring length "y" is not a whole number`},
+		{"${A:-${B}", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			_, err := parse(tt.value)
+			if tt.errPart == "" {
+				if err != nil {
+					t.Fatalf("parse() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("parse() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestExpansionLength(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"${A}", 4},
+		{"${A}rest", 4},
+		{"${A:-${B:-c}}/x", 13},
+		{`${A/\}/x}}`, 9},
+		{"${A:-$${B}}", 10},
+		{"${A:-${B}", -1},
+		{"$A", -1},
+		{"text", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := ExpansionLength(tt.value); got != tt.want {
+				t.Errorf("ExpansionLength(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package interpolator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// errPattern reports a shell pattern that cannot be compiled.
+const errPattern = "invalid pattern %q: %v"
+
+// pattern is a shell glob as used by the #, %, and / operators: '*' matches
+// any text, '?' any one character, [...] a character class ([!...] or [^...]
+// negated, [:alpha:] and the other POSIX classes inside) and '\' makes the
+// next character literal.
+type pattern struct {
+	re *regexp.Regexp // Anchored at both ends
+}
+
+// compilePattern compiles the glob src.
+func compilePattern(src string) (*pattern, error) {
+	var b strings.Builder
+	b.WriteString(`^(?s:`)
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch r {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '\\':
+			if i+size < len(src) {
+				i += size
+				r, size = utf8.DecodeRuneInString(src[i:])
+			}
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		case '[':
+			if class, n, ok := bracketClass(src[i:]); ok {
+				b.WriteString(class)
+				size = n
+				break
+			}
+			b.WriteString(`\[`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+		i += size
+	}
+	b.WriteString(`)$`)
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf(errPattern, src, err)
+	}
+	return &pattern{re: re}, nil
+}
+
+// bracketClass translates the [...] class at the start of src into a regexp
+// class, returning the number of bytes it spans. A '[' without a closing ']'
+// is not a class.
+func bracketClass(src string) (string, int, bool) {
+	var b strings.Builder
+	b.WriteByte('[')
+	i := 1
+	if i < len(src) && (src[i] == '!' || src[i] == '^') {
+		b.WriteByte('^')
+		i++
+	}
+	first := true
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ']' && !first:
+			b.WriteByte(']')
+			return b.String(), i + 1, true
+		case c == '[' && strings.HasPrefix(src[i:], "[:"):
+			end := strings.Index(src[i+2:], ":]")
+			if end < 0 {
+				b.WriteString(`\[`)
+				i++
+				break
+			}
+			b.WriteString(src[i : i+2+end+2])
+			i += 2 + end + 2
+		case c == '\\' && i+1 < len(src):
+			b.WriteString(regexp.QuoteMeta(src[i+1 : i+2]))
+			i += 2
+		case c == '\\' || c == '[' || c == ']' || c == '^':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+		first = false
+	}
+	return "", 0, false
+}
+
+// matches reports whether s matches the whole pattern.
+func (p *pattern) matches(s string) bool {
+	return p.re.MatchString(s)
+}
+
+// runeBoundaries returns the byte offset of every character boundary in s,
+// including 0 and len(s).
+func runeBoundaries(s string) []int {
+	bounds := make([]int, 0, len(s)+1)
+	for i := range s {
+		bounds = append(bounds, i)
+	}
+	return append(bounds, len(s))
+}
+
+// trimPrefix removes the shortest (or, if longest, the longest) prefix of s
+// that matches p.
+func (p *pattern) trimPrefix(s string, longest bool) string {
+	bounds := runeBoundaries(s)
+	if longest {
+		for i := len(bounds) - 1; i >= 0; i-- {
+			if p.matches(s[:bounds[i]]) {
+				return s[bounds[i]:]
+			}
+		}
+		return s
+	}
+	for _, end := range bounds {
+		if p.matches(s[:end]) {
+			return s[end:]
+		}
+	}
+	return s
+}
+
+// trimSuffix removes the shortest (or, if longest, the longest) suffix of s
+// that matches p.
+func (p *pattern) trimSuffix(s string, longest bool) string {
+	bounds := runeBoundaries(s)
+	if longest {
+		for _, start := range bounds {
+			if p.matches(s[start:]) {
+				return s[:start]
+			}
+		}
+		return s
+	}
+	for i := len(bounds) - 1; i >= 0; i-- {
+		if p.matches(s[bounds[i]:]) {
+			return s[:bounds[i]]
+		}
+	}
+	return s
+}
+
+// replace replaces the longest match of p starting at the leftmost position
+// where one exists. With all, it replaces every such match, scanning on from
+// the end of each. An anchor of "#" or "%" only allows a match at the start or
+// end of s. Empty matches are only replaced when anchored.
+func (p *pattern) replace(s, repl string, all bool, anchor byte) string {
+	bounds := runeBoundaries(s)
+	switch anchor {
+	case '#':
+		for i := len(bounds) - 1; i >= 0; i-- {
+			if p.matches(s[:bounds[i]]) {
+				return repl + s[bounds[i]:]
+			}
+		}
+		return s
+	case '%':
+		for _, start := range bounds {
+			if p.matches(s[start:]) {
+				return s[:start] + repl
+			}
+		}
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(bounds)-1; i++ {
+		start := bounds[i]
+		if start < last {
+			continue
+		}
+		end := -1
+		for j := len(bounds) - 1; j > i; j-- {
+			if p.matches(s[start:bounds[j]]) {
+				end = bounds[j]
+				break
+			}
+		}
+		if end < 0 {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(repl)
+		last = end
+		if !all {
+			break
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package interpolator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatternMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "main.gox", false},
+		{"v?.?", "v1.2", true},
+		{"[abc]x", "bx", true},
+		{"[!abc]x", "bx", false},
+		{"[^abc]x", "dx", true},
+		{"[a-c][[:digit:]]", "b7", true},
+		{"[]]", "]", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{"a.b", "axb", false},
+		{"[x", "[x", true},
+		{"é?", "éü", true},
+		{"*", "multi\nline", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.value, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compilePattern() unexpected error: %v", err)
+			}
+			if got := p.matches(tt.value); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := compilePattern("[z-a]"); err == nil || !strings.Contains(err.Error(), `invalid pattern "[z-a]"`) {
+		t.Errorf("compilePattern() error = %v, want invalid pattern", err)
+	}
+}
+
+func TestPatternTrimAndReplace(t *testing.T) {
+	mustCompile := func(src string) *pattern {
+		p, err := compilePattern(src)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) unexpected error: %v", src, err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{"Shortest prefix", mustCompile("*/").trimPrefix("a/b/c", false), "b/c"},
+		{"Longest prefix", mustCompile("*/").trimPrefix("a/b/c", true), "c"},
+		{"No prefix", mustCompile("x*").trimPrefix("abc", true), "abc"},
+		{"Shortest suffix", mustCompile(".*").trimSuffix("a.tar.gz", false), "a.tar"},
+		{"Longest suffix", mustCompile(".*").trimSuffix("a.tar.gz", true), "a"},
+		{"Multibyte suffix", mustCompile("?").trimSuffix("naïve€", false), "naïve"},
+		{"First match", mustCompile("o").replace("foo boo", "0", false, 0), "f0o boo"},
+		{"Every match", mustCompile("o").replace("foo boo", "0", true, 0), "f00 b00"},
+		{"Longest match", mustCompile("b*o").replace("a boo", "X", false, 0), "a X"},
+		{"Prefix match", mustCompile("f").replace("fof", "X", false, '#'), "Xof"},
+		{"Suffix match", mustCompile("f").replace("fof", "X", false, '%'), "foX"},
+		{"Anchored empty pattern", mustCompile("").replace("abc", "X", false, '#'), "Xabc"},
+		{"Unanchored empty pattern", mustCompile("").replace("abc", "X", true, 0), "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.expected {
+				t.Errorf("got %q, want %q", tt.got, tt.expected)
+			}
+		})
+	}
+}
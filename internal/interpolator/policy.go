@@ -0,0 +1,73 @@
+package interpolator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Error messages for the variable policy
+const (
+	errNamePattern = "invalid variable pattern %q: %v"
+	errDenied      = "variable %s is blocked by the interpolation denylist (%s)"
+	errNotAllowed  = "variable %s is not in the interpolation allowlist"
+)
+
+// defaultDeny lists the runner-internal credentials that environment
+// references may never read, whatever the allowlist says: the runtime and
+// cache service tokens and the OIDC token request.
+var defaultDeny = []string{
+	"ACTIONS_RUNTIME_*",
+	"ACTIONS_ID_TOKEN_REQUEST_*",
+	"ACTIONS_CACHE_*",
+	"ACTIONS_RESULTS_*",
+	"GITHUB_TOKEN",
+}
+
+// ParseNamePatterns splits input on commas and newlines into glob patterns
+// for Options.Allow and Options.Deny. Malformed ones are rejected.
+func ParseNamePatterns(input string) ([]string, error) {
+	var patterns []string
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '\n' }) {
+		pattern := strings.TrimSpace(field)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf(errNamePattern, pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// CheckAllowed reports an error when name may not be read from the
+// environment: it matches the default denylist or deny, or allow is not
+// empty and name matches none of it. Names match case-insensitively. Other
+// readers of the environment, such as the template env function, use it to
+// apply the same policy as ${VAR} references.
+func CheckAllowed(name string, allow, deny []string) error {
+	if pattern, ok := matchName(name, defaultDeny); ok {
+		return fmt.Errorf(errDenied, name, "default pattern "+pattern)
+	}
+	if pattern, ok := matchName(name, deny); ok {
+		return fmt.Errorf(errDenied, name, "pattern "+pattern)
+	}
+	if len(allow) > 0 {
+		if _, ok := matchName(name, allow); !ok {
+			return fmt.Errorf(errNotAllowed, name)
+		}
+	}
+	return nil
+}
+
+// matchName returns the first of patterns that name matches.
+func matchName(name string, patterns []string) (string, bool) {
+	upper := strings.ToUpper(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), upper); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,84 @@
+package interpolator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNamePatterns(t *testing.T) {
+	got, err := ParseNamePatterns(" GITHUB_*, APP_?\n\n,RUNNER_OS ")
+	if err != nil {
+		t.Fatalf("ParseNamePatterns() unexpected error: %v", err)
+	}
+	want := []string{"GITHUB_*", "APP_?", "RUNNER_OS"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNamePatterns() = %q, want %q", got, want)
+	}
+
+	if _, err := ParseNamePatterns("A,[B"); err == nil || !strings.Contains(err.Error(), `invalid variable pattern "[B"`) {
+		t.Errorf("ParseNamePatterns() error = %v, want invalid pattern", err)
+	}
+}
+
+func TestCheckAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		errPart string
+	}{
+		{name: "HOME"},
+		{name: "ACTIONS_RUNTIME_TOKEN", errPart: "blocked by the interpolation denylist (default pattern ACTIONS_RUNTIME_*)"},
+		{name: "ACTIONS_ID_TOKEN_REQUEST_TOKEN", allow: []string{"ACTIONS_*"}, errPart: "default pattern ACTIONS_ID_TOKEN_REQUEST_*"},
+		{name: "actions_cache_url", errPart: "default pattern ACTIONS_CACHE_*"},
+		{name: "GITHUB_TOKEN", errPart: "default pattern GITHUB_TOKEN"},
+		{name: "GITHUB_SHA", allow: []string{"github_*"}},
+		{name: "GITHUB_SHA", allow: []string{"GITHUB_*"}, deny: []string{"*_SHA"}, errPart: "denylist (pattern *_SHA)"},
+		{name: "HOME", allow: []string{"GITHUB_*", "RUNNER_*"}, errPart: "variable HOME is not in the interpolation allowlist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAllowed(tt.name, tt.allow, tt.deny)
+			if tt.errPart == "" {
+				if err != nil {
+					t.Errorf("CheckAllowed() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("CheckAllowed() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestInterpolatePolicy(t *testing.T) {
+	env := map[string]string{"GITHUB_SHA": "abc", "ACTIONS_RUNTIME_TOKEN": "secret", "HOME": "/root"}
+	ip := NewWithOptions(Options{
+		Lookup: func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		},
+		Variables: map[string]string{"DOMAIN": "example.com"},
+		Allow:     []string{"GITHUB_*"},
+	})
+
+	got, err := ip.InterpolatePairs([]string{"HOME", "URL"}, []string{"/home/app", "${HOME}/${DOMAIN}/${GITHUB_SHA}"})
+	if err != nil {
+		t.Fatalf("InterpolatePairs() unexpected error: %v", err)
+	}
+	if want := []string{"/home/app", "/home/app/example.com/abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("InterpolatePairs() = %q, want %q", got, want)
+	}
+
+	for _, value := range []string{"$HOME", "${ACTIONS_RUNTIME_TOKEN:+set}", "${UNSET:-${ACTIONS_RUNTIME_TOKEN}}"} {
+		if _, err := ip.Interpolate(value); err == nil {
+			t.Errorf("Interpolate(%q) expected error, got nil", value)
+		}
+	}
+	if got, err := ip.Interpolate("${GITHUB_SHA:-${ACTIONS_RUNTIME_TOKEN}}"); err != nil || got != "abc" {
+		t.Errorf("Interpolate() = %q, %v, want %q: unused defaults are not looked up", got, err, "abc")
+	}
+}
@@ -0,0 +1,594 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/structured"
+)
+
+// Error messages
+const (
+	errSyntax  = "invalid path %q: %s at offset %d"
+	errNoMatch = "path %q matched nothing: no value at %q"
+)
+
+// segmentKind identifies the step a path segment performs.
+type segmentKind int
+
+const (
+	segName     segmentKind = iota // .name or ["name"]
+	segIndex                       // [0] or [-1]
+	segWildcard                    // .* or [*]
+	segFilter                      // [?(@.field == "value")]
+)
+
+// segment is one step of a compiled path.
+type segment struct {
+	kind   segmentKind
+	name   string
+	index  int
+	filter expr
+	raw    string // source text of the segment, used in error messages
+}
+
+// Path is a compiled path expression. The language is a small subset shared by
+// jq and JSONPath:
+//
+//	.items[0].metadata.name   property and index access ($ prefix optional)
+//	["key with.dots"]         quoted property names
+//	[-1]                      negative indices count from the end
+//	.tags[*] / .spec.*        wildcards over array elements or object values
+//	[?(@.env == "prod")]      filters with == != < <= > >=, && || ! and ()
+//
+// Documents are the values produced by structured.Parse: *structured.Object,
+// []interface{}, string, json.Number, bool and nil.
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+// Compile parses a path expression. An empty expression, "." or "$" selects
+// the whole document.
+func Compile(expr string) (*Path, error) {
+	p := &parser{expr: strings.TrimSpace(expr)}
+	if strings.HasPrefix(p.expr, "$") {
+		p.pos++
+	}
+	segments, err := p.parseSegments(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.expr) {
+		return nil, p.errorf("unexpected %q", p.expr[p.pos])
+	}
+	return &Path{expr: p.expr, segments: segments}, nil
+}
+
+// String returns the path expression as written.
+func (p *Path) String() string {
+	return p.expr
+}
+
+// IsSingular reports whether the path selects at most one value, i.e. it
+// contains neither wildcards nor filters.
+func (p *Path) IsSingular() bool {
+	for _, seg := range p.segments {
+		if seg.kind == segWildcard || seg.kind == segFilter {
+			return false
+		}
+	}
+	return true
+}
+
+// Eval evaluates the path against doc and returns every matched value in
+// document order. It fails, naming the first segment that selected nothing,
+// when there is no match.
+func (p *Path) Eval(doc interface{}) ([]interface{}, error) {
+	nodes := []interface{}{doc}
+	var prefix strings.Builder
+	for _, seg := range p.segments {
+		prefix.WriteString(seg.raw)
+		nodes = seg.applyAll(nodes)
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf(errNoMatch, p.expr, prefix.String())
+		}
+	}
+	return nodes, nil
+}
+
+// evaluate applies segments to node without error reporting; it is used for
+// the relative paths inside filters.
+func evaluate(segments []segment, node interface{}) []interface{} {
+	nodes := []interface{}{node}
+	for _, seg := range segments {
+		nodes = seg.applyAll(nodes)
+		if len(nodes) == 0 {
+			break
+		}
+	}
+	return nodes
+}
+
+// applyAll applies the segment to every node and concatenates the results.
+func (s segment) applyAll(nodes []interface{}) []interface{} {
+	var next []interface{}
+	for _, node := range nodes {
+		next = append(next, s.apply(node)...)
+	}
+	return next
+}
+
+// apply returns the values the segment selects from a single node.
+func (s segment) apply(node interface{}) []interface{} {
+	switch s.kind {
+	case segName:
+		if obj, ok := node.(*structured.Object); ok {
+			if value, found := obj.Get(s.name); found {
+				return []interface{}{value}
+			}
+		}
+	case segIndex:
+		if items, ok := node.([]interface{}); ok {
+			idx := s.index
+			if idx < 0 {
+				idx += len(items)
+			}
+			if idx >= 0 && idx < len(items) {
+				return []interface{}{items[idx]}
+			}
+		}
+	case segWildcard, segFilter:
+		var children []interface{}
+		switch typed := node.(type) {
+		case *structured.Object:
+			for _, key := range typed.Keys() {
+				value, _ := typed.Get(key)
+				children = append(children, value)
+			}
+		case []interface{}:
+			children = typed
+		}
+		if s.kind == segWildcard {
+			return children
+		}
+		var matched []interface{}
+		for _, child := range children {
+			if s.filter.eval(child) {
+				matched = append(matched, child)
+			}
+		}
+		return matched
+	}
+	return nil
+}
+
+// parser is a recursive-descent parser over a path expression.
+type parser struct {
+	expr string
+	pos  int
+}
+
+// errorf builds a syntax error pointing at the current offset.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(errSyntax, p.expr, fmt.Sprintf(format, args...), p.pos)
+}
+
+// peek returns the current byte, or 0 at the end of input.
+func (p *parser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+	return 0
+}
+
+// skipSpaces advances past blanks (only significant inside filters).
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.expr) && (p.expr[p.pos] == ' ' || p.expr[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// expect consumes c or fails.
+func (p *parser) expect(c byte) error {
+	p.skipSpaces()
+	if p.peek() != c {
+		if p.pos >= len(p.expr) {
+			return p.errorf("expected %q, got end of path", c)
+		}
+		return p.errorf("expected %q, got %q", c, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+// parseSegments reads segments until the end of input or, inside a filter,
+// until a character that cannot continue a relative path.
+func (p *parser) parseSegments(inFilter bool) ([]segment, error) {
+	var segments []segment
+	for p.pos < len(p.expr) {
+		start := p.pos
+		switch p.expr[p.pos] {
+		case '.':
+			p.pos++
+			switch {
+			case p.peek() == '*':
+				p.pos++
+				segments = append(segments, segment{kind: segWildcard, raw: p.expr[start:p.pos]})
+			case p.peek() == '[':
+				// jq style ".[0]"; the bracket is parsed on the next iteration
+			default:
+				name := p.readName()
+				if name == "" {
+					// A lone "." (jq identity) is allowed at the very end
+					if p.pos == len(p.expr) && len(segments) == 0 {
+						return segments, nil
+					}
+					return nil, p.errorf("expected property name after '.'")
+				}
+				segments = append(segments, segment{kind: segName, name: name, raw: p.expr[start:p.pos]})
+			}
+		case '[':
+			seg, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			seg.raw = p.expr[start:p.pos]
+			segments = append(segments, seg)
+		default:
+			if inFilter {
+				return segments, nil
+			}
+			return nil, p.errorf("unexpected %q", p.expr[p.pos])
+		}
+	}
+	return segments, nil
+}
+
+// readName reads an unquoted property name.
+func (p *parser) readName() string {
+	start := p.pos
+	for p.pos < len(p.expr) && isNameChar(p.expr[p.pos]) {
+		p.pos++
+	}
+	return p.expr[start:p.pos]
+}
+
+// isNameChar reports whether c may appear in an unquoted property name.
+func isNameChar(c byte) bool {
+	return c == '_' || c == '-' || c == '$' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+// parseBracket parses "[...]" holding an index, a wildcard, a quoted name or a filter.
+func (p *parser) parseBracket() (segment, error) {
+	p.pos++ // '['
+	p.skipSpaces()
+
+	var seg segment
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		seg = segment{kind: segWildcard}
+	case c == '\'' || c == '"':
+		name, err := p.readQuoted()
+		if err != nil {
+			return seg, err
+		}
+		seg = segment{kind: segName, name: name}
+	case c == '?':
+		p.pos++
+		if err := p.expect('('); err != nil {
+			return seg, err
+		}
+		filter, err := p.parseOr()
+		if err != nil {
+			return seg, err
+		}
+		if err := p.expect(')'); err != nil {
+			return seg, err
+		}
+		seg = segment{kind: segFilter, filter: filter}
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.expr) && p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9' {
+			p.pos++
+		}
+		idx, err := strconv.Atoi(p.expr[start:p.pos])
+		if err != nil {
+			return seg, p.errorf("invalid index %q", p.expr[start:p.pos])
+		}
+		seg = segment{kind: segIndex, index: idx}
+	default:
+		return seg, p.errorf("expected index, '*', quoted name or filter after '['")
+	}
+
+	if err := p.expect(']'); err != nil {
+		return seg, err
+	}
+	return seg, nil
+}
+
+// readQuoted reads a single- or double-quoted string with backslash escapes.
+func (p *parser) readQuoted() (string, error) {
+	quote := p.expr[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.expr):
+			b.WriteByte(p.expr[p.pos+1])
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// expr is a boolean filter expression evaluated against one candidate value.
+type expr interface {
+	eval(node interface{}) bool
+}
+
+type orExpr struct{ left, right expr }
+type andExpr struct{ left, right expr }
+type notExpr struct{ inner expr }
+type existsExpr struct{ operand operand }
+type compareExpr struct {
+	left, right operand
+	op          string
+}
+
+func (e orExpr) eval(node interface{}) bool  { return e.left.eval(node) || e.right.eval(node) }
+func (e andExpr) eval(node interface{}) bool { return e.left.eval(node) && e.right.eval(node) }
+func (e notExpr) eval(node interface{}) bool { return !e.inner.eval(node) }
+
+func (e existsExpr) eval(node interface{}) bool {
+	_, ok := e.operand.resolve(node)
+	return ok
+}
+
+func (e compareExpr) eval(node interface{}) bool {
+	left, ok := e.left.resolve(node)
+	if !ok {
+		return false
+	}
+	right, ok := e.right.resolve(node)
+	if !ok {
+		return false
+	}
+	return compare(left, right, e.op)
+}
+
+// operand is either a relative path starting at '@' or a literal.
+type operand struct {
+	isPath  bool
+	path    []segment
+	literal interface{}
+}
+
+// resolve returns the operand's value for node; relative paths that select
+// nothing report false.
+func (o operand) resolve(node interface{}) (interface{}, bool) {
+	if !o.isPath {
+		return o.literal, true
+	}
+	values := evaluate(o.path, node)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// parseOr parses "a || b", the lowest-precedence operator.
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses "a && b".
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseUnary parses negation, parenthesized expressions and comparisons.
+func (p *parser) parseUnary() (expr, error) {
+	p.skipSpaces()
+	if p.peek() == '!' && !strings.HasPrefix(p.expr[p.pos:], "!=") {
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{inner: inner}, nil
+	}
+	if p.peek() == '(' {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+// comparisonOperators lists the supported operators, longest first.
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseComparison parses "operand op operand" or a bare existence test.
+func (p *parser) parseComparison() (expr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range comparisonOperators {
+		if p.consume(op) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return compareExpr{left: left, right: right, op: op}, nil
+		}
+	}
+	return existsExpr{operand: left}, nil
+}
+
+// parseOperand parses "@path", a quoted string, a number, true, false or null.
+func (p *parser) parseOperand() (operand, error) {
+	p.skipSpaces()
+	c := p.peek()
+	switch {
+	case c == '@':
+		p.pos++
+		segments, err := p.parseSegments(true)
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{isPath: true, path: segments}, nil
+	case c == '\'' || c == '"':
+		s, err := p.readQuoted()
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{literal: s}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.expr) && strings.IndexByte("+-.0123456789eE", p.expr[p.pos]) >= 0 {
+			p.pos++
+		}
+		text := p.expr[start:p.pos]
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return operand{}, p.errorf("invalid number %q", text)
+		}
+		return operand{literal: json.Number(text)}, nil
+	}
+	for keyword, value := range map[string]interface{}{"true": true, "false": false, "null": nil} {
+		if strings.HasPrefix(p.expr[p.pos:], keyword) {
+			p.pos += len(keyword)
+			return operand{literal: value}, nil
+		}
+	}
+	if p.pos >= len(p.expr) {
+		return operand{}, p.errorf("expected operand, got end of path")
+	}
+	return operand{}, p.errorf("expected '@', string, number, true, false or null")
+}
+
+// consume skips blanks and consumes token if it comes next.
+func (p *parser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.expr[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// compare applies op to two decoded values. Numbers compare numerically and
+// strings lexicographically; other types and mixed types only support
+// equality.
+func compare(left, right interface{}, op string) bool {
+	if l, ok := toFloat(left); ok {
+		if r, ok := toFloat(right); ok {
+			switch op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+
+	equal := scalarEqual(left, right)
+	switch op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	}
+	return false
+}
+
+// scalarEqual compares booleans and nulls; collections and mixed types are never equal.
+func scalarEqual(left, right interface{}) bool {
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	}
+	return false
+}
+
+// toFloat converts a decoded number to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,156 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/structured"
+)
+
+const testDocument = `{
+  "items": [
+    {"metadata": {"name": "web", "labels": {"app.kubernetes.io/name": "web"}}, "env": "prod", "replicas": 3},
+    {"metadata": {"name": "worker"}, "env": "dev", "replicas": 1},
+    {"metadata": {"name": "cron"}, "env": "prod", "replicas": 0, "suspended": true}
+  ],
+  "tags": ["a", "b", "c"],
+  "owner": null
+}`
+
+func decode(t *testing.T, doc string) interface{} {
+	t.Helper()
+	data, err := structured.DecodeJSON(doc)
+	if err != nil {
+		t.Fatalf("DecodeJSON() error: %v", err)
+	}
+	return data
+}
+
+func TestEval(t *testing.T) {
+	doc := decode(t, testDocument)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []interface{}
+	}{
+		{name: "Property and index", path: ".items[0].metadata.name", expected: []interface{}{"web"}},
+		{name: "Dollar prefix", path: "$.items[1].metadata.name", expected: []interface{}{"worker"}},
+		{name: "jq style index", path: ".tags.[1]", expected: []interface{}{"b"}},
+		{name: "Negative index", path: ".tags[-1]", expected: []interface{}{"c"}},
+		{name: "Quoted name", path: `.items[0].metadata.labels["app.kubernetes.io/name"]`, expected: []interface{}{"web"}},
+		{name: "Single-quoted name", path: `['tags'][0]`, expected: []interface{}{"a"}},
+		{name: "Array wildcard", path: ".tags[*]", expected: []interface{}{"a", "b", "c"}},
+		{name: "Wildcard then property", path: ".items[*].metadata.name", expected: []interface{}{"web", "worker", "cron"}},
+		{name: "Dot wildcard", path: ".items[1].metadata.*", expected: []interface{}{"worker"}},
+		{name: "String filter", path: `.items[?(@.env=="prod")].metadata.name`, expected: []interface{}{"web", "cron"}},
+		{name: "Numeric filter", path: `.items[?(@.replicas >= 1)].metadata.name`, expected: []interface{}{"web", "worker"}},
+		{name: "Combined filter", path: `.items[?(@.env == 'prod' && @.replicas > 0)].metadata.name`, expected: []interface{}{"web"}},
+		{name: "Or filter", path: `.items[?(@.env == "dev" || @.replicas == 0)].metadata.name`, expected: []interface{}{"worker", "cron"}},
+		{name: "Negated existence filter", path: `.items[?(!@.suspended)].metadata.name`, expected: []interface{}{"web", "worker"}},
+		{name: "Parenthesized filter", path: `.items[?(!(@.env == "prod"))].metadata.name`, expected: []interface{}{"worker"}},
+		{name: "Filter on scalars", path: `.tags[?(@ != "b")]`, expected: []interface{}{"a", "c"}},
+		{name: "Null value", path: ".owner", expected: []interface{}{nil}},
+		{name: "Numbers keep source text", path: ".items[0].replicas", expected: []interface{}{json.Number("3")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := Compile(tt.path)
+			if err != nil {
+				t.Fatalf("Compile(%q) error: %v", tt.path, err)
+			}
+			got, err := path.Eval(doc)
+			if err != nil {
+				t.Fatalf("Eval() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Eval() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEvalRoot(t *testing.T) {
+	doc := decode(t, `{"a":1}`)
+	for _, expr := range []string{"", ".", "$"} {
+		path, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", expr, err)
+		}
+		got, err := path.Eval(doc)
+		if err != nil || len(got) != 1 || got[0] != doc {
+			t.Errorf("Eval(%q) = %v, %v, want the document", expr, got, err)
+		}
+	}
+}
+
+func TestEvalNoMatch(t *testing.T) {
+	doc := decode(t, testDocument)
+
+	tests := []struct {
+		path    string
+		errPart string
+	}{
+		{path: ".items[0].metadata.namespace", errPart: `no value at ".items[0].metadata.namespace"`},
+		{path: ".items[5].metadata.name", errPart: `no value at ".items[5]"`},
+		{path: ".missing.name", errPart: `no value at ".missing"`},
+		{path: `.items[?(@.env=="staging")].metadata.name`, errPart: `no value at ".items[?(@.env==\"staging\")]"`},
+		{path: ".tags.name", errPart: `no value at ".tags.name"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := Compile(tt.path)
+			if err != nil {
+				t.Fatalf("Compile(%q) error: %v", tt.path, err)
+			}
+			_, err = path.Eval(doc)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Eval() error = %v, want containing %s", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{
+		"items",
+		".items[",
+		".items[abc]",
+		".items[0",
+		`.items["open]`,
+		".items[?(@.env ==)]",
+		".items[?@.env]",
+		".a..b",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Compile(expr); err == nil || !strings.Contains(err.Error(), "invalid path") {
+				t.Errorf("Compile(%q) error = %v, want invalid path", expr, err)
+			}
+		})
+	}
+}
+
+func TestIsSingular(t *testing.T) {
+	tests := map[string]bool{
+		".items[0].name":      true,
+		`.a["b"][-1]`:         true,
+		".tags[*]":            false,
+		".spec.*":             false,
+		`.items[?(@.x == 1)]`: false,
+		".items[*].meta.name": false,
+		"":                    true,
+	}
+	for expr, expected := range tests {
+		path, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", expr, err)
+		}
+		if got := path.IsSingular(); got != expected {
+			t.Errorf("IsSingular(%q) = %v, want %v", expr, got, expected)
+		}
+	}
+}
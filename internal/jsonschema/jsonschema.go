@@ -0,0 +1,524 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/structured"
+)
+
+// Error messages
+const (
+	errDecode         = "invalid schema: %v"
+	errKeyword        = "invalid schema at %q: %s"
+	errUnsupported    = "invalid schema at %q: keyword %q is not supported (supported: %s)"
+	errUnsupportedRef = "invalid schema at %q: unsupported $ref %q (only local references such as #/$defs/name are supported)"
+	errUnresolvedRef  = "invalid schema at %q: $ref %q does not point into the schema"
+	errRefCycle       = "invalid schema at %q: $ref loops back without reaching a property or item"
+)
+
+// supportedKeywords are the validation keywords this implementation applies.
+var supportedKeywords = []string{"$ref", "additionalProperties", "enum", "items", "pattern", "properties", "required", "type"}
+
+// unsupportedKeywords are draft 2020-12 assertions that are rejected rather
+// than silently ignored, so a schema never appears to enforce more than it does.
+var unsupportedKeywords = map[string]bool{
+	"allOf": true, "anyOf": true, "oneOf": true, "not": true,
+	"if": true, "then": true, "else": true,
+	"const": true, "multipleOf": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+	"minLength": true, "maxLength": true,
+	"minItems": true, "maxItems": true, "uniqueItems": true, "contains": true,
+	"minContains": true, "maxContains": true, "prefixItems": true, "unevaluatedItems": true,
+	"minProperties": true, "maxProperties": true, "patternProperties": true,
+	"propertyNames": true, "dependentRequired": true, "dependentSchemas": true,
+	"unevaluatedProperties": true, "$dynamicRef": true,
+}
+
+// validTypes are the values allowed in the type keyword.
+var validTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// node is one compiled (sub)schema.
+type node struct {
+	boolean    *bool // set for the boolean schemas true and false
+	ref        *node
+	types      []string
+	required   []string
+	enum       []interface{}
+	pattern    *regexp.Regexp
+	properties map[string]*node
+	additional *node
+	items      *node
+}
+
+// Schema is a compiled JSON Schema. It implements the subset of draft 2020-12
+// needed to check configuration documents: type, required, enum, pattern,
+// properties, items, additionalProperties and $ref to locations in the same
+// schema (typically #/$defs/name). Annotations such as title, description
+// and format are accepted and ignored; other assertions are rejected.
+type Schema struct {
+	doc   interface{}
+	nodes map[string]*node
+	root  *node
+}
+
+// Error is one violation, located by the JSON pointer of the offending value.
+type Error struct {
+	Path    string // JSON pointer into the document, "" for the document itself
+	Message string
+}
+
+// Error returns the violation as "#/pointer: message".
+func (e Error) Error() string {
+	return fmt.Sprintf("#%s: %s", e.Path, e.Message)
+}
+
+// Compile parses and compiles a schema document.
+func Compile(data []byte) (*Schema, error) {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf(errDecode, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf(errDecode, "invalid character after top-level value")
+	}
+
+	s := &Schema{doc: doc, nodes: make(map[string]*node)}
+	root, err := s.compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRefCycles(); err != nil {
+		return nil, err
+	}
+	s.root = root
+	return s, nil
+}
+
+// checkRefCycles rejects $ref chains that lead back to a schema already on
+// the chain. Following one during validation never reaches a property or
+// item, so it would recurse without end on the same value.
+func (s *Schema) checkRefCycles() error {
+	pointers := make([]string, 0, len(s.nodes))
+	pointerOf := make(map[*node]string, len(s.nodes))
+	for pointer, n := range s.nodes {
+		pointers = append(pointers, pointer)
+		pointerOf[n] = pointer
+	}
+	sort.Strings(pointers)
+
+	for _, pointer := range pointers {
+		seen := make(map[*node]bool)
+		for n := s.nodes[pointer]; n.ref != nil; n = n.ref {
+			if seen[n] {
+				// n is on the loop; report its own $ref
+				return fmt.Errorf(errRefCycle, "#"+pointerOf[n]+"/$ref")
+			}
+			seen[n] = true
+		}
+	}
+	return nil
+}
+
+// compile compiles the subschema v found at pointer. Nodes are registered
+// before their children are compiled, so recursive $refs resolve to the
+// node being built.
+func (s *Schema) compile(v interface{}, pointer string) (*node, error) {
+	if n, ok := s.nodes[pointer]; ok {
+		return n, nil
+	}
+
+	n := &node{}
+	s.nodes[pointer] = n
+
+	if b, ok := v.(bool); ok {
+		n.boolean = &b
+		return n, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf(errKeyword, "#"+pointer, "a schema must be an object or a boolean")
+	}
+
+	keywords := make([]string, 0, len(obj))
+	for k := range obj {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+
+	for _, keyword := range keywords {
+		value := obj[keyword]
+		at := pointer + "/" + escapeToken(keyword)
+		var err error
+		switch keyword {
+		case "$ref":
+			n.ref, err = s.compileRef(value, at)
+		case "type":
+			n.types, err = compileTypes(value, at)
+		case "required":
+			n.required, err = stringList(value, at)
+		case "enum":
+			items, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf(errKeyword, "#"+at, "enum must be an array")
+			}
+			n.enum = items
+		case "pattern":
+			src, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf(errKeyword, "#"+at, "pattern must be a string")
+			}
+			if n.pattern, err = regexp.Compile(src); err != nil {
+				return nil, fmt.Errorf(errKeyword, "#"+at, err)
+			}
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf(errKeyword, "#"+at, "properties must be an object")
+			}
+			n.properties = make(map[string]*node, len(props))
+			for name, sub := range props {
+				if n.properties[name], err = s.compile(sub, at+"/"+escapeToken(name)); err != nil {
+					return nil, err
+				}
+			}
+		case "additionalProperties":
+			n.additional, err = s.compile(value, at)
+		case "items":
+			n.items, err = s.compile(value, at)
+		case "$defs", "definitions":
+			defs, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf(errKeyword, "#"+at, keyword+" must be an object")
+			}
+			for name, sub := range defs {
+				if _, err := s.compile(sub, at+"/"+escapeToken(name)); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			if unsupportedKeywords[keyword] {
+				return nil, fmt.Errorf(errUnsupported, "#"+pointer, keyword, strings.Join(supportedKeywords, ", "))
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// compileRef resolves a local $ref and compiles its target.
+func (s *Schema) compileRef(value interface{}, at string) (*node, error) {
+	ref, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf(errKeyword, "#"+at, "$ref must be a string")
+	}
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf(errUnsupportedRef, "#"+at, ref)
+	}
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil || (pointer != "" && !strings.HasPrefix(pointer, "/")) {
+		return nil, fmt.Errorf(errUnsupportedRef, "#"+at, ref)
+	}
+
+	target, ok := resolvePointer(s.doc, pointer)
+	if !ok {
+		return nil, fmt.Errorf(errUnresolvedRef, "#"+at, ref)
+	}
+	return s.compile(target, pointer)
+}
+
+// resolvePointer returns the value at a JSON pointer in a decoded schema.
+func resolvePointer(doc interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return doc, true
+	}
+	current := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = unescapeToken(token)
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			next, ok := typed[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(typed) {
+				return nil, false
+			}
+			current = typed[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// compileTypes reads the type keyword: a type name or a list of them.
+func compileTypes(value interface{}, at string) ([]string, error) {
+	var types []string
+	switch typed := value.(type) {
+	case string:
+		types = []string{typed}
+	case []interface{}:
+		var err error
+		if types, err = stringList(value, at); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf(errKeyword, "#"+at, "type must be a string or an array of strings")
+	}
+	for _, t := range types {
+		if !validTypes[t] {
+			return nil, fmt.Errorf(errKeyword, "#"+at, fmt.Sprintf("unknown type %q", t))
+		}
+	}
+	return types, nil
+}
+
+// stringList reads an array of strings.
+func stringList(value interface{}, at string) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(errKeyword, "#"+at, "expected an array of strings")
+	}
+	result := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf(errKeyword, "#"+at, "expected an array of strings")
+		}
+		result[i] = s
+	}
+	return result, nil
+}
+
+// Validate checks a document and returns every violation, in document order.
+// Documents are the values produced by structured.Parse (*structured.Object,
+// []interface{}, string, json.Number, bool and nil); plain maps and Go
+// numbers are accepted as well.
+func (s *Schema) Validate(doc interface{}) []Error {
+	var errs []Error
+	s.root.validate(doc, "", &errs)
+	return errs
+}
+
+// validate applies n to v, appending violations to errs.
+func (n *node) validate(v interface{}, pointer string, errs *[]Error) {
+	if n.boolean != nil {
+		if !*n.boolean {
+			*errs = append(*errs, Error{pointer, "no value is allowed here"})
+		}
+		return
+	}
+	if n.ref != nil {
+		n.ref.validate(v, pointer, errs)
+	}
+
+	if len(n.types) > 0 && !matchesType(v, n.types) {
+		*errs = append(*errs, Error{pointer, fmt.Sprintf("expected %s, got %s", strings.Join(n.types, " or "), typeOf(v))})
+		return
+	}
+
+	if n.enum != nil {
+		found := false
+		for _, candidate := range n.enum {
+			if equal(v, candidate) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*errs = append(*errs, Error{pointer, fmt.Sprintf("value %s is not one of %s", render(v), render(n.enum))})
+		}
+	}
+
+	switch typed := v.(type) {
+	case string:
+		if n.pattern != nil && !n.pattern.MatchString(typed) {
+			*errs = append(*errs, Error{pointer, fmt.Sprintf("value %q does not match pattern %q", typed, n.pattern.String())})
+		}
+	case []interface{}:
+		if n.items != nil {
+			for i, item := range typed {
+				n.items.validate(item, pointer+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case *structured.Object, map[string]interface{}:
+		n.validateObject(typed, pointer, errs)
+	}
+}
+
+// validateObject applies required, properties and additionalProperties.
+func (n *node) validateObject(v interface{}, pointer string, errs *[]Error) {
+	keys, get := objectView(v)
+	for _, name := range n.required {
+		if _, ok := get(name); !ok {
+			*errs = append(*errs, Error{pointer, fmt.Sprintf("missing required property %q", name)})
+		}
+	}
+	for _, key := range keys {
+		value, _ := get(key)
+		at := pointer + "/" + escapeToken(key)
+		if sub, ok := n.properties[key]; ok {
+			sub.validate(value, at, errs)
+		} else if n.additional != nil {
+			if n.additional.boolean != nil && !*n.additional.boolean {
+				*errs = append(*errs, Error{at, fmt.Sprintf("property %q is not allowed", key)})
+			} else {
+				n.additional.validate(value, at, errs)
+			}
+		}
+	}
+}
+
+// objectView returns the keys of an object (source order for
+// *structured.Object, sorted for maps) and a lookup function.
+func objectView(v interface{}) ([]string, func(string) (interface{}, bool)) {
+	if obj, ok := v.(*structured.Object); ok {
+		return obj.Keys(), obj.Get
+	}
+	m := v.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, func(k string) (interface{}, bool) {
+		value, ok := m[k]
+		return value, ok
+	}
+}
+
+// matchesType reports whether v is one of the JSON types in types.
+func matchesType(v interface{}, types []string) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON type of v; numbers with no fractional part are
+// "integer", as in the specification.
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case *structured.Object, map[string]interface{}:
+		return "object"
+	}
+	if r, ok := toRat(v); ok {
+		if r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// toRat converts a number to an exact rational for comparison.
+func toRat(v interface{}) (*big.Rat, bool) {
+	var s string
+	switch typed := v.(type) {
+	case json.Number:
+		s = typed.String()
+	case float64:
+		s = strconv.FormatFloat(typed, 'g', -1, 64)
+	case int:
+		s = strconv.Itoa(typed)
+	case int64:
+		s = strconv.FormatInt(typed, 10)
+	default:
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// equal compares two JSON values: numbers by value, objects regardless of key order.
+func equal(a, b interface{}) bool {
+	if ra, ok := toRat(a); ok {
+		rb, ok := toRat(b)
+		return ok && ra.Cmp(rb) == 0
+	}
+	switch ta := a.(type) {
+	case []interface{}:
+		tb, ok := b.([]interface{})
+		if !ok || len(ta) != len(tb) {
+			return false
+		}
+		for i := range ta {
+			if !equal(ta[i], tb[i]) {
+				return false
+			}
+		}
+		return true
+	case *structured.Object, map[string]interface{}:
+		if typeOf(b) != "object" {
+			return false
+		}
+		keysA, getA := objectView(a)
+		keysB, getB := objectView(b)
+		if len(keysA) != len(keysB) {
+			return false
+		}
+		for _, k := range keysA {
+			va, _ := getA(k)
+			vb, ok := getB(k)
+			if !ok || !equal(va, vb) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// maxRenderLength limits how much of a value is quoted in a message.
+const maxRenderLength = 80
+
+// render formats v as compact JSON for messages.
+func render(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(data) > maxRenderLength {
+		return string(data[:maxRenderLength]) + "..."
+	}
+	return string(data)
+}
+
+// escapeToken escapes a JSON pointer reference token.
+func escapeToken(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// unescapeToken reverses escapeToken.
+func unescapeToken(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
@@ -0,0 +1,196 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/structured"
+)
+
+const deploymentSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Deployment",
+  "type": "object",
+  "required": ["name", "spec"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "pattern": "^[a-z][a-z0-9-]*$"},
+    "spec": {"$ref": "#/$defs/spec"},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+  },
+  "$defs": {
+    "spec": {
+      "type": "object",
+      "required": ["replicas"],
+      "properties": {
+        "replicas": {"type": "integer"},
+        "env": {"enum": ["dev", "staging", "prod"]},
+        "ports": {"type": "array", "items": {"$ref": "#/$defs/port"}}
+      }
+    },
+    "port": {"type": ["integer", "string"]}
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(deploymentSchema))
+	if err != nil {
+		t.Fatalf("Compile() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		expected []string
+	}{
+		{
+			name:     "Valid document",
+			document: `{"name":"web","spec":{"replicas":3,"env":"prod","ports":[80,"https"]},"labels":{"team":"a"}}`,
+		},
+		{
+			name:     "Integer written as 2.0",
+			document: `{"name":"web","spec":{"replicas":2.0}}`,
+		},
+		{
+			name:     "Nested type error",
+			document: `{"name":"web","spec":{"replicas":"3"}}`,
+			expected: []string{`#/spec/replicas: expected integer, got string`},
+		},
+		{
+			name:     "Typo in a property name",
+			document: `{"name":"web","spec":{"replicas":1},"lables":{}}`,
+			expected: []string{`#/lables: property "lables" is not allowed`},
+		},
+		{
+			name:     "Missing required properties",
+			document: `{"spec":{}}`,
+			expected: []string{`#: missing required property "name"`, `#/spec: missing required property "replicas"`},
+		},
+		{
+			name:     "Enum, pattern and items",
+			document: `{"name":"Web","spec":{"replicas":1,"env":"qa","ports":[80,true]},"labels":{"team":1}}`,
+			expected: []string{
+				`#/name: value "Web" does not match pattern "^[a-z][a-z0-9-]*$"`,
+				`#/spec/env: value "qa" is not one of ["dev","staging","prod"]`,
+				`#/spec/ports/1: expected integer or string, got boolean`,
+				`#/labels/team: expected string, got integer`,
+			},
+		},
+		{
+			name:     "Wrong root type",
+			document: `[1,2]`,
+			expected: []string{`#: expected object, got array`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := structured.DecodeJSON(tt.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range schema.Validate(doc) {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Validate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidatePlainValues(t *testing.T) {
+	schema, err := Compile([]byte(`{"type":"object","properties":{"n":{"enum":[1, {"a":[true,null]}]}}}`))
+	if err != nil {
+		t.Fatalf("Compile() unexpected error: %v", err)
+	}
+
+	valid := []interface{}{
+		map[string]interface{}{"n": 1.0},
+		map[string]interface{}{"n": map[string]interface{}{"a": []interface{}{true, nil}}},
+	}
+	for _, doc := range valid {
+		if errs := schema.Validate(doc); len(errs) != 0 {
+			t.Errorf("Validate(%v) = %v, want no errors", doc, errs)
+		}
+	}
+	if errs := schema.Validate(map[string]interface{}{"n": 2}); len(errs) != 1 {
+		t.Errorf("Validate() = %v, want one error", errs)
+	}
+}
+
+func TestRecursiveRefAndBooleanSchemas(t *testing.T) {
+	schema, err := Compile([]byte(`{
+		"$defs": {"tree": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/tree"}}, "blocked": false}}},
+		"$ref": "#/$defs/tree"
+	}`))
+	if err != nil {
+		t.Fatalf("Compile() unexpected error: %v", err)
+	}
+
+	doc, _ := structured.DecodeJSON(`{"children":[{"children":[{"children":"x"}]},{"blocked":1}]}`)
+	var got []string
+	for _, e := range schema.Validate(doc) {
+		got = append(got, e.Error())
+	}
+	want := []string{
+		"#/children/0/children/0/children: expected array, got string",
+		"#/children/1/blocked: no value is allowed here",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Validate() = %q, want %q", got, want)
+	}
+
+	if errs := mustCompile(t, `true`).Validate("anything"); len(errs) != 0 {
+		t.Errorf("true schema rejected a value: %v", errs)
+	}
+}
+
+func TestPointerEscaping(t *testing.T) {
+	schema := mustCompile(t, `{"properties":{"a/b":{"$ref":"#/$defs/x~1y"}},"$defs":{"x/y":{"type":"string"}}}`)
+	doc, _ := structured.DecodeJSON(`{"a/b":1}`)
+	errs := schema.Validate(doc)
+	if len(errs) != 1 || errs[0].Path != "/a~1b" {
+		t.Errorf("Validate() = %v, want one error at /a~1b", errs)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		errPart string
+	}{
+		{"Not JSON", `{"type":`, "invalid schema:"},
+		{"Schema is a string", `"object"`, `invalid schema at "#": a schema must be an object or a boolean`},
+		{"Unknown type", `{"properties":{"a":{"type":"int"}}}`, `invalid schema at "#/properties/a/type": unknown type "int"`},
+		{"Unsupported keyword", `{"properties":{"a":{"minimum":1}}}`, `keyword "minimum" is not supported`},
+		{"Remote ref", `{"$ref":"https://example.com/schema.json"}`, "only local references"},
+		{"Dangling ref", `{"$ref":"#/$defs/missing"}`, `$ref "#/$defs/missing" does not point into the schema`},
+		{"Ref to the root", `{"$ref":"#"}`, `invalid schema at "#/$ref": $ref loops back`},
+		{"Ref to itself", `{"$defs":{"a":{"$ref":"#/$defs/a"}},"$ref":"#/$defs/a"}`, `invalid schema at "#/$defs/a/$ref": $ref loops back`},
+		{"Ref cycle through defs", `{"$defs":{"a":{"$ref":"#/$defs/b"},"b":{"type":"object","$ref":"#/$defs/a"}},"properties":{"x":{"$ref":"#/$defs/a"}}}`, "$ref loops back without reaching a property or item"},
+		{"Bad pattern", `{"pattern":"[a-"}`, `invalid schema at "#/pattern"`},
+		{"Bad required", `{"required":"name"}`, "expected an array of strings"},
+		{"Trailing data", `{} {}`, "invalid character after top-level value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Compile() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func mustCompile(t *testing.T, schema string) *Schema {
+	t.Helper()
+	s, err := Compile([]byte(schema))
+	if err != nil {
+		t.Fatalf("Compile(%s) unexpected error: %v", schema, err)
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package keyname
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Supported key styles
+const (
+	StylePreserve   = "preserve"    // leave names untouched
+	StyleSanitize   = "sanitize"    // replace characters outside [A-Za-z0-9_] with '_'
+	StyleUpperSnake = "upper_snake" // sanitize, split camelCase words, upper-case
+	StyleLowerSnake = "lower_snake" // sanitize, split camelCase words, lower-case
+)
+
+// IsValidStyle reports whether style is one of the supported key styles.
+func IsValidStyle(style string) bool {
+	switch strings.ToLower(style) {
+	case StylePreserve, StyleSanitize, StyleUpperSnake, StyleLowerSnake:
+		return true
+	}
+	return false
+}
+
+// Convert rewrites name according to style. Every run of characters that is
+// not an ASCII letter, digit or underscore becomes a single '_' (so
+// "server-host" and "a.b" become "server_host" and "a_b"); the snake styles
+// additionally insert '_' at camelCase word boundaries ("serverHost" ->
+// "server_Host", "HTTPServer" -> "HTTP_Server") before changing case.
+// Unknown styles behave like StylePreserve.
+func Convert(name, style string) string {
+	switch strings.ToLower(style) {
+	case StyleSanitize:
+		return sanitize(name)
+	case StyleUpperSnake:
+		return strings.ToUpper(splitWords(sanitize(name)))
+	case StyleLowerSnake:
+		return strings.ToLower(splitWords(sanitize(name)))
+	default:
+		return name
+	}
+}
+
+// IsValidChar reports whether r may appear in an environment variable name.
+func IsValidChar(r rune) bool {
+	return r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+}
+
+// sanitize replaces each run of invalid characters with a single underscore.
+func sanitize(name string) string {
+	var b strings.Builder
+	inInvalid := false
+	for _, r := range name {
+		if IsValidChar(r) {
+			b.WriteRune(r)
+			inInvalid = false
+			continue
+		}
+		if !inInvalid {
+			b.WriteByte('_')
+			inInvalid = true
+		}
+	}
+	return b.String()
+}
+
+// splitWords inserts underscores at camelCase boundaries of an ASCII name.
+func splitWords(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package keyname
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		style    string
+		expected string
+	}{
+		{name: "Preserve", input: "server-host", style: StylePreserve, expected: "server-host"},
+		{name: "Unknown style preserves", input: "a.b", style: "other", expected: "a.b"},
+		{name: "Sanitize dash", input: "server-host", style: StyleSanitize, expected: "server_host"},
+		{name: "Sanitize dot", input: "a.b", style: StyleSanitize, expected: "a_b"},
+		{name: "Sanitize collapses runs", input: "a - b", style: StyleSanitize, expected: "a_b"},
+		{name: "Sanitize keeps case", input: "serverHost", style: StyleSanitize, expected: "serverHost"},
+		{name: "Sanitize non-ASCII", input: "café", style: StyleSanitize, expected: "caf_"},
+		{name: "Upper snake from camelCase", input: "serverHost", style: StyleUpperSnake, expected: "SERVER_HOST"},
+		{name: "Upper snake from kebab", input: "server-host", style: StyleUpperSnake, expected: "SERVER_HOST"},
+		{name: "Upper snake acronym", input: "HTTPServer", style: StyleUpperSnake, expected: "HTTP_SERVER"},
+		{name: "Upper snake digits", input: "v2Api", style: StyleUpperSnake, expected: "V2_API"},
+		{name: "Upper snake already snake", input: "SERVER_HOST", style: StyleUpperSnake, expected: "SERVER_HOST"},
+		{name: "Lower snake", input: "maxRetryCount", style: StyleLowerSnake, expected: "max_retry_count"},
+		{name: "Case-insensitive style name", input: "a.b", style: "UPPER_SNAKE", expected: "A_B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert(tt.input, tt.style); got != tt.expected {
+				t.Errorf("Convert(%q, %q) = %q, want %q", tt.input, tt.style, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsValidStyle(t *testing.T) {
+	for _, style := range []string{"preserve", "sanitize", "upper_snake", "lower_snake", "Upper_Snake"} {
+		if !IsValidStyle(style) {
+			t.Errorf("IsValidStyle(%q) = false, want true", style)
+		}
+	}
+	if IsValidStyle("camel") {
+		t.Error("IsValidStyle(camel) = true, want false")
+	}
+}
+
+func TestIsValidChar(t *testing.T) {
+	for _, r := range "azAZ09_" {
+		if !IsValidChar(r) {
+			t.Errorf("IsValidChar(%q) = false, want true", r)
+		}
+	}
+	for _, r := range "-. =\né" {
+		if IsValidChar(r) {
+			t.Errorf("IsValidChar(%q) = true, want false", r)
+		}
+	}
+}
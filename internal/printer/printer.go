@@ -81,6 +81,28 @@ func PrintInfo(message string) {
 		ResetColor)
 }
 
+// PrintAddMask emits an ::add-mask:: workflow command so the runner redacts
+// value from every subsequent log line. The value is escaped per the workflow
+// command rules so '%', CR and LF cannot break out of the command.
+func PrintAddMask(value string) {
+	PrintWorkflowCommand("add-mask", value)
+}
+
+// PrintWorkflowCommand prints a GitHub Actions workflow command
+// (::command::message) with the message escaped as command data.
+func PrintWorkflowCommand(command, message string) {
+	fmt.Printf("::%s::%s\n", command, EscapeCommandData(message))
+}
+
+// EscapeCommandData escapes a workflow command message the same way the
+// actions toolkit does: '%' first, then CR and LF.
+func EscapeCommandData(value string) string {
+	value = strings.ReplaceAll(value, "%", "%25")
+	value = strings.ReplaceAll(value, "\r", "%0D")
+	value = strings.ReplaceAll(value, "\n", "%0A")
+	return value
+}
+
 // PrintDebugSection prints a debug section header with a title.
 // It creates a visual separation for debug information.
 func PrintDebugSection(title string) {
@@ -539,3 +539,34 @@ func ExampleFormatColor() {
 	formatted := FormatColor("Important", SuccessColor)
 	fmt.Println(formatted)
 }
+
+func TestEscapeCommandData(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "Plain value", value: "secret", expected: "secret"},
+		{name: "Percent sign", value: "100%", expected: "100%25"},
+		{name: "Newlines", value: "a\r\nb", expected: "a%0D%0Ab"},
+		{name: "Already escaped sequence", value: "%0A", expected: "%250A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EscapeCommandData(tt.value); got != tt.expected {
+				t.Errorf("EscapeCommandData() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrintAddMask(t *testing.T) {
+	output := captureOutput(func() {
+		PrintAddMask("my\nsecret")
+	})
+
+	if output != "::add-mask::my%0Asecret\n" {
+		t.Errorf("PrintAddMask() output = %q", output)
+	}
+}
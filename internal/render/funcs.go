@@ -0,0 +1,79 @@
+package render
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/somaz94/env-output-setter/internal/transformer"
+)
+
+// Error messages for template functions
+const (
+	errEnvNotSet = "environment variable %s is not set"
+	errNoValue   = "%s needs a value to transform"
+)
+
+// funcMap returns the functions available in templates: every transformer
+// function, taking the value last so it can be piped ({{ .TAG | truncate 12 }}),
+// trunc as a short name for truncate, and:
+//
+//   - default FALLBACK VALUE: VALUE, or FALLBACK if VALUE is empty
+//   - env NAME: the environment variable NAME, an error if it is not set
+//   - env_or NAME FALLBACK: the environment variable NAME, or FALLBACK if it is not set
+//
+// Both env functions fail for variables the lookup may not read.
+//   - has KEY: whether KEY is set in the invocation
+func (r *Renderer) funcMap(data map[string]string) template.FuncMap {
+	funcs := template.FuncMap{}
+	for _, name := range transformer.FunctionNames() {
+		funcs[name] = transformFunc(name)
+	}
+	funcs["trunc"] = transformFunc("truncate")
+
+	funcs["default"] = func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+	funcs["env"] = func(name string) (string, error) {
+		value, ok, err := r.lookupEnv(name)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf(errEnvNotSet, name)
+		}
+		return value, nil
+	}
+	funcs["env_or"] = func(name, fallback string) (string, error) {
+		value, ok, err := r.lookupEnv(name)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fallback, nil
+		}
+		return value, nil
+	}
+	funcs["has"] = func(key string) bool {
+		_, ok := data[key]
+		return ok
+	}
+	return funcs
+}
+
+// transformFunc wraps the transformer function name for templates: the last
+// argument is the value, the ones before it the function's arguments.
+func transformFunc(name string) func(args ...interface{}) (string, error) {
+	return func(args ...interface{}) (string, error) {
+		if len(args) == 0 {
+			return "", fmt.Errorf(errNoValue, name)
+		}
+		fnArgs := make([]string, len(args)-1)
+		for i, arg := range args[:len(args)-1] {
+			fnArgs[i] = fmt.Sprint(arg)
+		}
+		return transformer.Apply(name, fnArgs, fmt.Sprint(args[len(args)-1]))
+	}
+}
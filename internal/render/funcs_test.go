@@ -0,0 +1,60 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransformFunc(t *testing.T) {
+	truncate := transformFunc("truncate")
+	if got, err := truncate(3, "abcdef"); err != nil || got != "abc" {
+		t.Errorf("truncate(3, abcdef) = %q, %v, want \"abc\"", got, err)
+	}
+	if _, err := truncate(); err == nil || !strings.Contains(err.Error(), "truncate needs a value to transform") {
+		t.Errorf("truncate() error = %v, want a missing value error", err)
+	}
+	if got, err := transformFunc("replace")("[0-9]", "#", "a1b2"); err != nil || got != "a#b#" {
+		t.Errorf("replace([0-9], #, a1b2) = %q, %v, want \"a#b#\"", got, err)
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	r := NewWithLookup(lookupIn(map[string]string{"SET": "value", "EMPTY": ""}))
+	funcs := r.funcMap(map[string]string{"KEY": ""})
+
+	for _, name := range []string{"lower", "dns_label", "sha256", "trunc", "default", "env", "env_or", "has"} {
+		if _, ok := funcs[name]; !ok {
+			t.Errorf("funcMap() missing %q", name)
+		}
+	}
+
+	envOr := funcs["env_or"].(func(string, string) (string, error))
+	if got, err := envOr("EMPTY", "fallback"); err != nil || got != "" {
+		t.Errorf("env_or(EMPTY) = %q, %v, want the empty value", got, err)
+	}
+	if got, err := envOr("UNSET", "fallback"); err != nil || got != "fallback" {
+		t.Errorf("env_or(UNSET) = %q, %v, want \"fallback\"", got, err)
+	}
+	if has := funcs["has"].(func(string) bool); !has("KEY") || has("OTHER") {
+		t.Error("has() should report only keys that are set")
+	}
+}
+
+func TestEnvFuncsBlocked(t *testing.T) {
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", "supersecret")
+	t.Setenv("APP_NAME", "web")
+	funcs := New().funcMap(nil)
+	env := funcs["env"].(func(string) (string, error))
+	envOr := funcs["env_or"].(func(string, string) (string, error))
+
+	const blocked = "variable ACTIONS_RUNTIME_TOKEN is blocked by the interpolation denylist"
+	if got, err := env("ACTIONS_RUNTIME_TOKEN"); err == nil || got != "" || !strings.Contains(err.Error(), blocked) {
+		t.Errorf("env(ACTIONS_RUNTIME_TOKEN) = %q, %v, want the blocked error", got, err)
+	}
+	if got, err := envOr("ACTIONS_RUNTIME_TOKEN", "fallback"); err == nil || got != "" || !strings.Contains(err.Error(), blocked) {
+		t.Errorf("env_or(ACTIONS_RUNTIME_TOKEN) = %q, %v, want the blocked error", got, err)
+	}
+	if got, err := env("APP_NAME"); err != nil || got != "web" {
+		t.Errorf("env(APP_NAME) = %q, %v, want \"web\"", got, err)
+	}
+}
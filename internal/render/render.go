@@ -0,0 +1,277 @@
+// Package render renders values written as Go text/template templates, such
+// as '{{ .REGISTRY }}/{{ .IMAGE | lower }}:{{ .TAG | trunc 12 }}'. Templates
+// see the other keys set in the same invocation, and are rendered so that a
+// key is rendered before every template that refers to it.
+package render
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/somaz94/env-output-setter/internal/interpolator"
+)
+
+// Error messages
+const (
+	errParse   = "invalid template for %s: %v"
+	errExecute = "failed to render template for %s: %v"
+	errCycle   = "templates refer to each other in a cycle: %s"
+)
+
+// LookupFunc resolves an environment variable, reporting whether it is set,
+// or returns an error if the variable may not be read.
+type LookupFunc func(name string) (string, bool, error)
+
+// Renderer renders template values.
+type Renderer struct {
+	lookupEnv LookupFunc
+}
+
+// New creates a Renderer whose env function reads the process environment,
+// except the runner credentials of the interpolation default denylist.
+func New() *Renderer {
+	return &Renderer{lookupEnv: lookupProcessEnv}
+}
+
+// NewWithLookup creates a Renderer whose env function resolves variables
+// through lookup instead of the process environment.
+func NewWithLookup(lookup LookupFunc) *Renderer {
+	if lookup == nil {
+		lookup = lookupProcessEnv
+	}
+	return &Renderer{lookupEnv: lookup}
+}
+
+// lookupProcessEnv reads the process environment under the interpolation
+// default denylist.
+func lookupProcessEnv(name string) (string, bool, error) {
+	if err := interpolator.CheckAllowed(name, nil, nil); err != nil {
+		return "", false, err
+	}
+	value, ok := os.LookupEnv(name)
+	return value, ok, nil
+}
+
+// IsTemplate reports whether value contains a template action.
+func IsTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// Render returns values with every template rendered. values[i] is the value
+// of keys[i]; only values that contain "{{" and, if render is not nil, have
+// render[i] set are templates. In a template, .KEY (or index . "KEY") is the
+// final value of KEY, so templates that refer to another template's key are
+// rendered after it; templates that refer to each other are an error naming
+// the cycle. A reference to a key that is not set is an error too
+// (missingkey=error). When a key is set more than once, its first value is
+// the one templates see.
+func (r *Renderer) Render(keys, values []string, render []bool) ([]string, error) {
+	result := append([]string(nil), values...)
+	data := make(map[string]string)
+	funcs := r.funcMap(data)
+
+	templates := make(map[int]*template.Template)
+	var pending []int
+	for i, key := range keys {
+		if i >= len(values) {
+			break
+		}
+		if (render == nil || (i < len(render) && render[i])) && IsTemplate(values[i]) {
+			tmpl, err := template.New(key).Option("missingkey=error").Funcs(funcs).Parse(values[i])
+			if err != nil {
+				return nil, fmt.Errorf(errParse, key, err)
+			}
+			templates[i] = tmpl
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	// Plain values are final already; a templated key is the first
+	// occurrence of a key that only templates set
+	for i, key := range keys {
+		if _, isTemplate := templates[i]; !isTemplate && i < len(values) {
+			if _, ok := data[key]; !ok {
+				data[key] = values[i]
+			}
+		}
+	}
+	templated := make(map[string]int)
+	for _, i := range pending {
+		if _, ok := data[keys[i]]; !ok {
+			if _, ok := templated[keys[i]]; !ok {
+				templated[keys[i]] = i
+			}
+		}
+	}
+
+	order, err := renderOrder(keys, pending, templates, templated)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range order {
+		var buf strings.Builder
+		if err := templates[i].Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf(errExecute, keys[i], err)
+		}
+		result[i] = buf.String()
+		if templated[keys[i]] == i {
+			data[keys[i]] = result[i]
+		}
+	}
+	return result, nil
+}
+
+// renderOrder returns the pending templates in an order where every template
+// comes after the templated keys it refers to, keeping the input order
+// otherwise.
+func renderOrder(keys []string, pending []int, templates map[int]*template.Template, templated map[string]int) ([]int, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[int]int)
+	var path, order []int
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			var cycle []string
+			for j := len(path) - 1; j >= 0; j-- {
+				cycle = append([]string{keys[path[j]]}, cycle...)
+				if path[j] == i {
+					break
+				}
+			}
+			return fmt.Errorf(errCycle, strings.Join(append(cycle, keys[i]), " -> "))
+		}
+
+		state[i] = visiting
+		path = append(path, i)
+		for _, ref := range References(templates[i]) {
+			if j, ok := templated[ref]; ok {
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = done
+		order = append(order, i)
+		return nil
+	}
+
+	for _, i := range pending {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// References returns the keys tmpl refers to through .KEY, index . "KEY" or
+// has "KEY", in the order they first appear.
+func References(tmpl *template.Template) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			refs = append(refs, key)
+		}
+	}
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd)
+				}
+			}
+		case *parse.CommandNode:
+			if key, ok := namedKey(n.Args); ok {
+				add(key)
+			}
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			add(n.Ident[0])
+		case *parse.ChainNode:
+			walk(n.Node)
+		}
+	}
+
+	// Walk tmpl itself first, then the templates it defines by name;
+	// Templates() returns them in no particular order
+	if tmpl.Tree != nil {
+		walk(tmpl.Tree.Root)
+	}
+	defined := tmpl.Templates()
+	sort.Slice(defined, func(i, j int) bool { return defined[i].Name() < defined[j].Name() })
+	for _, t := range defined {
+		if t != tmpl && t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+	return refs
+}
+
+// namedKey returns KEY for the commands index . "KEY" and has "KEY".
+func namedKey(args []parse.Node) (string, bool) {
+	if len(args) < 2 {
+		return "", false
+	}
+	ident, ok := args[0].(*parse.IdentifierNode)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case ident.Ident == "index" && len(args) == 3:
+		if _, isDot := args[1].(*parse.DotNode); !isDot {
+			return "", false
+		}
+		if s, ok := args[2].(*parse.StringNode); ok {
+			return s.Text, true
+		}
+	case ident.Ident == "has" && len(args) == 2:
+		if s, ok := args[1].(*parse.StringNode); ok {
+			return s.Text, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,115 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func lookupIn(env map[string]string) LookupFunc {
+	return func(name string) (string, bool, error) {
+		value, ok := env[name]
+		return value, ok, nil
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := NewWithLookup(lookupIn(map[string]string{"GITHUB_SHA": "0123456789abcdef"}))
+
+	tests := []struct {
+		name     string
+		keys     []string
+		values   []string
+		render   []bool
+		expected []string
+	}{
+		{
+			name:     "Composes other keys",
+			keys:     []string{"REGISTRY", "IMAGE", "TAG", "REF"},
+			values:   []string{"ghcr.io/acme", "Web", "0123456789abcdef", "{{ .REGISTRY }}/{{ .IMAGE | lower }}:{{ .TAG | trunc 12 }}"},
+			expected: []string{"ghcr.io/acme", "Web", "0123456789abcdef", "ghcr.io/acme/web:0123456789ab"},
+		},
+		{
+			name:     "Renders dependencies first",
+			keys:     []string{"URL", "HOST", "DOMAIN"},
+			values:   []string{"https://{{ .HOST }}/", "app.{{ .DOMAIN }}", "{{ \"Example.COM\" | lower }}"},
+			expected: []string{"https://app.example.com/", "app.example.com", "example.com"},
+		},
+		{
+			name:     "Environment and helpers",
+			keys:     []string{"SHORT_SHA", "STAGE", "NAME"},
+			values:   []string{`{{ env "GITHUB_SHA" | truncate 7 }}`, `{{ env_or "STAGE" "dev" }}`, `{{ if has "SUFFIX" }}x{{ else }}{{ "" | default "app" }}{{ end }}`},
+			expected: []string{"0123456", "dev", "app"},
+		},
+		{
+			name:     "Index for keys that are not identifiers",
+			keys:     []string{"app-name", "LABEL"},
+			values:   []string{"{{ \"web\" }}", `{{ index . "app-name" | upper }}`},
+			expected: []string{"web", "WEB"},
+		},
+		{
+			name:     "Only marked values are rendered",
+			keys:     []string{"RAW", "OUT"},
+			values:   []string{"{{ .OUT }}", "{{ .RAW }}"},
+			render:   []bool{false, true},
+			expected: []string{"{{ .OUT }}", "{{ .OUT }}"},
+		},
+		{
+			name:     "No templates",
+			keys:     []string{"A"},
+			values:   []string{"plain ${VAR}"},
+			expected: []string{"plain ${VAR}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Render(tt.keys, tt.values, tt.render)
+			if err != nil {
+				t.Fatalf("Render() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Render() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	r := NewWithLookup(lookupIn(nil))
+
+	tests := []struct {
+		name    string
+		keys    []string
+		values  []string
+		errPart string
+	}{
+		{"Cycle", []string{"A", "B", "C"}, []string{"{{ .B }}", "{{ .C }}", "x{{ .A }}"}, "templates refer to each other in a cycle: A -> B -> C -> A"},
+		{"Self reference", []string{"A"}, []string{"{{ .A }}"}, "cycle: A -> A"},
+		{"Missing key", []string{"A"}, []string{"{{ .MISSING }}"}, `failed to render template for A: template: A:1:3: executing "A" at <.MISSING>: map has no entry for key "MISSING"`},
+		{"Unset environment variable", []string{"A"}, []string{`{{ env "NOPE" }}`}, "environment variable NOPE is not set"},
+		{"Syntax error", []string{"A"}, []string{"{{ .B "}, "invalid template for A"},
+		{"Unknown function", []string{"A"}, []string{"{{ shout .B }}"}, `function "shout" not defined`},
+		{"Bad transform argument", []string{"A"}, []string{`{{ "x" | truncate 0 }}`}, "n must be a positive whole number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.keys, tt.values, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("Render() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	tmpl := template.Must(template.New("t").Funcs(New().funcMap(nil)).Parse(
+		`{{ .A }}{{ if has "B" }}{{ index . "c-d" }}{{ else }}{{ .A | lower }}{{ end }}{{ with .E }}{{ . }}{{ end }}{{ define "x" }}{{ .F }}{{ end }}`))
+
+	want := []string{"A", "B", "c-d", "E", "F"}
+	if got := References(tmpl); !reflect.DeepEqual(got, want) {
+		t.Errorf("References() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,214 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Object is a decoded mapping that remembers the order in which its keys first
+// appeared in the source document. Re-setting an existing key replaces its
+// value but keeps its original position.
+type Object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewObject creates an empty Object.
+func NewObject() *Object {
+	return &Object{values: make(map[string]interface{})}
+}
+
+// FromMap builds an Object from a plain map with its keys in sorted order,
+// since a Go map carries no source order. Nested maps are converted as well.
+func FromMap(m map[string]interface{}) *Object {
+	obj := NewObject()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		obj.Set(k, fromPlain(m[k]))
+	}
+	return obj
+}
+
+// fromPlain converts nested plain maps into Objects.
+func fromPlain(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case map[string]interface{}:
+		return FromMap(typed)
+	case []interface{}:
+		result := make([]interface{}, len(typed))
+		for i, item := range typed {
+			result[i] = fromPlain(item)
+		}
+		return result
+	}
+	return v
+}
+
+// Set assigns value to key, appending key if it is new.
+func (o *Object) Set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Get returns the value for key and whether it is present.
+func (o *Object) Get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Keys returns the keys in source order. The slice must not be modified.
+func (o *Object) Keys() []string {
+	return o.keys
+}
+
+// SortedKeys returns a sorted copy of the keys.
+func (o *Object) SortedKeys() []string {
+	keys := make([]string, len(o.keys))
+	copy(keys, o.keys)
+	sort.Strings(keys)
+	return keys
+}
+
+// Len returns the number of keys.
+func (o *Object) Len() int {
+	return len(o.keys)
+}
+
+// Marshal encodes v as compact JSON, with the keys of Objects in source order.
+// Unlike json.Marshal it leaves &, < and > as they are, so the result is the
+// compact form of the source document.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// MarshalJSON encodes the object with its keys in source order, without HTML
+// escaping (see Marshal).
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// ToPlain converts Objects (recursively) into plain maps, e.g. for comparison
+// with values produced by json.Unmarshal.
+func ToPlain(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case *Object:
+		m := make(map[string]interface{}, typed.Len())
+		for _, k := range typed.keys {
+			m[k] = ToPlain(typed.values[k])
+		}
+		return m
+	case []interface{}:
+		result := make([]interface{}, len(typed))
+		for i, item := range typed {
+			result[i] = ToPlain(item)
+		}
+		return result
+	}
+	return v
+}
+
+// DecodeJSON decodes a JSON document by walking json.Decoder tokens so object
+// keys keep their source order. Objects become *Object, arrays []interface{}
+// and numbers json.Number, so their source text survives a round trip.
+func DecodeJSON(data string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+
+	value, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid character after top-level value")
+	}
+	return value, nil
+}
+
+// decodeJSONValue reads one complete value from the token stream.
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := NewObject()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyTok.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid object key %v", keyTok)
+			}
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, value)
+		}
+		if _, err := dec.Token(); err != nil { // '}'
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		if _, err := dec.Token(); err != nil { // ']'
+			return nil, err
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unexpected delimiter %v", delim)
+}
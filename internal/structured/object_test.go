@@ -0,0 +1,113 @@
+package structured
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObject(t *testing.T) {
+	obj := NewObject()
+	obj.Set("b", 1)
+	obj.Set("a", 2)
+	obj.Set("b", 3)
+
+	if got := obj.Keys(); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("Keys() = %v, want [b a]", got)
+	}
+	if got := obj.SortedKeys(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("SortedKeys() = %v, want [a b]", got)
+	}
+	if v, ok := obj.Get("b"); !ok || v != 3 {
+		t.Errorf("Get(b) = %v, %v, want 3, true", v, ok)
+	}
+	if _, ok := obj.Get("missing"); ok {
+		t.Error("Get(missing) reported present")
+	}
+	if obj.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", obj.Len())
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("MarshalJSON() error: %v", err)
+	}
+	if string(data) != `{"b":3,"a":2}` {
+		t.Errorf("MarshalJSON() = %s, want {\"b\":3,\"a\":2}", data)
+	}
+}
+
+func TestMarshalKeepsHTMLCharacters(t *testing.T) {
+	obj := NewObject()
+	obj.Set("a&b", []interface{}{"x&y", "<tag>"})
+
+	data, err := Marshal(obj)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if want := `{"a&b":["x&y","<tag>"]}`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+}
+
+func TestFromMapAndToPlain(t *testing.T) {
+	plain := map[string]interface{}{
+		"z": "last",
+		"a": map[string]interface{}{"y": 1, "x": []interface{}{map[string]interface{}{"k": "v"}}},
+	}
+
+	obj := FromMap(plain)
+	if got := obj.Keys(); !reflect.DeepEqual(got, []string{"a", "z"}) {
+		t.Errorf("FromMap() keys = %v, want sorted [a z]", got)
+	}
+	nested, _ := obj.Get("a")
+	if _, ok := nested.(*Object); !ok {
+		t.Errorf("FromMap() nested value is %T, want *Object", nested)
+	}
+
+	if !reflect.DeepEqual(ToPlain(obj), plain) {
+		t.Errorf("ToPlain(FromMap()) = %#v, want %#v", ToPlain(obj), plain)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	t.Run("preserves source key order", func(t *testing.T) {
+		value, err := DecodeJSON(`{"zeta":1,"alpha":{"mid":true,"first":null},"list":[{"b":1,"a":2}]}`)
+		if err != nil {
+			t.Fatalf("DecodeJSON() error: %v", err)
+		}
+
+		obj := value.(*Object)
+		if got := obj.Keys(); !reflect.DeepEqual(got, []string{"zeta", "alpha", "list"}) {
+			t.Errorf("Keys() = %v, want [zeta alpha list]", got)
+		}
+		alpha, _ := obj.Get("alpha")
+		if got := alpha.(*Object).Keys(); !reflect.DeepEqual(got, []string{"mid", "first"}) {
+			t.Errorf("nested Keys() = %v, want [mid first]", got)
+		}
+		list, _ := obj.Get("list")
+		if got := list.([]interface{})[0].(*Object).Keys(); !reflect.DeepEqual(got, []string{"b", "a"}) {
+			t.Errorf("array element Keys() = %v, want [b a]", got)
+		}
+	})
+
+	t.Run("decodes scalars and arrays", func(t *testing.T) {
+		value, err := DecodeJSON(`["a", 1.5, 1000000, true, null, []]`)
+		if err != nil {
+			t.Fatalf("DecodeJSON() error: %v", err)
+		}
+		expected := []interface{}{"a", json.Number("1.5"), json.Number("1000000"), true, nil, []interface{}{}}
+		if !reflect.DeepEqual(value, expected) {
+			t.Errorf("DecodeJSON() = %#v, want %#v", value, expected)
+		}
+	})
+
+	for _, input := range []string{`{"a":`, `{"a":1} trailing`, `{"a" 1}`, ``} {
+		t.Run("rejects "+strings.TrimSpace(input), func(t *testing.T) {
+			if _, err := DecodeJSON(input); err == nil {
+				t.Errorf("DecodeJSON(%q) expected error, got nil", input)
+			}
+		})
+	}
+}
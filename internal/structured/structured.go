@@ -0,0 +1,110 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/jsonutil"
+)
+
+// Supported structured formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatAuto = "auto"
+)
+
+// Heuristics used by auto detection on the first meaningful line of a value.
+var (
+	tomlLinePattern = regexp.MustCompile(`^(\[\[?[^\]]+\]\]?|[A-Za-z0-9_."'-]+\s*=.*)$`)
+	yamlLinePattern = regexp.MustCompile(`^(-(\s|$)|[^\s:#][^:#]*:(\s|$)|---)`)
+)
+
+// IsValidFormat reports whether format is one of the supported structured formats.
+func IsValidFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case FormatJSON, FormatYAML, FormatTOML, FormatAuto:
+		return true
+	}
+	return false
+}
+
+// FormatFromPath maps a file extension to a structured format, or "" when the
+// extension is not recognized.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return ""
+}
+
+// Detect decides which format value should be parsed with, or returns "" when
+// it should be left alone. mode is the configured structured_format and hint
+// the format derived from the value's source file (may be empty).
+//
+// JSON-looking values use JSON (in the non-JSON modes only when they are valid
+// JSON, so a TOML table header is not mistaken for an array). In auto mode a
+// file hint wins; otherwise
+// only multi-line values whose first meaningful line looks like TOML or YAML
+// are considered, so ordinary strings such as "Note: done" are never flattened.
+func Detect(value, mode, hint string) string {
+	mode = strings.ToLower(mode)
+	if jsonutil.IsJSONLike(value) && (mode == "" || mode == FormatJSON || json.Valid([]byte(value))) {
+		return FormatJSON
+	}
+
+	switch mode {
+	case FormatYAML, FormatTOML:
+		return mode
+	case FormatAuto:
+		if hint != "" {
+			return hint
+		}
+		if !strings.Contains(value, "\n") {
+			return ""
+		}
+		line := firstMeaningfulLine(value)
+		switch {
+		case tomlLinePattern.MatchString(line):
+			return FormatTOML
+		case yamlLinePattern.MatchString(line):
+			return FormatYAML
+		}
+	}
+	return ""
+}
+
+// firstMeaningfulLine returns the first non-blank, non-comment line.
+func firstMeaningfulLine(value string) string {
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+// Parse decodes value with the given format into *Object mappings (keys in
+// source order), slices and scalars.
+func Parse(value, format string) (interface{}, error) {
+	switch format {
+	case FormatJSON:
+		return DecodeJSON(value)
+	case FormatYAML:
+		return ParseYAML(value)
+	case FormatTOML:
+		return ParseTOML(value)
+	default:
+		return nil, fmt.Errorf("unsupported structured format: %s", format)
+	}
+}
@@ -0,0 +1,80 @@
+package structured
+
+import "testing"
+
+func TestIsValidFormat(t *testing.T) {
+	for _, format := range []string{"json", "yaml", "toml", "auto", "YAML"} {
+		if !IsValidFormat(format) {
+			t.Errorf("IsValidFormat(%q) = false, want true", format)
+		}
+	}
+	for _, format := range []string{"", "xml", "yml"} {
+		if IsValidFormat(format) {
+			t.Errorf("IsValidFormat(%q) = true, want false", format)
+		}
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	tests := map[string]string{
+		"values.yaml":        FormatYAML,
+		"dir/compose.YML":    FormatYAML,
+		"Cargo.toml":         FormatTOML,
+		"/tmp/response.json": FormatJSON,
+		"notes.txt":          "",
+		"noext":              "",
+	}
+	for path, expected := range tests {
+		if got := FormatFromPath(path); got != expected {
+			t.Errorf("FormatFromPath(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		mode     string
+		hint     string
+		expected string
+	}{
+		{name: "JSON in json mode", value: `{"a":1}`, mode: FormatJSON, expected: FormatJSON},
+		{name: "Invalid JSON-like in json mode", value: `{broken}`, mode: FormatJSON, expected: FormatJSON},
+		{name: "Plain value in json mode", value: "a: b\nc: d", mode: FormatJSON, expected: ""},
+		{name: "YAML ignores hint in json mode", value: "a: b\nc: d", mode: FormatJSON, hint: FormatYAML, expected: ""},
+		{name: "Valid JSON in yaml mode", value: `[1,2]`, mode: FormatYAML, expected: FormatJSON},
+		{name: "Any value in yaml mode", value: "a: b", mode: FormatYAML, expected: FormatYAML},
+		{name: "TOML header in toml mode", value: "[a]\nb = [1]", mode: FormatTOML, expected: FormatTOML},
+		{name: "Auto uses file hint", value: "a: b", mode: FormatAuto, hint: FormatYAML, expected: FormatYAML},
+		{name: "Auto skips single-line values", value: "Note: done", mode: FormatAuto, expected: ""},
+		{name: "Auto detects YAML mapping", value: "# header\nserver:\n  host: x", mode: FormatAuto, expected: FormatYAML},
+		{name: "Auto detects YAML sequence", value: "- a\n- b", mode: FormatAuto, expected: FormatYAML},
+		{name: "Auto detects TOML table", value: "[server]\nhost = \"x\"", mode: FormatAuto, expected: FormatTOML},
+		{name: "Auto detects TOML key", value: "host = \"x\"\nport = 1", mode: FormatAuto, expected: FormatTOML},
+		{name: "Auto leaves prose alone", value: "first line\nsecond line", mode: FormatAuto, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.value, tt.mode, tt.hint); got != tt.expected {
+				t.Errorf("Detect() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse(`{"a":1}`, FormatJSON); err != nil {
+		t.Errorf("Parse(json) unexpected error: %v", err)
+	}
+	if _, err := Parse("a: 1", FormatYAML); err != nil {
+		t.Errorf("Parse(yaml) unexpected error: %v", err)
+	}
+	if _, err := Parse("a = 1", FormatTOML); err != nil {
+		t.Errorf("Parse(toml) unexpected error: %v", err)
+	}
+	if _, err := Parse("a", "xml"); err == nil {
+		t.Error("Parse(xml) expected error, got nil")
+	}
+}
@@ -0,0 +1,579 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TOML value patterns.
+var (
+	tomlIntPattern      = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)$`)
+	tomlFloatPattern    = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][-+]?[0-9](_?[0-9])*)?$`)
+	tomlDatePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	tomlDateTimePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?)?|\d{2}:\d{2}(:\d{2}(\.\d+)?)?)([Zz]|[-+]\d{2}:\d{2})?$`)
+	tomlBareKeyPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// tomlParser is a character-level TOML v1.0 parser covering tables, arrays of
+// tables, dotted keys, all string forms, integers, floats, booleans, date-times
+// (kept as strings), arrays and inline tables.
+type tomlParser struct {
+	s       string
+	pos     int
+	line    int
+	root    *Object
+	current *Object
+}
+
+// ParseTOML parses a TOML document into an *Object whose tables keep their
+// source order, with numbers returned as json.Number.
+func ParseTOML(src string) (*Object, error) {
+	root := NewObject()
+	p := &tomlParser{
+		s:       strings.ReplaceAll(src, "\r\n", "\n"),
+		line:    1,
+		root:    root,
+		current: root,
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// skipSpaces skips spaces and tabs.
+func (p *tomlParser) skipSpaces() {
+	for !p.eof() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment skips a '#' comment up to (not including) the newline.
+func (p *tomlParser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.eof() && p.s[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.line++
+			p.pos++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// expectLineEnd requires only whitespace or a comment before the next newline.
+func (p *tomlParser) expectLineEnd() error {
+	p.skipSpaces()
+	p.skipComment()
+	if p.eof() {
+		return nil
+	}
+	if p.s[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.peek() != '\n' {
+		return p.errorf("unexpected content %q", p.restOfLine())
+	}
+	return nil
+}
+
+func (p *tomlParser) restOfLine() string {
+	rest := p.s[p.pos:]
+	if idx := strings.IndexByte(rest, '\n'); idx >= 0 {
+		return rest[:idx]
+	}
+	return rest
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil
+		}
+
+		var err error
+		if p.peek() == '[' {
+			if strings.HasPrefix(p.s[p.pos:], "[[") {
+				err = p.parseArrayTable()
+			} else {
+				err = p.parseTable()
+			}
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.expectLineEnd(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseTable handles a [table] header.
+func (p *tomlParser) parseTable() error {
+	p.pos++ // '['
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpaces()
+	if p.peek() != ']' {
+		return p.errorf("expected ']' after table name")
+	}
+	p.pos++
+
+	table, err := p.descend(p.root, keys)
+	if err != nil {
+		return err
+	}
+	p.current = table
+	return nil
+}
+
+// parseArrayTable handles an [[array.of.tables]] header.
+func (p *tomlParser) parseArrayTable() error {
+	p.pos += 2 // '[['
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpaces()
+	if !strings.HasPrefix(p.s[p.pos:], "]]") {
+		return p.errorf("expected ']]' after array table name")
+	}
+	p.pos += 2
+
+	parent, err := p.descend(p.root, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+
+	table := NewObject()
+	existing, _ := parent.Get(last)
+	switch typed := existing.(type) {
+	case nil:
+		parent.Set(last, []interface{}{table})
+	case []interface{}:
+		parent.Set(last, append(typed, table))
+	default:
+		return p.errorf("key %q is already defined as a non-array value", last)
+	}
+	p.current = table
+	return nil
+}
+
+// descend walks keys from base, creating tables as needed. When a key holds an
+// array of tables, the most recently added table is used.
+func (p *tomlParser) descend(base *Object, keys []string) (*Object, error) {
+	table := base
+	for _, key := range keys {
+		value, _ := table.Get(key)
+		switch existing := value.(type) {
+		case nil:
+			child := NewObject()
+			table.Set(key, child)
+			table = child
+		case *Object:
+			table = existing
+		case []interface{}:
+			if len(existing) == 0 {
+				return nil, p.errorf("key %q is an empty array", key)
+			}
+			child, ok := existing[len(existing)-1].(*Object)
+			if !ok {
+				return nil, p.errorf("key %q is already defined as a non-table value", key)
+			}
+			table = child
+		default:
+			return nil, p.errorf("key %q is already defined as a non-table value", key)
+		}
+	}
+	return table, nil
+}
+
+// parseKeyValue parses "key = value" into table.
+func (p *tomlParser) parseKeyValue(table *Object) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpaces()
+	if p.peek() != '=' {
+		return p.errorf("expected '=' after key %q", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipSpaces()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := parent.Get(last); exists {
+		return p.errorf("duplicate key %q", strings.Join(keys, "."))
+	}
+	parent.Set(last, value)
+	return nil
+}
+
+// parseKey parses a bare, quoted or dotted key into its segments.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpaces()
+		var key string
+		switch p.peek() {
+		case '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := p.pos
+			for !p.eof() && isTOMLBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			key = p.s[start:p.pos]
+			if !tomlBareKeyPattern.MatchString(key) {
+				return nil, p.errorf("invalid key %q", strings.TrimSpace(p.restOfLine()))
+			}
+		}
+		keys = append(keys, key)
+
+		p.skipSpaces()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isTOMLBareKeyChar(ch byte) bool {
+	return ch == '_' || ch == '-' || (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9')
+}
+
+// parseValue parses any TOML value at the current position.
+func (p *tomlParser) parseValue() (interface{}, error) {
+	switch p.peek() {
+	case '"':
+		if strings.HasPrefix(p.s[p.pos:], `"""`) {
+			return p.parseMultilineBasicString()
+		}
+		return p.parseBasicString()
+	case '\'':
+		if strings.HasPrefix(p.s[p.pos:], "'''") {
+			return p.parseMultilineLiteralString()
+		}
+		return p.parseLiteralString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	case 0, '\n':
+		return nil, p.errorf("missing value")
+	}
+	return p.parseScalar()
+}
+
+// parseScalar parses booleans, numbers and date-times.
+func (p *tomlParser) parseScalar() (interface{}, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_.+-:", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	token := p.s[start:p.pos]
+
+	// A local date may be followed by a space and a time
+	if tomlDatePattern.MatchString(token) && p.pos+1 < len(p.s) && p.s[p.pos] == ' ' && p.s[p.pos+1] >= '0' && p.s[p.pos+1] <= '9' {
+		p.pos++
+		timeStart := p.pos
+		for !p.eof() && strings.IndexByte("0123456789:.+-Zz", p.s[p.pos]) >= 0 {
+			p.pos++
+		}
+		token += " " + p.s[timeStart:p.pos]
+	}
+
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		return token, nil
+	case "":
+		return nil, p.errorf("invalid value %q", strings.TrimSpace(p.restOfLine()))
+	}
+
+	switch {
+	case strings.HasPrefix(token, "0x"), strings.HasPrefix(token, "0o"), strings.HasPrefix(token, "0b"):
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[token[1]]
+		n, err := strconv.ParseInt(strings.ReplaceAll(token[2:], "_", ""), base, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %q", token)
+		}
+		return json.Number(strconv.FormatInt(n, 10)), nil
+	case tomlIntPattern.MatchString(token), tomlFloatPattern.MatchString(token):
+		return json.Number(strings.TrimPrefix(strings.ReplaceAll(token, "_", ""), "+")), nil
+	case tomlDateTimePattern.MatchString(token):
+		return token, nil
+	}
+	return nil, p.errorf("invalid value %q", token)
+}
+
+// parseArray parses [v1, v2, ...], allowing newlines, comments and a trailing comma.
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	p.pos++ // '['
+	arr := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return arr, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// parseInlineTable parses { key = value, ... }.
+func (p *tomlParser) parseInlineTable() (*Object, error) {
+	p.pos++ // '{'
+	table := NewObject()
+	p.skipSpaces()
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// parseBasicString parses a "double-quoted" string with escapes.
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++ // '"'
+	var b strings.Builder
+	for !p.eof() {
+		ch := p.s[p.pos]
+		switch ch {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\n':
+			return "", p.errorf("newline in basic string")
+		case '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte(ch)
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseMultilineBasicString parses a """multi-line""" string.
+func (p *tomlParser) parseMultilineBasicString() (string, error) {
+	p.pos += 3
+	p.skipLeadingNewline()
+	var b strings.Builder
+	for !p.eof() {
+		if strings.HasPrefix(p.s[p.pos:], `"""`) {
+			// Up to two extra quotes may directly precede the closing delimiter
+			for strings.HasPrefix(p.s[p.pos+1:], `"""`) {
+				b.WriteByte('"')
+				p.pos++
+			}
+			p.pos += 3
+			return b.String(), nil
+		}
+
+		ch := p.s[p.pos]
+		if ch == '\\' {
+			// Line-ending backslash trims all following whitespace and newlines
+			rest := strings.TrimLeft(p.s[p.pos+1:], " \t\r")
+			if strings.HasPrefix(rest, "\n") {
+				p.pos = len(p.s) - len(rest)
+				for !p.eof() && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+					if p.s[p.pos] == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if ch == '\n' {
+			p.line++
+		}
+		b.WriteByte(ch)
+		p.pos++
+	}
+	return "", p.errorf("unterminated multi-line string")
+}
+
+// parseLiteralString parses a 'single-quoted' string without escapes.
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++ // '\''
+	start := p.pos
+	for !p.eof() {
+		switch p.s[p.pos] {
+		case '\'':
+			value := p.s[start:p.pos]
+			p.pos++
+			return value, nil
+		case '\n':
+			return "", p.errorf("newline in literal string")
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated literal string")
+}
+
+// parseMultilineLiteralString parses a multi-line literal string delimited by three single quotes.
+func (p *tomlParser) parseMultilineLiteralString() (string, error) {
+	p.pos += 3
+	p.skipLeadingNewline()
+	end := strings.Index(p.s[p.pos:], "'''")
+	if end < 0 {
+		return "", p.errorf("unterminated multi-line literal string")
+	}
+	// Up to two extra quotes may directly precede the closing delimiter
+	for strings.HasPrefix(p.s[p.pos+end+1:], "'''") {
+		end++
+	}
+	value := p.s[p.pos : p.pos+end]
+	p.line += strings.Count(value, "\n")
+	p.pos += end + 3
+	return value, nil
+}
+
+// skipLeadingNewline drops a newline immediately following an opening delimiter.
+func (p *tomlParser) skipLeadingNewline() {
+	if strings.HasPrefix(p.s[p.pos:], "\n") {
+		p.pos++
+		p.line++
+	}
+}
+
+// parseEscape decodes the escape sequence at the current backslash.
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	if p.pos+1 >= len(p.s) {
+		return p.errorf("unterminated escape sequence")
+	}
+	esc := p.s[p.pos+1]
+	p.pos += 2
+	switch esc {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"', '\\':
+		b.WriteByte(esc)
+	case 'u', 'U':
+		width := 4
+		if esc == 'U' {
+			width = 8
+		}
+		if p.pos+width > len(p.s) {
+			return p.errorf("invalid \\%c escape", esc)
+		}
+		code, err := strconv.ParseUint(p.s[p.pos:p.pos+width], 16, 32)
+		if err != nil {
+			return p.errorf("invalid \\%c escape", esc)
+		}
+		b.WriteRune(rune(code))
+		p.pos += width
+	default:
+		return p.errorf("unknown escape sequence \\%c", esc)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package structured
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  map[string]interface{}
+		wantError bool
+		errMsg    string
+	}{
+		{
+			name:     "Empty document",
+			input:    "# comment only\n",
+			expected: map[string]interface{}{},
+		},
+		{
+			name:  "Scalar types",
+			input: "name = \"app\"\nport = 8_080\nratio = 0.5\nbig = 1e6\nenabled = true\nhex = 0xff\nwhen = 1979-05-27T07:32:00Z\nlocal = 1979-05-27 07:32:00\nday = 1979-05-27\n",
+			expected: map[string]interface{}{
+				"name":    "app",
+				"port":    json.Number("8080"),
+				"ratio":   json.Number("0.5"),
+				"big":     json.Number("1e6"),
+				"enabled": true,
+				"hex":     json.Number("255"),
+				"when":    "1979-05-27T07:32:00Z",
+				"local":   "1979-05-27 07:32:00",
+				"day":     "1979-05-27",
+			},
+		},
+		{
+			name:  "Tables and dotted keys",
+			input: "title = \"x\"\n\n[server]\nhost = \"example.com\" # comment\ntls.enabled = false\n\n[server.limits]\nmax = 10\n\n[\"quoted table\"]\nk = 'v'\n",
+			expected: map[string]interface{}{
+				"title": "x",
+				"server": map[string]interface{}{
+					"host":   "example.com",
+					"tls":    map[string]interface{}{"enabled": false},
+					"limits": map[string]interface{}{"max": json.Number("10")},
+				},
+				"quoted table": map[string]interface{}{"k": "v"},
+			},
+		},
+		{
+			name:  "Arrays and inline tables",
+			input: "ports = [ 80, 443, ]\nnested = [[1, 2], [\"a\"]]\nmulti = [\n  \"x\", # comment\n  \"y\"\n]\npoint = { x = 1, y.z = 2 }\n",
+			expected: map[string]interface{}{
+				"ports":  []interface{}{json.Number("80"), json.Number("443")},
+				"nested": []interface{}{[]interface{}{json.Number("1"), json.Number("2")}, []interface{}{"a"}},
+				"multi":  []interface{}{"x", "y"},
+				"point":  map[string]interface{}{"x": json.Number("1"), "y": map[string]interface{}{"z": json.Number("2")}},
+			},
+		},
+		{
+			name:  "Array of tables",
+			input: "[[servers]]\nname = \"a\"\n\n[[servers]]\nname = \"b\"\n\n[servers.meta]\nrole = \"backup\"\n",
+			expected: map[string]interface{}{
+				"servers": []interface{}{
+					map[string]interface{}{"name": "a"},
+					map[string]interface{}{"name": "b", "meta": map[string]interface{}{"role": "backup"}},
+				},
+			},
+		},
+		{
+			name:  "String forms",
+			input: "basic = \"tab\\tquote\\\" \\u00e9\"\nliteral = 'C:\\path'\nml = \"\"\"\nline1\nline2 \\\n   joined\"\"\"\nmll = '''\nraw \\n\n'''\n",
+			expected: map[string]interface{}{
+				"basic":   "tab\tquote\" é",
+				"literal": `C:\path`,
+				"ml":      "line1\nline2 joined",
+				"mll":     "raw \\n\n",
+			},
+		},
+		{
+			name:      "Duplicate key",
+			input:     "a = 1\na = 2\n",
+			wantError: true,
+			errMsg:    "toml line 2: duplicate key",
+		},
+		{
+			name:      "Missing equals",
+			input:     "a 1\n",
+			wantError: true,
+			errMsg:    "expected '='",
+		},
+		{
+			name:      "Invalid value",
+			input:     "a = nope\n",
+			wantError: true,
+			errMsg:    "invalid value",
+		},
+		{
+			name:      "Trailing garbage",
+			input:     "a = 1 2\n",
+			wantError: true,
+			errMsg:    "unexpected content",
+		},
+		{
+			name:      "Unterminated string",
+			input:     "a = \"open\n",
+			wantError: true,
+			errMsg:    "newline in basic string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseTOML(tt.input)
+
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("ParseTOML() expected error, got %#v", result)
+				}
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("ParseTOML() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseTOML() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ToPlain(result), tt.expected) {
+				t.Errorf("ParseTOML() = %#v, want %#v", ToPlain(result), tt.expected)
+			}
+		})
+	}
+}
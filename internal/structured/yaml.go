@@ -0,0 +1,778 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// YAML scalar patterns (YAML 1.2 core schema).
+var (
+	yamlIntPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlHexPattern   = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	yamlOctPattern   = regexp.MustCompile(`^0o[0-7]+$`)
+	yamlFloatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// mergeKey is the YAML merge key used to inherit entries from an aliased mapping.
+const mergeKey = "<<"
+
+// yamlLine is a single source line with its indentation and comment stripped.
+type yamlLine struct {
+	num    int    // 1-based line number
+	indent int    // number of leading spaces
+	text   string // content without indentation or trailing comment
+	raw    string // original line, used verbatim by block scalars
+}
+
+// yamlParser is an indentation-driven parser for the block and flow subset of
+// YAML used by configuration files: mappings, sequences, plain/quoted scalars,
+// literal and folded block scalars, flow collections, anchors, aliases and the
+// "<<" merge key. Only the first document of a stream is read.
+type yamlParser struct {
+	lines   []yamlLine
+	pos     int
+	anchors map[string]interface{}
+}
+
+// ParseYAML parses a YAML document into *Object (mappings, in source order),
+// []interface{}, string, bool and nil values, with numbers returned as
+// json.Number holding the source text.
+func ParseYAML(src string) (interface{}, error) {
+	p := &yamlParser{
+		lines:   splitYAMLLines(src),
+		anchors: make(map[string]interface{}),
+	}
+
+	p.skipBlank()
+	if p.eof() {
+		return nil, nil
+	}
+
+	node, err := p.parseBlock(p.cur().indent)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+	if !p.eof() {
+		return nil, p.errorf(p.cur(), "unexpected content %q", p.cur().text)
+	}
+	return node, nil
+}
+
+// splitYAMLLines splits src into lines, dropping directives and stopping at the
+// end of the first document.
+func splitYAMLLines(src string) []yamlLine {
+	rawLines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	lines := make([]yamlLine, 0, len(rawLines))
+	seenContent := false
+
+	for i, raw := range rawLines {
+		if strings.HasPrefix(raw, "%") {
+			continue
+		}
+		if raw == "---" || strings.HasPrefix(raw, "--- ") {
+			if seenContent {
+				break
+			}
+			continue
+		}
+		if raw == "..." {
+			break
+		}
+
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		text := strings.TrimSpace(stripYAMLComment(raw[indent:]))
+		if text != "" {
+			seenContent = true
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: indent, text: text, raw: raw})
+	}
+	return lines
+}
+
+// stripYAMLComment removes a '#' comment that is outside quotes and either
+// starts the line or follows whitespace.
+func stripYAMLComment(s string) string {
+	inSingle, inDouble := false, false
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\\' && inDouble:
+			i++
+		case ch == '\'' && !inDouble:
+			inSingle = !inSingle
+		case ch == '"' && !inSingle:
+			inDouble = !inDouble
+		case ch == '#' && !inSingle && !inDouble && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func (p *yamlParser) eof() bool {
+	return p.pos >= len(p.lines)
+}
+
+func (p *yamlParser) cur() yamlLine {
+	return p.lines[p.pos]
+}
+
+// skipBlank advances past blank and comment-only lines.
+func (p *yamlParser) skipBlank() {
+	for !p.eof() && p.cur().text == "" {
+		p.pos++
+	}
+}
+
+func (p *yamlParser) errorf(line yamlLine, format string, args ...interface{}) error {
+	return fmt.Errorf("yaml line %d: %s", line.num, fmt.Sprintf(format, args...))
+}
+
+// parseBlock parses the node starting at the current line, which must be
+// indented by exactly indent spaces.
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	p.skipBlank()
+	if p.eof() || p.cur().indent < indent {
+		return nil, nil
+	}
+
+	line := p.cur()
+	if isYAMLSeqItem(line.text) {
+		return p.parseSequence(line.indent)
+	}
+	if _, _, ok := splitYAMLMappingEntry(line.text); ok {
+		return p.parseMapping(line.indent)
+	}
+
+	p.pos++
+	return p.parseInlineValue(line.text, line.indent-1, line)
+}
+
+// parseChild parses the block nested under a parent at parentIndent, or
+// returns nil when the next line is not more indented.
+func (p *yamlParser) parseChild(parentIndent int) (interface{}, error) {
+	p.skipBlank()
+	if p.eof() || p.cur().indent <= parentIndent {
+		return nil, nil
+	}
+	return p.parseBlock(p.cur().indent)
+}
+
+// parseSequence parses "- item" entries at the given indentation.
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	seq := []interface{}{}
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			break
+		}
+		line := p.cur()
+		if line.indent != indent || !isYAMLSeqItem(line.text) {
+			if line.indent > indent {
+				return nil, p.errorf(line, "bad indentation of a sequence entry")
+			}
+			break
+		}
+
+		afterDash := line.text[1:]
+		rest := strings.TrimLeft(afterDash, " ")
+		offset := indent + 1 + len(afterDash) - len(rest)
+
+		anchor, rest := takeYAMLProperties(rest)
+
+		var item interface{}
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			item, err = p.parseChild(indent)
+		case isYAMLSeqItem(rest) || isYAMLMappingEntry(rest):
+			// Compact nested node ("- key: value" / "- - item"): re-read the
+			// remainder of this line as if it started at its own column.
+			p.lines[p.pos] = yamlLine{num: line.num, indent: offset, text: rest, raw: line.raw}
+			item, err = p.parseBlock(offset)
+		default:
+			p.pos++
+			item, err = p.parseInlineValue(rest, indent, line)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if anchor != "" {
+			p.anchors[anchor] = item
+		}
+		seq = append(seq, item)
+	}
+
+	return seq, nil
+}
+
+// parseMapping parses "key: value" entries at the given indentation.
+func (p *yamlParser) parseMapping(indent int) (*Object, error) {
+	m := NewObject()
+	var merges []interface{}
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			break
+		}
+		line := p.cur()
+		if line.indent != indent {
+			if line.indent > indent {
+				return nil, p.errorf(line, "bad indentation of a mapping entry")
+			}
+			break
+		}
+		key, rest, ok := splitYAMLMappingEntry(line.text)
+		if !ok {
+			if isYAMLSeqItem(line.text) {
+				return nil, p.errorf(line, "unexpected sequence entry in mapping")
+			}
+			return nil, p.errorf(line, "expected \"key: value\", got %q", line.text)
+		}
+		p.pos++
+
+		anchor, rest := takeYAMLProperties(rest)
+
+		var value interface{}
+		var err error
+		switch {
+		case rest == "":
+			p.skipBlank()
+			if !p.eof() && p.cur().indent == indent && isYAMLSeqItem(p.cur().text) {
+				// A sequence may sit at the same indentation as its key
+				value, err = p.parseSequence(indent)
+			} else {
+				value, err = p.parseChild(indent)
+			}
+		case rest[0] == '|' || rest[0] == '>':
+			value, err = p.parseBlockScalar(rest, indent, line)
+		default:
+			value, err = p.parseInlineValue(rest, indent, line)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if anchor != "" {
+			p.anchors[anchor] = value
+		}
+		if key == mergeKey {
+			merges = append(merges, value)
+			continue
+		}
+		m.Set(key, value)
+	}
+
+	// Merged entries never override keys set explicitly in this mapping
+	for _, merge := range merges {
+		sources, ok := merge.([]interface{})
+		if !ok {
+			sources = []interface{}{merge}
+		}
+		for _, source := range sources {
+			sourceObj, ok := source.(*Object)
+			if !ok {
+				return nil, fmt.Errorf("yaml: merge key value must be a mapping")
+			}
+			for _, k := range sourceObj.Keys() {
+				if _, exists := m.Get(k); !exists {
+					v, _ := sourceObj.Get(k)
+					m.Set(k, v)
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// parseInlineValue parses a value that starts on the current line: an alias,
+// a flow collection, a quoted scalar or a (possibly multi-line) plain scalar.
+// Continuation lines must be indented deeper than parentIndent.
+func (p *yamlParser) parseInlineValue(text string, parentIndent int, line yamlLine) (interface{}, error) {
+	switch text[0] {
+	case '*':
+		name := strings.TrimSpace(text[1:])
+		value, ok := p.anchors[name]
+		if !ok {
+			return nil, p.errorf(line, "unknown alias %q", name)
+		}
+		return value, nil
+	case '[', '{':
+		for !flowBalanced(text) && !p.eof() {
+			if next := p.cur(); next.text != "" {
+				text += " " + next.text
+			}
+			p.pos++
+		}
+		value, err := parseYAMLFlow(text, p.anchors)
+		if err != nil {
+			return nil, p.errorf(line, "%v", err)
+		}
+		return value, nil
+	case '"', '\'':
+		for !quoteClosed(text) && !p.eof() {
+			text += " " + strings.TrimSpace(p.cur().raw)
+			p.pos++
+		}
+		value, rest, err := unquoteYAML(text)
+		if err != nil {
+			return nil, p.errorf(line, "%v", err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, p.errorf(line, "unexpected content after quoted scalar: %q", rest)
+		}
+		return value, nil
+	}
+
+	// Plain scalar, folded across more-indented continuation lines
+	parts := []string{text}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			break
+		}
+		next := p.cur()
+		if next.indent <= parentIndent || isYAMLSeqItem(next.text) || isYAMLMappingEntry(next.text) {
+			break
+		}
+		parts = append(parts, next.text)
+		p.pos++
+	}
+	return resolveYAMLPlain(strings.Join(parts, " ")), nil
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) block scalar.
+func (p *yamlParser) parseBlockScalar(header string, parentIndent int, line yamlLine) (string, error) {
+	style := header[0]
+	chomp := byte(0)
+	explicitIndent := 0
+	for _, ch := range header[1:] {
+		switch {
+		case ch == '-' || ch == '+':
+			chomp = byte(ch)
+		case ch >= '1' && ch <= '9':
+			explicitIndent = int(ch - '0')
+		case ch == ' ' || ch == '\t':
+		default:
+			return "", p.errorf(line, "invalid block scalar header %q", header)
+		}
+	}
+
+	// Collect raw lines that are blank or indented deeper than the parent
+	var raws []string
+	for !p.eof() {
+		raw := p.cur().raw
+		rawIndent := len(raw) - len(strings.TrimLeft(raw, " "))
+		if strings.TrimSpace(raw) != "" && rawIndent <= parentIndent {
+			break
+		}
+		raws = append(raws, raw)
+		p.pos++
+	}
+
+	contentIndent := parentIndent + explicitIndent
+	if explicitIndent == 0 {
+		for _, raw := range raws {
+			if strings.TrimSpace(raw) != "" {
+				contentIndent = len(raw) - len(strings.TrimLeft(raw, " "))
+				break
+			}
+		}
+	}
+
+	content := make([]string, len(raws))
+	for i, raw := range raws {
+		if len(raw) >= contentIndent {
+			content[i] = raw[contentIndent:]
+		} else {
+			content[i] = strings.TrimLeft(raw, " ")
+		}
+	}
+
+	// Separate trailing blank lines for chomping
+	trailing := 0
+	for len(content) > 0 && strings.TrimSpace(content[len(content)-1]) == "" {
+		content = content[:len(content)-1]
+		trailing++
+	}
+
+	var body string
+	if style == '|' {
+		body = strings.Join(content, "\n")
+	} else {
+		body = foldYAMLLines(content)
+	}
+
+	switch chomp {
+	case '-':
+		return body, nil
+	case '+':
+		return body + strings.Repeat("\n", trailing+1), nil
+	default:
+		if body == "" {
+			return "", nil
+		}
+		return body + "\n", nil
+	}
+}
+
+// foldYAMLLines joins folded block scalar lines: adjacent lines are joined with
+// a space, blank lines become newlines and more-indented lines keep their breaks.
+func foldYAMLLines(lines []string) string {
+	var b strings.Builder
+	prevText := false
+	for i, line := range lines {
+		moreIndented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		switch {
+		case line == "":
+			b.WriteString("\n")
+			prevText = false
+		case i == 0:
+			b.WriteString(line)
+			prevText = !moreIndented
+		case prevText && !moreIndented:
+			b.WriteString(" " + line)
+		default:
+			if i > 0 && lines[i-1] != "" {
+				b.WriteString("\n")
+			}
+			b.WriteString(line)
+			prevText = !moreIndented
+		}
+	}
+	return b.String()
+}
+
+// isYAMLSeqItem reports whether text is a block sequence entry.
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isYAMLMappingEntry reports whether text is a "key: value" entry.
+func isYAMLMappingEntry(text string) bool {
+	_, _, ok := splitYAMLMappingEntry(text)
+	return ok
+}
+
+// splitYAMLMappingEntry splits "key: value" into its key and the trimmed rest.
+func splitYAMLMappingEntry(text string) (string, string, bool) {
+	if text == "" {
+		return "", "", false
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		key, rest, err := unquoteYAML(text)
+		if err != nil {
+			return "", "", false
+		}
+		rest = strings.TrimLeft(rest, " ")
+		if rest == ":" || strings.HasPrefix(rest, ": ") {
+			return key, strings.TrimSpace(rest[1:]), true
+		}
+		return "", "", false
+	}
+
+	switch text[0] {
+	case '[', '{', '#', '|', '>', '*', '&', '!', '%', '@', '`':
+		return "", "", false
+	}
+	if isYAMLSeqItem(text) {
+		return "", "", false
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+			key := strings.TrimSpace(text[:i])
+			if key == "" {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// takeYAMLProperties strips a leading anchor (&name) and tag (!tag) from a
+// value, returning the anchor name and the remaining text.
+func takeYAMLProperties(text string) (string, string) {
+	anchor := ""
+	for text != "" && (text[0] == '&' || text[0] == '!') {
+		token := text
+		rest := ""
+		if idx := strings.IndexAny(text, " \t"); idx >= 0 {
+			token, rest = text[:idx], strings.TrimSpace(text[idx:])
+		}
+		if token[0] == '&' {
+			anchor = token[1:]
+		}
+		text = rest
+	}
+	return anchor, text
+}
+
+// resolveYAMLPlain converts a plain scalar to null, bool, number or string.
+func resolveYAMLPlain(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+
+	switch {
+	case yamlIntPattern.MatchString(s) || yamlFloatPattern.MatchString(s):
+		return json.Number(strings.TrimPrefix(s, "+"))
+	case yamlHexPattern.MatchString(s):
+		if n, err := strconv.ParseInt(s[2:], 16, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	case yamlOctPattern.MatchString(s):
+		if n, err := strconv.ParseInt(s[2:], 8, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	}
+	return s
+}
+
+// unquoteYAML parses a single- or double-quoted scalar at the start of s and
+// returns its value plus the unconsumed remainder.
+func unquoteYAML(s string) (string, string, error) {
+	quote := s[0]
+	var b strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		ch := s[i]
+		if quote == '\'' {
+			if ch == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+					continue
+				}
+				return b.String(), s[i+1:], nil
+			}
+			b.WriteByte(ch)
+			continue
+		}
+
+		switch ch {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", "", fmt.Errorf("unterminated escape sequence")
+			}
+			i++
+			switch esc := s[i]; esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case 'e':
+				b.WriteByte(0x1b)
+			case '"', '\\', '/', ' ':
+				b.WriteByte(esc)
+			case 'x', 'u', 'U':
+				width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[esc]
+				if i+width >= len(s) {
+					return "", "", fmt.Errorf("invalid \\%c escape", esc)
+				}
+				code, err := strconv.ParseUint(s[i+1:i+1+width], 16, 32)
+				if err != nil {
+					return "", "", fmt.Errorf("invalid \\%c escape: %w", esc, err)
+				}
+				b.WriteRune(rune(code))
+				i += width
+			default:
+				return "", "", fmt.Errorf("unknown escape sequence \\%c", esc)
+			}
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted scalar")
+}
+
+// quoteClosed reports whether the quoted scalar at the start of s is closed.
+func quoteClosed(s string) bool {
+	_, _, err := unquoteYAML(s)
+	return err == nil
+}
+
+// flowBalanced reports whether all brackets in a flow collection are closed.
+func flowBalanced(s string) bool {
+	depth := 0
+	inSingle, inDouble := false, false
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\\' && inDouble:
+			i++
+		case ch == '\'' && !inDouble:
+			inSingle = !inSingle
+		case ch == '"' && !inSingle:
+			inDouble = !inDouble
+		case inSingle || inDouble:
+		case ch == '[' || ch == '{':
+			depth++
+		case ch == ']' || ch == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// yamlFlowParser parses flow collections such as [a, b] and {k: v}.
+type yamlFlowParser struct {
+	s       string
+	pos     int
+	anchors map[string]interface{}
+}
+
+// parseYAMLFlow parses a complete flow collection.
+func parseYAMLFlow(s string, anchors map[string]interface{}) (interface{}, error) {
+	fp := &yamlFlowParser{s: s, anchors: anchors}
+	value, err := fp.parseValue(false)
+	if err != nil {
+		return nil, err
+	}
+	fp.skipSpaces()
+	if fp.pos < len(fp.s) {
+		return nil, fmt.Errorf("unexpected content after flow collection: %q", fp.s[fp.pos:])
+	}
+	return value, nil
+}
+
+func (fp *yamlFlowParser) skipSpaces() {
+	for fp.pos < len(fp.s) && (fp.s[fp.pos] == ' ' || fp.s[fp.pos] == '\t') {
+		fp.pos++
+	}
+}
+
+// parseValue parses a flow node. In key position a plain scalar also stops at ':'.
+func (fp *yamlFlowParser) parseValue(isKey bool) (interface{}, error) {
+	fp.skipSpaces()
+	if fp.pos >= len(fp.s) {
+		return nil, fmt.Errorf("unexpected end of flow collection")
+	}
+
+	switch fp.s[fp.pos] {
+	case '[':
+		return fp.parseSequence()
+	case '{':
+		return fp.parseMapping()
+	case '"', '\'':
+		value, rest, err := unquoteYAML(fp.s[fp.pos:])
+		if err != nil {
+			return nil, err
+		}
+		fp.pos = len(fp.s) - len(rest)
+		return value, nil
+	case '*':
+		start := fp.pos + 1
+		for fp.pos < len(fp.s) && !strings.ContainsRune(",]} ", rune(fp.s[fp.pos])) {
+			fp.pos++
+		}
+		value, ok := fp.anchors[fp.s[start:fp.pos]]
+		if !ok {
+			return nil, fmt.Errorf("unknown alias %q", fp.s[start:fp.pos])
+		}
+		return value, nil
+	}
+
+	stops := ",]}"
+	if isKey {
+		stops += ":"
+	}
+	start := fp.pos
+	for fp.pos < len(fp.s) && !strings.ContainsRune(stops, rune(fp.s[fp.pos])) {
+		fp.pos++
+	}
+	return resolveYAMLPlain(strings.TrimSpace(fp.s[start:fp.pos])), nil
+}
+
+func (fp *yamlFlowParser) parseSequence() ([]interface{}, error) {
+	fp.pos++ // '['
+	seq := []interface{}{}
+	for {
+		fp.skipSpaces()
+		if fp.pos >= len(fp.s) {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		if fp.s[fp.pos] == ']' {
+			fp.pos++
+			return seq, nil
+		}
+
+		item, err := fp.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, item)
+
+		fp.skipSpaces()
+		if fp.pos < len(fp.s) && fp.s[fp.pos] == ',' {
+			fp.pos++
+		} else if fp.pos < len(fp.s) && fp.s[fp.pos] != ']' {
+			return nil, fmt.Errorf("expected ',' or ']' in flow sequence")
+		}
+	}
+}
+
+func (fp *yamlFlowParser) parseMapping() (*Object, error) {
+	fp.pos++ // '{'
+	m := NewObject()
+	for {
+		fp.skipSpaces()
+		if fp.pos >= len(fp.s) {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+		if fp.s[fp.pos] == '}' {
+			fp.pos++
+			return m, nil
+		}
+
+		rawKey, err := fp.parseValue(true)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%v", rawKey)
+		if rawKey == nil {
+			key = ""
+		}
+
+		var value interface{}
+		fp.skipSpaces()
+		if fp.pos < len(fp.s) && fp.s[fp.pos] == ':' {
+			fp.pos++
+			fp.skipSpaces()
+			if fp.pos < len(fp.s) && fp.s[fp.pos] != ',' && fp.s[fp.pos] != '}' {
+				if value, err = fp.parseValue(false); err != nil {
+					return nil, err
+				}
+			}
+		}
+		m.Set(key, value)
+
+		fp.skipSpaces()
+		if fp.pos < len(fp.s) && fp.s[fp.pos] == ',' {
+			fp.pos++
+		} else if fp.pos < len(fp.s) && fp.s[fp.pos] != '}' {
+			return nil, fmt.Errorf("expected ',' or '}' in flow mapping")
+		}
+	}
+}
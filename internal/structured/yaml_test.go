@@ -0,0 +1,161 @@
+package structured
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  interface{}
+		wantError bool
+		errMsg    string
+	}{
+		{
+			name:     "Empty document",
+			input:    "# only a comment\n",
+			expected: nil,
+		},
+		{
+			name:  "Flat mapping with scalar types",
+			input: "name: app\nport: 8080\nratio: 0.5\nenabled: true\nnothing: null\ntilde: ~\nhex: 0x1F\n",
+			expected: map[string]interface{}{
+				"name":    "app",
+				"port":    json.Number("8080"),
+				"ratio":   json.Number("0.5"),
+				"enabled": true,
+				"nothing": nil,
+				"tilde":   nil,
+				"hex":     json.Number("31"),
+			},
+		},
+		{
+			name:  "Nested mappings and comments",
+			input: "---\nserver:\n  host: example.com # inline comment\n  # full-line comment\n  tls:\n    enabled: false\n",
+			expected: map[string]interface{}{
+				"server": map[string]interface{}{
+					"host": "example.com",
+					"tls":  map[string]interface{}{"enabled": false},
+				},
+			},
+		},
+		{
+			name:  "Sequences of scalars and mappings",
+			input: "ports:\n  - 80\n  - 443\nservers:\n- name: a\n  port: 1\n- name: b\n  port: 2\n",
+			expected: map[string]interface{}{
+				"ports": []interface{}{json.Number("80"), json.Number("443")},
+				"servers": []interface{}{
+					map[string]interface{}{"name": "a", "port": json.Number("1")},
+					map[string]interface{}{"name": "b", "port": json.Number("2")},
+				},
+			},
+		},
+		{
+			name:     "Top-level sequence with nested sequence",
+			input:    "- a\n- - b\n  - c\n-\n  d: e\n",
+			expected: []interface{}{"a", []interface{}{"b", "c"}, map[string]interface{}{"d": "e"}},
+		},
+		{
+			name:  "Quoted scalars and keys",
+			input: "\"quoted key\": \"line\\nbreak \\\"x\\\"\"\nsingle: 'it''s # not a comment'\nurl: http://host:8080/path\n",
+			expected: map[string]interface{}{
+				"quoted key": "line\nbreak \"x\"",
+				"single":     "it's # not a comment",
+				"url":        "http://host:8080/path",
+			},
+		},
+		{
+			name:  "Flow collections",
+			input: "tags: [a, \"b c\", 3]\nlabels: {app: web, tier: \"front\"}\nmulti: [\n  x,\n  y\n]\n",
+			expected: map[string]interface{}{
+				"tags":   []interface{}{"a", "b c", json.Number("3")},
+				"labels": map[string]interface{}{"app": "web", "tier": "front"},
+				"multi":  []interface{}{"x", "y"},
+			},
+		},
+		{
+			name:  "Literal and folded block scalars",
+			input: "script: |\n  echo one\n  echo two\nsummary: >-\n  folded\n  text\n\n  para\nkeep: |+\n  x\n\nend: 1\n",
+			expected: map[string]interface{}{
+				"script":  "echo one\necho two\n",
+				"summary": "folded text\npara",
+				"keep":    "x\n\n",
+				"end":     json.Number("1"),
+			},
+		},
+		{
+			name:  "Block scalar keeps comment-like lines",
+			input: "run: |\n  # not a comment\n  make\n",
+			expected: map[string]interface{}{
+				"run": "# not a comment\nmake\n",
+			},
+		},
+		{
+			name:  "Multi-line plain scalar",
+			input: "description: first\n  second\nnext: x\n",
+			expected: map[string]interface{}{
+				"description": "first second",
+				"next":        "x",
+			},
+		},
+		{
+			name:  "Anchors, aliases and merge keys",
+			input: "defaults: &defaults\n  image: nginx\n  replicas: 1\nweb:\n  <<: *defaults\n  replicas: 3\ncopy: *defaults\n",
+			expected: map[string]interface{}{
+				"defaults": map[string]interface{}{"image": "nginx", "replicas": json.Number("1")},
+				"web":      map[string]interface{}{"image": "nginx", "replicas": json.Number("3")},
+				"copy":     map[string]interface{}{"image": "nginx", "replicas": json.Number("1")},
+			},
+		},
+		{
+			name:     "Only the first document is read",
+			input:    "a: 1\n---\nb: 2\n",
+			expected: map[string]interface{}{"a": json.Number("1")},
+		},
+		{
+			name:      "Bad indentation",
+			input:     "a:\n  b: 1\n    c: 2\n",
+			wantError: true,
+			errMsg:    "yaml line 3",
+		},
+		{
+			name:      "Unknown alias",
+			input:     "a: *missing\n",
+			wantError: true,
+			errMsg:    "unknown alias",
+		},
+		{
+			name:      "Unterminated flow sequence",
+			input:     "a: [1, 2\n",
+			wantError: true,
+			errMsg:    "unterminated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseYAML(tt.input)
+
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("ParseYAML() expected error, got %#v", result)
+				}
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("ParseYAML() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseYAML() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ToPlain(result), tt.expected) {
+				t.Errorf("ParseYAML() = %#v, want %#v", ToPlain(result), tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package summary
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strings"
+)
+
+// Destinations a key can be written to
+const (
+	DestinationEnv    = "env"
+	DestinationOutput = "output"
+	DestinationBoth   = "both"
+	DestinationPath   = "path"
+	DestinationState  = "state"
+)
+
+// Sources a value can come from
+const (
+	SourceLiteral      = "literal"
+	SourceFile         = "file://"
+	SourceEnvFile      = "env_file"
+	SourceInterpolated = "interpolated"
+	SourceTemplated    = "templated"
+	SourceSelected     = "json_select"
+	SourceFlattened    = "JSON-flattened"
+)
+
+// Entry describes one key written by the action.
+type Entry struct {
+	Key             string   // Final key name (including any group prefix)
+	Destination     string   // env, output, both, path or state
+	Source          string   // Where the value came from, e.g. "file://, interpolated"
+	Transformations []string // Transformations that changed the value
+	Value           string   // Value as written, already masked
+}
+
+// Report collects the entries of a run and renders them as Markdown for
+// $GITHUB_STEP_SUMMARY.
+type Report struct {
+	entries []Entry
+	index   map[string]int
+}
+
+// NewReport creates an empty Report.
+func NewReport() *Report {
+	return &Report{index: make(map[string]int)}
+}
+
+// Add records an entry. A key already recorded for the other of env/output is
+// merged into a single row with destination "both".
+func (r *Report) Add(entry Entry) {
+	if i, ok := r.index[entry.Key]; ok && isEnvOrOutput(entry.Destination) && isEnvOrOutput(r.entries[i].Destination) {
+		existing := &r.entries[i]
+		if existing.Destination != entry.Destination && existing.Destination != DestinationBoth {
+			existing.Destination = DestinationBoth
+			return
+		}
+	}
+	r.index[entry.Key] = len(r.entries)
+	r.entries = append(r.entries, entry)
+}
+
+// isEnvOrOutput reports whether destination takes part in "both" merging.
+func isEnvOrOutput(destination string) bool {
+	return destination == DestinationEnv || destination == DestinationOutput || destination == DestinationBoth
+}
+
+// Entries returns the recorded entries in the order they were added.
+func (r *Report) Entries() []Entry {
+	return r.entries
+}
+
+// Markdown renders the report. When errMsg is not empty a failure section
+// holding it is appended after the table of the keys set before the failure.
+func (r *Report) Markdown(errMsg string) string {
+	var b strings.Builder
+	b.WriteString("## Environment and Output Setter\n\n")
+
+	if len(r.entries) == 0 {
+		b.WriteString("_No variables were set._\n")
+	} else {
+		b.WriteString("| Key | Destination | Source | Transformations | Value |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, e := range r.entries {
+			transformations := "-"
+			if len(e.Transformations) > 0 {
+				transformations = strings.Join(e.Transformations, ", ")
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				escapeCell(e.Key),
+				escapeCell(e.Destination),
+				escapeCell(e.Source),
+				escapeCell(transformations),
+				escapeCell(e.Value))
+		}
+	}
+
+	if errMsg != "" {
+		b.WriteString("\n### :x: Failed\n\n")
+		b.WriteString("```text\n")
+		b.WriteString(strings.ReplaceAll(errMsg, "```", "'''"))
+		b.WriteString("\n```\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// Append appends the rendered report to the file at path (normally
+// $GITHUB_STEP_SUMMARY).
+func (r *Report) Append(path, errMsg string) (err error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open step summary: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close step summary: %w", cerr)
+		}
+	}()
+
+	if _, err := f.WriteString(r.Markdown(errMsg)); err != nil {
+		return fmt.Errorf("failed to write step summary: %w", err)
+	}
+	return nil
+}
+
+// escapeCell makes s safe to place in a Markdown table cell: HTML is escaped,
+// pipes are backslash-escaped and line breaks become <br>.
+func escapeCell(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	s = strings.ReplaceAll(s, "\r", "<br>")
+	return s
+}
@@ -0,0 +1,134 @@
+package summary
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportAdd(t *testing.T) {
+	r := NewReport()
+	r.Add(Entry{Key: "A", Destination: DestinationEnv, Source: SourceLiteral})
+	r.Add(Entry{Key: "B", Destination: DestinationOutput, Source: SourceLiteral})
+	r.Add(Entry{Key: "A", Destination: DestinationOutput, Source: SourceLiteral})
+	r.Add(Entry{Key: "B", Destination: DestinationOutput, Source: SourceLiteral})
+
+	entries := r.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("Entries() = %v, want 3 entries", entries)
+	}
+	if entries[0].Key != "A" || entries[0].Destination != DestinationBoth {
+		t.Errorf("entries[0] = %+v, want A merged into both", entries[0])
+	}
+	if entries[1].Key != "B" || entries[1].Destination != DestinationOutput {
+		t.Errorf("entries[1] = %+v, want B output", entries[1])
+	}
+	// A repeated key in the same destination keeps its own row
+	if entries[2].Key != "B" || entries[2].Destination != DestinationOutput {
+		t.Errorf("entries[2] = %+v, want second B output", entries[2])
+	}
+}
+
+func TestReportAddPathAndState(t *testing.T) {
+	r := NewReport()
+	r.Add(Entry{Key: "PATH", Destination: DestinationPath, Source: SourceLiteral, Value: "/opt/a"})
+	r.Add(Entry{Key: "PATH", Destination: DestinationPath, Source: SourceLiteral, Value: "/opt/b"})
+	r.Add(Entry{Key: "TOKEN", Destination: DestinationEnv, Source: SourceLiteral})
+	r.Add(Entry{Key: "TOKEN", Destination: DestinationState, Source: SourceLiteral})
+
+	entries := r.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("Entries() = %v, want 4 entries", entries)
+	}
+	// path and state rows are never merged into "both"
+	if entries[2].Destination != DestinationEnv || entries[3].Destination != DestinationState {
+		t.Errorf("Entries() = %+v, want separate env and state rows", entries)
+	}
+}
+
+func TestReportMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []Entry
+		errMsg   string
+		contains []string
+		absent   []string
+	}{
+		{
+			name:     "Empty report",
+			contains: []string{"## Environment and Output Setter", "_No variables were set._"},
+			absent:   []string{"| Key |", "Failed"},
+		},
+		{
+			name: "Table rows",
+			entries: []Entry{
+				{Key: "HOST", Destination: DestinationEnv, Source: SourceLiteral, Value: "example.com"},
+				{Key: "CFG_port", Destination: DestinationOutput, Source: SourceFlattened, Transformations: []string{"to_upper", "max_length(5)"}, Value: "***"},
+			},
+			contains: []string{
+				"| Key | Destination | Source | Transformations | Value |",
+				"| HOST | env | literal | - | example.com |",
+				"| CFG_port | output | JSON-flattened | to_upper, max_length(5) | *** |",
+			},
+		},
+		{
+			name: "Cells are escaped",
+			entries: []Entry{
+				{Key: "K", Destination: DestinationEnv, Source: SourceFile, Value: "a|b\nc <script>"},
+			},
+			contains: []string{`| K | env | file:// | - | a\|b<br>c &lt;script&gt; |`},
+		},
+		{
+			name:     "Failure section",
+			entries:  []Entry{{Key: "A", Destination: DestinationEnv, Source: SourceLiteral, Value: "1"}},
+			errMsg:   "Error setting output variables: empty value for key: B",
+			contains: []string{"| A | env |", "### :x: Failed", "```text\nError setting output variables: empty value for key: B\n```"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReport()
+			for _, e := range tt.entries {
+				r.Add(e)
+			}
+			got := r.Markdown(tt.errMsg)
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Markdown() missing %q in:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.absent {
+				if strings.Contains(got, notWant) {
+					t.Errorf("Markdown() unexpectedly contains %q in:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReportAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	if err := os.WriteFile(path, []byte("# Previous step\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReport()
+	r.Add(Entry{Key: "A", Destination: DestinationEnv, Source: SourceLiteral, Value: "1"})
+	if err := r.Append(path, ""); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "# Previous step\n## Environment and Output Setter") {
+		t.Errorf("Append() did not append to existing content:\n%s", content)
+	}
+
+	if err := r.Append(filepath.Join(t.TempDir(), "missing", "summary.md"), ""); err == nil {
+		t.Error("Append() expected error for missing directory")
+	}
+}
@@ -0,0 +1,310 @@
+package transformer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/somaz94/env-output-setter/internal/keyname"
+)
+
+// Error messages for transform function arguments
+const (
+	errFunctionArity = "%s takes %s, got %d (usage: %s)"
+	errFunctionArg   = "%s: %s (usage: %s)"
+	errNotBase64     = "value is not valid base64"
+	errNotUTF8       = "decoded value is not valid UTF-8 text"
+)
+
+// dnsLabelMax is the maximum length of a DNS label (RFC 1123).
+const dnsLabelMax = 63
+
+// stepFunc transforms one value in a pipeline.
+type stepFunc func(value string) (string, error)
+
+// function is a named transform usable in a pipeline. bind checks the
+// arguments once, when the pipeline is parsed, and returns the step to run.
+type function struct {
+	usage            string // e.g. "truncate(n)", for error messages
+	minArgs, maxArgs int
+	bind             func(args []string) (stepFunc, error)
+}
+
+// functions holds the transform functions available in pipelines.
+var functions = map[string]function{
+	"trim":            simpleFunction("trim", strings.TrimSpace),
+	"lower":           simpleFunction("lower", strings.ToLower),
+	"upper":           simpleFunction("upper", strings.ToUpper),
+	"slugify":         simpleFunction("slugify", slugify),
+	"encode_url":      simpleFunction("encode_url", url.QueryEscape),
+	"escape_newlines": simpleFunction("escape_newlines", escapeNewlines),
+	"truncate": {"truncate(n)", 1, 1, func(args []string) (stepFunc, error) {
+		n, err := positiveArg("truncate", "truncate(n)", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(value string) (string, error) {
+			return truncateRunes(value, n), nil
+		}, nil
+	}},
+	"ellipsis": {"ellipsis(n[, marker])", 1, 2, func(args []string) (stepFunc, error) {
+		n, err := positiveArg("ellipsis", "ellipsis(n[, marker])", args[0])
+		if err != nil {
+			return nil, err
+		}
+		marker := "..."
+		if len(args) > 1 {
+			marker = args[1]
+		}
+		return func(value string) (string, error) {
+			return ellipsis(value, n, marker), nil
+		}, nil
+	}},
+	"trim_prefix": stringArgFunction("trim_prefix(prefix)", strings.TrimPrefix),
+	"trim_suffix": stringArgFunction("trim_suffix(suffix)", strings.TrimSuffix),
+	"replace": {"replace(pattern, replacement)", 2, 2, func(args []string) (stepFunc, error) {
+		re, err := regexp.Compile(args[0])
+		if err != nil {
+			return nil, fmt.Errorf(errFunctionArg, "replace", err, "replace(pattern, replacement)")
+		}
+		return func(value string) (string, error) {
+			return re.ReplaceAllString(value, args[1]), nil
+		}, nil
+	}},
+	"dns_label": {"dns_label([n])", 0, 1, func(args []string) (stepFunc, error) {
+		n := dnsLabelMax
+		if len(args) > 0 {
+			var err error
+			if n, err = positiveArg("dns_label", "dns_label([n])", args[0]); err != nil {
+				return nil, err
+			}
+			if n > dnsLabelMax {
+				return nil, fmt.Errorf(errFunctionArg, "dns_label", fmt.Sprintf("n must be at most %d", dnsLabelMax), "dns_label([n])")
+			}
+		}
+		return func(value string) (string, error) {
+			return dnsLabel(value, n), nil
+		}, nil
+	}},
+	"snake_case": simpleFunction("snake_case", func(value string) string {
+		return strings.Join(words(value), "_")
+	}),
+	"kebab_case": simpleFunction("kebab_case", func(value string) string {
+		return strings.Join(words(value), "-")
+	}),
+	"camel_case": simpleFunction("camel_case", camelCase),
+	"base64_encode": simpleFunction("base64_encode", func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}),
+	"base64_decode": {"base64_decode", 0, 0, func([]string) (stepFunc, error) {
+		return base64Decode, nil
+	}},
+	"sha256":      hashFunction("sha256", sha256.New),
+	"sha1":        hashFunction("sha1", sha1.New),
+	"md5":         hashFunction("md5", md5.New),
+	"shell_quote": simpleFunction("shell_quote", shellQuote),
+	"json_quote":  simpleFunction("json_quote", jsonQuote),
+}
+
+// FunctionNames returns the name of every transform function, sorted.
+func FunctionNames() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// functionNames lists every transform function in sorted order, for error messages.
+func functionNames() string {
+	return strings.Join(FunctionNames(), ", ")
+}
+
+// Apply runs the transform function name with args on value, like the
+// pipeline step name(args...) would.
+func Apply(name string, args []string, value string) (string, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return "", fmt.Errorf(errUnknownFunction, name, functionNames())
+	}
+	if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+		return "", fmt.Errorf(errFunctionArity, name, fn.arity(), len(args), fn.usage)
+	}
+	run, err := fn.bind(args)
+	if err != nil {
+		return "", err
+	}
+	return run(value)
+}
+
+// simpleFunction wraps a string-to-string function that takes no arguments.
+func simpleFunction(name string, f func(string) string) function {
+	return function{name, 0, 0, func([]string) (stepFunc, error) {
+		return func(value string) (string, error) {
+			return f(value), nil
+		}, nil
+	}}
+}
+
+// stringArgFunction wraps a function of the value and one string argument.
+func stringArgFunction(usage string, f func(value, arg string) string) function {
+	return function{usage, 1, 1, func(args []string) (stepFunc, error) {
+		return func(value string) (string, error) {
+			return f(value, args[0]), nil
+		}, nil
+	}}
+}
+
+// hashFunction returns the hex digest of the value under newHash.
+func hashFunction(name string, newHash func() hash.Hash) function {
+	return simpleFunction(name, func(value string) string {
+		h := newHash()
+		h.Write([]byte(value))
+		return hex.EncodeToString(h.Sum(nil))
+	})
+}
+
+// positiveArg parses arg as a whole number greater than zero.
+func positiveArg(name, usage, arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf(errFunctionArg, name, "n must be a positive whole number", usage)
+	}
+	return n, nil
+}
+
+// arity describes how many arguments fn takes, for error messages.
+func (fn function) arity() string {
+	switch {
+	case fn.maxArgs == 0:
+		return "no arguments"
+	case fn.minArgs == fn.maxArgs && fn.minArgs == 1:
+		return "1 argument"
+	case fn.minArgs == fn.maxArgs:
+		return fmt.Sprintf("%d arguments", fn.minArgs)
+	default:
+		return fmt.Sprintf("%d to %d arguments", fn.minArgs, fn.maxArgs)
+	}
+}
+
+// slugify lowercases value and turns every run of characters other than
+// ASCII letters and digits into a single '-', trimmed from both ends.
+func slugify(value string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range strings.ToLower(value) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingDash = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingDash = true
+	}
+	return b.String()
+}
+
+// dnsLabel turns value into a DNS label (RFC 1123) of at most n characters,
+// as used for Kubernetes names and preview environments: slugify, then cut to
+// n characters without leaving a '-' at the end.
+func dnsLabel(value string, n int) string {
+	return strings.TrimRight(truncateRunes(slugify(value), n), "-")
+}
+
+// words splits value into lowercase ASCII words at separators and camelCase
+// boundaries, the way snake-case key names are formed.
+func words(value string) []string {
+	return strings.FieldsFunc(keyname.Convert(value, keyname.StyleLowerSnake), func(r rune) bool {
+		return r == '_'
+	})
+}
+
+// camelCase joins the words of value as "firstSecondThird".
+func camelCase(value string) string {
+	var b strings.Builder
+	for i, word := range words(value) {
+		if i > 0 {
+			r, size := utf8.DecodeRuneInString(word)
+			b.WriteRune(unicode.ToUpper(r))
+			word = word[size:]
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// base64Decode decodes standard base64, with or without padding. The result
+// must be text, since values end up in command files and logs.
+func base64Decode(value string) (string, error) {
+	encoded := strings.TrimSpace(value)
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if decoded, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			return "", errors.New(errNotBase64)
+		}
+	}
+	if !utf8.Valid(decoded) {
+		return "", errors.New(errNotUTF8)
+	}
+	return string(decoded), nil
+}
+
+// shellQuote quotes value for a POSIX shell: it is wrapped in single quotes and
+// every single quote inside becomes '\”.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// jsonQuote returns value as a JSON string literal, without escaping <, > and &.
+func jsonQuote(value string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(value) // Encoding a string cannot fail
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// ellipsis cuts value to at most n characters, ending it with marker when it
+// is cut. If marker does not fit in n characters the value is just truncated.
+func ellipsis(value string, n int, marker string) string {
+	if utf8.RuneCountInString(value) <= n {
+		return value
+	}
+	keep := n - utf8.RuneCountInString(marker)
+	if keep <= 0 {
+		return truncateRunes(value, n)
+	}
+	return truncateRunes(value, keep) + marker
+}
+
+// escapeNewlines replaces newline characters with their escaped equivalents.
+func escapeNewlines(value string) string {
+	result := strings.ReplaceAll(value, "\n", "\\n")
+	return strings.ReplaceAll(result, "\r", "\\r")
+}
+
+// truncateRunes cuts value to at most n characters.
+func truncateRunes(value string, n int) string {
+	runes := []rune(value)
+	if len(runes) <= n {
+		return value
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,158 @@
+package transformer
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		step     string
+		value    string
+		expected string
+	}{
+		{"base64_encode", "user:pass", "dXNlcjpwYXNz"},
+		{"base64_decode", "dXNlcjpwYXNz", "user:pass"},
+		{"base64_decode", " aGk ", "hi"},
+		{"sha256", "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sha1", "abc", "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{"md5", "abc", "900150983cd24fb0d6963f7d28e17f72"},
+		{"snake_case", "HTTPServer name-v2", "http_server_name_v2"},
+		{"kebab_case", "myServiceName", "my-service-name"},
+		{"camel_case", "my_service-name", "myServiceName"},
+		{"camel_case", "HTTP server", "httpServer"},
+		{"dns_label", "Feature/JIRA-123: New Login!", "feature-jira-123-new-login"},
+		{"dns_label(12)", "Feature/JIRA-123", "feature-jira"},
+		{"dns_label(8)", "feature/x", "feature"},
+		{"dns_label", strings.Repeat("a", 70), strings.Repeat("a", 63)},
+		{`replace("[^0-9]+", "")`, "v1.2.3", "123"},
+		{`replace('^refs/heads/(.*)$', '$1')`, "refs/heads/main", "main"},
+		{`replace("\d", "#")`, "a1b2", "a#b#"},
+		{"trim_prefix(refs/heads/)", "refs/heads/main", "main"},
+		{"trim_suffix(.git)", "repo.git", "repo"},
+		{`trim_prefix("v")`, "1.0", "1.0"},
+		{"shell_quote", "it's here", `'it'\''s here'`},
+		{"json_quote", `say "<hi>"` + "\n", `"say \"<hi>\"\n"`},
+		{"ellipsis(8)", "a long description", "a lon..."},
+		{"ellipsis(8)", "short", "short"},
+		{`ellipsis(6, "…")`, "héllo world", "héllo…"},
+		{"ellipsis(2)", "abcdef", "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.step+"/"+tt.value, func(t *testing.T) {
+			p, err := ParsePipeline(tt.step)
+			if err != nil {
+				t.Fatalf("ParsePipeline() unexpected error: %v", err)
+			}
+			got, _, err := p.apply("KEY", tt.value)
+			if err != nil {
+				t.Fatalf("apply() unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("%s(%q) = %q, want %q", tt.step, tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFunctionErrors(t *testing.T) {
+	parseErrors := []struct {
+		step    string
+		errPart string
+	}{
+		{`replace("(", "x")`, "replace: error parsing regexp"},
+		{"replace(a)", "replace takes 2 arguments, got 1"},
+		{"dns_label(64)", "n must be at most 63"},
+		{"ellipsis", "ellipsis takes 1 to 2 arguments, got 0"},
+		{"ellipsis(-1)", "n must be a positive whole number"},
+		{"sha256(x)", "sha256 takes no arguments"},
+	}
+	for _, tt := range parseErrors {
+		t.Run(tt.step, func(t *testing.T) {
+			_, err := ParsePipeline(tt.step)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("ParsePipeline() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+
+	runErrors := []struct {
+		value   string
+		errPart string
+	}{
+		{"not base64!", "transform base64_decode failed for TOKEN: value is not valid base64"},
+		{"//79", "decoded value is not valid UTF-8 text"},
+	}
+	for _, tt := range runErrors {
+		t.Run(tt.value, func(t *testing.T) {
+			p, err := ParsePipeline("base64_decode")
+			if err != nil {
+				t.Fatalf("ParsePipeline() unexpected error: %v", err)
+			}
+			_, _, err = p.apply("TOKEN", tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("apply() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"Feature/ABC-123", "feature-abc-123"},
+		{"  spaces  and__underscores ", "spaces-and-underscores"},
+		{"Grüße", "gr-e"},
+		{"---", ""},
+		{"already-a-slug", "already-a-slug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := slugify(tt.value); got != tt.expected {
+				t.Errorf("slugify(%q) = %q, want %q", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	if len(FunctionNames()) != len(functions) {
+		t.Errorf("FunctionNames() = %v, want every function", FunctionNames())
+	}
+
+	names := functionNames()
+	for _, name := range []string{"lower", "slugify", "truncate"} {
+		if !strings.Contains(names, name) {
+			t.Errorf("functionNames() = %q, missing %q", names, name)
+		}
+	}
+	if !sort.StringsAreSorted(strings.Split(names, ", ")) {
+		t.Errorf("functionNames() = %q, want sorted", names)
+	}
+}
+
+func TestApply(t *testing.T) {
+	got, err := Apply("truncate", []string{"3"}, "abcdef")
+	if err != nil || got != "abc" {
+		t.Errorf("Apply(truncate) = %q, %v, want \"abc\"", got, err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		args    []string
+		errPart string
+	}{
+		{"shout", nil, `unknown transform function "shout"`},
+		{"truncate", nil, "truncate takes 1 argument, got 0"},
+		{"truncate", []string{"x"}, "n must be a positive whole number"},
+	} {
+		if _, err := Apply(tt.name, tt.args, "value"); err == nil || !strings.Contains(err.Error(), tt.errPart) {
+			t.Errorf("Apply(%s, %q) error = %v, want containing %q", tt.name, tt.args, err, tt.errPart)
+		}
+	}
+}
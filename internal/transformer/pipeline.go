@@ -0,0 +1,277 @@
+package transformer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Error messages for transforms and pipelines
+const (
+	errTransformsLine  = "transforms line %d: expected KEY: pipeline"
+	errTransformsKey   = "transforms line %d: invalid key pattern %q: %v"
+	errTransformsEntry = "transforms line %d: %v"
+	errPipelineSyntax  = "invalid pipeline %q: %s at offset %d"
+	errUnknownFunction = "unknown transform function %q (available: %s)"
+	errStepFailed      = "transform %s failed for %s"
+)
+
+// pipelineStep is one parsed step of a Pipeline.
+type pipelineStep struct {
+	name string // Step as written, e.g. "truncate(63)"
+	run  stepFunc
+}
+
+// Pipeline is an ordered list of transform functions, written as
+// "trim | lower | slugify | truncate(63)". Each step gets the result of the
+// previous one.
+type Pipeline struct {
+	src   string
+	steps []pipelineStep
+}
+
+// ParsePipeline parses a pipeline. Steps are separated by '|'; a step is a
+// function name, optionally followed by arguments in parentheses. Arguments
+// are separated by commas and may be quoted with " or ' to contain commas,
+// parentheses or spaces; inside quotes a backslash escapes the quote character
+// and another backslash only. Unknown functions and bad arguments are errors.
+func ParsePipeline(src string) (*Pipeline, error) {
+	p := &pipelineParser{src: src}
+	pipeline := &Pipeline{src: strings.TrimSpace(src)}
+
+	for {
+		p.skipSpace()
+		start := p.pos
+		name := p.readName()
+		if name == "" {
+			return nil, p.errorf("expected a function name")
+		}
+		fn, ok := functions[name]
+		if !ok {
+			return nil, fmt.Errorf(errUnknownFunction, name, functionNames())
+		}
+
+		var args []string
+		p.skipSpace()
+		if p.peek() == '(' {
+			var err error
+			if args, err = p.readArgs(); err != nil {
+				return nil, err
+			}
+		}
+		if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+			return nil, fmt.Errorf(errFunctionArity, name, fn.arity(), len(args), fn.usage)
+		}
+		run, err := fn.bind(args)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.steps = append(pipeline.steps, pipelineStep{
+			name: strings.TrimSpace(src[start:p.pos]),
+			run:  run,
+		})
+
+		p.skipSpace()
+		switch p.peek() {
+		case 0:
+			return pipeline, nil
+		case '|':
+			p.pos++
+		default:
+			return nil, p.errorf(fmt.Sprintf("unexpected %q", p.peek()))
+		}
+	}
+}
+
+// String returns the pipeline as written.
+func (p *Pipeline) String() string {
+	return p.src
+}
+
+// apply runs every step on value and returns the result and the steps that
+// changed the value.
+func (p *Pipeline) apply(key, value string) (string, []string, error) {
+	var applied []string
+	for _, step := range p.steps {
+		next, err := step.run(value)
+		if err != nil {
+			return "", nil, &TransformationError{
+				Message: fmt.Sprintf(errStepFailed, step.name, key),
+				Cause:   err,
+			}
+		}
+		if next != value {
+			applied = append(applied, step.name)
+			value = next
+		}
+	}
+	return value, applied, nil
+}
+
+// keyPipeline is a Pipeline configured for a key glob.
+type keyPipeline struct {
+	pattern  string
+	pipeline *Pipeline
+}
+
+// Transforms maps key names and globs (*, ? and [...]) to the Pipeline run on
+// their values. An exact key name wins over globs; otherwise the first glob
+// that matches, in the order written, applies.
+type Transforms struct {
+	exact map[string]*Pipeline
+	globs []keyPipeline
+}
+
+// ParseTransforms parses the transforms input: one "KEY: pipeline" mapping
+// per line, where KEY is a key name or glob. Blank lines and lines starting
+// with '#' are skipped.
+func ParseTransforms(input string) (*Transforms, error) {
+	ts := &Transforms{exact: make(map[string]*Pipeline)}
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, src, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf(errTransformsLine, i+1)
+		}
+		pipeline, err := ParsePipeline(src)
+		if err != nil {
+			return nil, fmt.Errorf(errTransformsEntry, i+1, err)
+		}
+
+		if !strings.ContainsAny(key, "*?[") {
+			ts.exact[key] = pipeline
+			continue
+		}
+		if _, err := path.Match(key, ""); err != nil {
+			return nil, fmt.Errorf(errTransformsKey, i+1, key, err)
+		}
+		ts.globs = append(ts.globs, keyPipeline{pattern: key, pipeline: pipeline})
+	}
+	return ts, nil
+}
+
+// Match returns the Pipeline configured for key, or nil if there is none.
+// A nil Transforms matches nothing.
+func (ts *Transforms) Match(key string) *Pipeline {
+	if ts == nil {
+		return nil
+	}
+	if pipeline, ok := ts.exact[key]; ok {
+		return pipeline
+	}
+	for _, g := range ts.globs {
+		if matched, _ := path.Match(g.pattern, key); matched {
+			return g.pipeline
+		}
+	}
+	return nil
+}
+
+// pipelineParser reads a pipeline from left to right.
+type pipelineParser struct {
+	src string
+	pos int
+}
+
+// errorf reports a syntax error at the current position.
+func (p *pipelineParser) errorf(msg string) error {
+	return fmt.Errorf(errPipelineSyntax, strings.TrimSpace(p.src), msg, p.pos)
+}
+
+// peek returns the next byte, or 0 at the end.
+func (p *pipelineParser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *pipelineParser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// readName reads a function name made of letters, digits and underscores.
+func (p *pipelineParser) readName() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// readArgs reads a parenthesized argument list, starting at '('.
+func (p *pipelineParser) readArgs() ([]string, error) {
+	p.pos++ // '('
+	p.skipSpace()
+	if p.peek() == ')' {
+		p.pos++
+		return nil, nil
+	}
+
+	var args []string
+	for {
+		p.skipSpace()
+		arg, err := p.readArg()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return args, nil
+		case 0:
+			return nil, p.errorf("missing ')'")
+		default:
+			return nil, p.errorf(fmt.Sprintf("unexpected %q", p.peek()))
+		}
+	}
+}
+
+// readArg reads one argument: a quoted string, or everything up to the next
+// ',' or ')' with surrounding spaces removed.
+func (p *pipelineParser) readArg() (string, error) {
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		start := p.pos
+		for p.pos < len(p.src) && p.src[p.pos] != ',' && p.src[p.pos] != ')' {
+			p.pos++
+		}
+		return strings.TrimSpace(p.src[start:p.pos]), nil
+	}
+
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.src) && (p.src[p.pos+1] == quote || p.src[p.pos+1] == '\\'):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
@@ -0,0 +1,126 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePipeline(t *testing.T) {
+	tests := []struct {
+		src      string
+		value    string
+		expected string
+	}{
+		{"trim", "  a  ", "a"},
+		{"trim | lower | slugify | truncate(63)", "  Feature/ABC_123  ", "feature-abc-123"},
+		{" upper|trim ", " a ", "A"},
+		{"truncate( 3 )", "héllo", "hél"},
+		{"truncate(\"2\")", "abc", "ab"},
+		{"slugify()", "--A  b--", "a-b"},
+		{"escape_newlines", "a\nb", `a\nb`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			p, err := ParsePipeline(tt.src)
+			if err != nil {
+				t.Fatalf("ParsePipeline() unexpected error: %v", err)
+			}
+			got, _, err := p.apply("KEY", tt.value)
+			if err != nil {
+				t.Fatalf("apply() unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("apply() = %q, want %q", got, tt.expected)
+			}
+			if p.String() != strings.TrimSpace(tt.src) {
+				t.Errorf("String() = %q, want %q", p.String(), strings.TrimSpace(tt.src))
+			}
+		})
+	}
+}
+
+func TestParsePipelineErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		errPart string
+	}{
+		{"", "expected a function name at offset 0"},
+		{"trim |", "expected a function name at offset 6"},
+		{"trim lower", `unexpected 'l' at offset 5`},
+		{"nope", `unknown transform function "nope" (available: `},
+		{"truncate", "truncate takes 1 argument, got 0 (usage: truncate(n))"},
+		{"trim(1)", "trim takes no arguments, got 1"},
+		{"truncate(0)", "n must be a positive whole number"},
+		{"truncate(x)", "n must be a positive whole number"},
+		{"truncate(3", "missing ')'"},
+		{`truncate("3)`, "unterminated string at offset 9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := ParsePipeline(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("ParsePipeline() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestParseTransforms(t *testing.T) {
+	ts, err := ParseTransforms(`
+# Kubernetes names
+IMAGE_NAME: trim | lower | slugify
+APP_*: upper
+APP_URL: encode_url
+*: trim
+`)
+	if err != nil {
+		t.Fatalf("ParseTransforms() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"IMAGE_NAME", "trim | lower | slugify"},
+		{"APP_URL", "encode_url"},
+		{"APP_NAME", "upper"},
+		{"OTHER", "trim"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			p := ts.Match(tt.key)
+			if p == nil || p.String() != tt.expected {
+				t.Errorf("Match(%q) = %v, want %q", tt.key, p, tt.expected)
+			}
+		})
+	}
+
+	var none *Transforms
+	if none.Match("KEY") != nil {
+		t.Error("nil Transforms should match nothing")
+	}
+}
+
+func TestParseTransformsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		errPart string
+	}{
+		{"Missing colon", "IMAGE_NAME trim", "transforms line 1: expected KEY: pipeline"},
+		{"Missing key", ": trim", "transforms line 1: expected KEY: pipeline"},
+		{"Bad glob", "\nAPP_[: trim", `transforms line 2: invalid key pattern "APP_["`},
+		{"Unknown function", "KEY: trim | shout", `transforms line 1: unknown transform function "shout"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTransforms(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("ParseTransforms() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
@@ -39,6 +39,7 @@ type Transformer struct {
 	// Masking settings
 	maskSecrets bool
 	maskPattern *regexp.Regexp
+	maskAll     bool
 
 	// Case conversion settings
 	toUpper bool
@@ -50,6 +51,10 @@ type Transformer struct {
 
 	// Length limitation settings
 	maxLength int
+
+	// Pipelines: one for every value, then one per key
+	pipeline   *Pipeline
+	transforms *Transforms
 }
 
 // Options holds the configuration for constructing a Transformer. Using a
@@ -59,11 +64,14 @@ type Transformer struct {
 type Options struct {
 	MaskSecrets    bool
 	MaskPattern    string
+	MaskAll        bool
 	ToUpper        bool
 	ToLower        bool
 	EncodeURL      bool
 	EscapeNewlines bool
 	MaxLength      int
+	Pipeline       string // Pipeline for every value, see ParsePipeline
+	Transforms     string // Per-key pipelines, see ParseTransforms
 }
 
 // New creates a new Transformer with the specified configuration options.
@@ -80,14 +88,35 @@ func New(opts Options) *Transformer {
 		}
 	}
 
+	var pipeline *Pipeline
+	if strings.TrimSpace(opts.Pipeline) != "" {
+		var err error
+		pipeline, err = ParsePipeline(opts.Pipeline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid global transforms: %v\n", err)
+		}
+	}
+
+	var transforms *Transforms
+	if strings.TrimSpace(opts.Transforms) != "" {
+		var err error
+		transforms, err = ParseTransforms(opts.Transforms)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid transforms: %v\n", err)
+		}
+	}
+
 	return &Transformer{
 		maskSecrets:    opts.MaskSecrets,
 		maskPattern:    pattern,
+		maskAll:        opts.MaskAll,
 		toUpper:        opts.ToUpper,
 		toLower:        opts.ToLower,
 		encodeURL:      opts.EncodeURL,
 		escapeNewlines: opts.EscapeNewlines,
 		maxLength:      opts.MaxLength,
+		pipeline:       pipeline,
+		transforms:     transforms,
 	}
 }
 
@@ -112,33 +141,115 @@ func (t *Transformer) TransformValue(value string, supportJSON bool) string {
 	return t.applyTransformations(value)
 }
 
+// TransformKeyValue applies TransformValue, then the pipeline for every value
+// and then the transforms pipeline configured for key, if any. The pipeline
+// for every value is skipped where TransformValue leaves the value alone (an
+// empty value or valid JSON with supportJSON); the key's pipeline always runs,
+// since it is configured for the key explicitly. It fails only if a pipeline
+// step does.
+func (t *Transformer) TransformKeyValue(key, value string, supportJSON bool) (string, error) {
+	result, _, err := t.transformKeyValue(key, value, supportJSON)
+	return result, err
+}
+
+// transformKeyValue performs TransformKeyValue and also returns the names of
+// the transformations that changed the value.
+func (t *Transformer) transformKeyValue(key, value string, supportJSON bool) (string, []string, error) {
+	result := t.TransformValue(value, supportJSON)
+	applied := t.AppliedTransformations(value, supportJSON)
+
+	var pipelines []*Pipeline
+	if t.pipeline != nil && value != "" && !(supportJSON && isValidJSON(value)) {
+		pipelines = append(pipelines, t.pipeline)
+	}
+	if pipeline := t.transforms.Match(key); pipeline != nil {
+		pipelines = append(pipelines, pipeline)
+	}
+
+	for _, pipeline := range pipelines {
+		next, steps, err := pipeline.apply(key, result)
+		if err != nil {
+			return "", nil, err
+		}
+		result = next
+		applied = append(applied, steps...)
+	}
+	return result, applied, nil
+}
+
 // applyTransformations applies all non-JSON transformations in sequence:
 // case conversion, URL encoding, newline escaping, and length limitation.
 func (t *Transformer) applyTransformations(value string) string {
+	result, _ := t.applyTransformationSteps(value)
+	return result
+}
+
+// applyTransformationSteps performs the transformations of applyTransformations
+// and also returns the names of the ones that changed the value.
+func (t *Transformer) applyTransformationSteps(value string) (string, []string) {
 	result := value
+	var applied []string
+	step := func(name, next string) {
+		if next != result {
+			applied = append(applied, name)
+			result = next
+		}
+	}
 
 	// 1. Apply case conversion (mutually exclusive)
-	result = t.applyCaseConversion(result)
+	if t.toUpper {
+		step("to_upper", t.applyCaseConversion(result))
+	} else if t.toLower {
+		step("to_lower", t.applyCaseConversion(result))
+	}
 
 	// 2. Apply URL encoding if enabled
 	if t.encodeURL {
-		result = url.QueryEscape(result)
+		step("encode_url", url.QueryEscape(result))
 	}
 
 	// 3. Escape newlines if enabled
 	if t.escapeNewlines {
-		result = t.escapeNewlineCharacters(result)
+		step("escape_newlines", t.escapeNewlineCharacters(result))
 	}
 
 	// 4. Apply length limitation if configured (rune-aware to preserve UTF-8 boundaries)
 	if t.maxLength > 0 {
 		runes := []rune(result)
 		if len(runes) > t.maxLength {
-			result = string(runes[:t.maxLength])
+			step(fmt.Sprintf("max_length(%d)", t.maxLength), string(runes[:t.maxLength]))
 		}
 	}
 
-	return result
+	return result, applied
+}
+
+// AppliedTransformations returns the names of the transformations that
+// TransformValue would apply to value and that actually change it, e.g.
+// ["to_upper", "max_length(10)"]. Valid JSON values left untouched by
+// TransformValue yield nil.
+func (t *Transformer) AppliedTransformations(value string, supportJSON bool) []string {
+	if value == "" {
+		return nil
+	}
+	if supportJSON && isValidJSON(value) {
+		return nil
+	}
+	_, applied := t.applyTransformationSteps(value)
+	return applied
+}
+
+// AppliedKeyTransformations is AppliedTransformations for TransformKeyValue:
+// it also returns the pipeline steps that change the value, as written, e.g.
+// ["to_upper", "slugify", "truncate(63)"]. It returns nil if a step fails.
+func (t *Transformer) AppliedKeyTransformations(key, value string, supportJSON bool) []string {
+	_, applied, _ := t.transformKeyValue(key, value, supportJSON)
+	return applied
+}
+
+// isValidJSON reports whether value looks like JSON and parses as JSON.
+func isValidJSON(value string) bool {
+	return jsonutil.IsJSONLike(value) && json.Valid([]byte(value))
 }
 
 // handleJSONValue processes a value that appears to be JSON.
@@ -168,9 +279,20 @@ func (t *Transformer) applyCaseConversion(value string) string {
 
 // escapeNewlineCharacters replaces newline characters with their escaped equivalents.
 func (t *Transformer) escapeNewlineCharacters(value string) string {
-	result := strings.ReplaceAll(value, "\n", "\\n")
-	result = strings.ReplaceAll(result, "\r", "\\r")
-	return result
+	return escapeNewlines(value)
+}
+
+// ShouldMask reports whether value must be registered with the runner's log
+// masker: every non-empty value when mask_all is on, otherwise only values
+// matching mask_pattern while mask_secrets is enabled.
+func (t *Transformer) ShouldMask(value string) bool {
+	if value == "" {
+		return false
+	}
+	if t.maskAll {
+		return true
+	}
+	return t.maskSecrets && t.maskPattern != nil && t.maskPattern.MatchString(value)
 }
 
 // MaskValue applies masking to sensitive values to hide their content.
@@ -178,7 +300,7 @@ func (t *Transformer) escapeNewlineCharacters(value string) string {
 // Operates on runes to preserve UTF-8 boundaries.
 func (t *Transformer) MaskValue(value string) string {
 	// Skip masking if disabled or value is empty
-	if !t.maskSecrets || value == "" {
+	if (!t.maskSecrets && !t.maskAll) || value == "" {
 		return value
 	}
 
@@ -2,6 +2,7 @@ package transformer
 
 import (
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -726,3 +727,177 @@ func BenchmarkTransformJSON(b *testing.B) {
 		tr.TransformJSON(value)
 	}
 }
+
+func TestShouldMask(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		value    string
+		expected bool
+	}{
+		{name: "Masking disabled", opts: Options{}, value: "secret", expected: false},
+		{name: "Mask all", opts: Options{MaskAll: true}, value: "anything", expected: true},
+		{name: "Mask all skips empty", opts: Options{MaskAll: true}, value: "", expected: false},
+		{name: "Pattern match", opts: Options{MaskSecrets: true, MaskPattern: "^sk_"}, value: "sk_live", expected: true},
+		{name: "Pattern miss", opts: Options{MaskSecrets: true, MaskPattern: "^sk_"}, value: "pk_live", expected: false},
+		{name: "Pattern without mask_secrets", opts: Options{MaskPattern: "^sk_"}, value: "sk_live", expected: false},
+		{name: "mask_secrets without pattern", opts: Options{MaskSecrets: true}, value: "sk_live", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.opts).ShouldMask(tt.value); got != tt.expected {
+				t.Errorf("ShouldMask() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAppliedTransformations(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		value       string
+		supportJSON bool
+		expected    []string
+	}{
+		{name: "Nothing enabled", opts: Options{}, value: "value", expected: nil},
+		{name: "Empty value", opts: Options{ToUpper: true}, value: "", expected: nil},
+		{name: "Upper changes value", opts: Options{ToUpper: true}, value: "value", expected: []string{"to_upper"}},
+		{name: "Upper without effect", opts: Options{ToUpper: true}, value: "VALUE", expected: nil},
+		{name: "Lower", opts: Options{ToLower: true}, value: "Value", expected: []string{"to_lower"}},
+		{name: "Newlines only when present", opts: Options{EscapeNewlines: true}, value: "a\nb", expected: []string{"escape_newlines"}},
+		{name: "No newline to escape", opts: Options{EscapeNewlines: true}, value: "ab", expected: nil},
+		{
+			name:     "Several in order",
+			opts:     Options{ToUpper: true, EncodeURL: true, MaxLength: 4},
+			value:    "a b c",
+			expected: []string{"to_upper", "encode_url", "max_length(4)"},
+		},
+		{name: "Valid JSON untouched", opts: Options{ToUpper: true}, value: `{"a":"b"}`, supportJSON: true, expected: nil},
+		{name: "Invalid JSON transformed", opts: Options{ToUpper: true}, value: `{"a":`, supportJSON: true, expected: []string{"to_upper"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.opts).AppliedTransformations(tt.value, tt.supportJSON)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("AppliedTransformations() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTransformKeyValue(t *testing.T) {
+	transforms := "IMAGE_NAME: trim | lower | slugify | truncate(10)\n*_URL: encode_url\nCONFIG: trim"
+
+	tests := []struct {
+		name        string
+		opts        Options
+		key         string
+		value       string
+		supportJSON bool
+		expected    string
+		applied     []string
+	}{
+		{
+			name:     "Pipeline for exact key",
+			opts:     Options{Transforms: transforms},
+			key:      "IMAGE_NAME",
+			value:    "  My Feature/Branch ",
+			expected: "my-feature",
+			applied:  []string{"trim", "lower", "slugify", "truncate(10)"},
+		},
+		{
+			name:     "Pipeline for glob",
+			opts:     Options{Transforms: transforms},
+			key:      "CALLBACK_URL",
+			value:    "a b",
+			expected: "a+b",
+			applied:  []string{"encode_url"},
+		},
+		{
+			name:     "No pipeline for key",
+			opts:     Options{Transforms: transforms},
+			key:      "OTHER",
+			value:    " Value ",
+			expected: " Value ",
+		},
+		{
+			name:     "Runs after global options",
+			opts:     Options{ToUpper: true, Transforms: transforms},
+			key:      "IMAGE_NAME",
+			value:    "web app",
+			expected: "web-app",
+			applied:  []string{"to_upper", "lower", "slugify"},
+		},
+		{
+			name:        "Runs on JSON values",
+			opts:        Options{ToUpper: true, Transforms: transforms},
+			key:         "CONFIG",
+			value:       ` {"a":"b"} `,
+			supportJSON: true,
+			expected:    `{"a":"b"}`,
+			applied:     []string{"trim"},
+		},
+		{
+			name:     "Pipeline for every value before the key's",
+			opts:     Options{Pipeline: "trim | base64_encode", Transforms: "TOKEN: ellipsis(6)"},
+			key:      "TOKEN",
+			value:    " secret ",
+			expected: "c2V...",
+			applied:  []string{"trim", "base64_encode", "ellipsis(6)"},
+		},
+		{
+			name:        "Pipeline for every value skips JSON",
+			opts:        Options{Pipeline: "sha256"},
+			key:         "CONFIG",
+			value:       `{"a":"b"}`,
+			supportJSON: true,
+			expected:    `{"a":"b"}`,
+		},
+		{
+			name:     "Pipeline for every value skips empty values",
+			opts:     Options{Pipeline: "sha256"},
+			key:      "EMPTY",
+			value:    "",
+			expected: "",
+		},
+		{
+			name:     "Invalid transforms are ignored",
+			opts:     Options{Pipeline: "nope", Transforms: "IMAGE_NAME: nope"},
+			key:      "IMAGE_NAME",
+			value:    "Value",
+			expected: "Value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := New(tt.opts)
+			got, err := tr.TransformKeyValue(tt.key, tt.value, tt.supportJSON)
+			if err != nil {
+				t.Fatalf("TransformKeyValue() unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("TransformKeyValue() = %q, want %q", got, tt.expected)
+			}
+			if applied := tr.AppliedKeyTransformations(tt.key, tt.value, tt.supportJSON); !reflect.DeepEqual(applied, tt.applied) {
+				t.Errorf("AppliedKeyTransformations() = %v, want %v", applied, tt.applied)
+			}
+		})
+	}
+}
+
+func TestTransformKeyValueError(t *testing.T) {
+	tr := New(Options{Transforms: "TOKEN: base64_decode"})
+	_, err := tr.TransformKeyValue("TOKEN", "not base64!", false)
+
+	var transformErr *TransformationError
+	if !errors.As(err, &transformErr) || !strings.Contains(err.Error(), "transform base64_decode failed for TOKEN") {
+		t.Fatalf("TransformKeyValue() error = %v, want a *TransformationError naming the step and key", err)
+	}
+	if applied := tr.AppliedKeyTransformations("TOKEN", "not base64!", false); applied != nil {
+		t.Errorf("AppliedKeyTransformations() = %v, want nil", applied)
+	}
+}
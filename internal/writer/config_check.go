@@ -0,0 +1,77 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/export"
+	"github.com/somaz94/env-output-setter/internal/interpolator"
+	"github.com/somaz94/env-output-setter/internal/transformer"
+)
+
+// errGlobalTransforms reports a global_transforms pipeline that does not parse.
+const errGlobalTransforms = "global_transforms: %v"
+
+// CheckConfig reports configuration errors that can be found before any input
+// is processed, such as an unknown validation_mode, size_limit_policy or
+// path_check, an invalid validation rule, an expression that does not parse or
+// a transform pipeline with an unknown function, a malformed
+// interpolation_allow or interpolation_deny pattern, a json_separator that
+// cannot appear in a variable name, a json_select source key no input sets, a
+// json_schema that cannot be read or compiled or an unknown export_format, so
+// the step fails before anything is written. It returns the Step that sets
+// the destinations, reusing what the checks prepared.
+func CheckConfig(cfg *config.Config) (*Step, error) {
+	if _, err := newErrorCollector(cfg.ValidationMode); err != nil {
+		return nil, err
+	}
+	if _, err := ParseRuleSet(cfg.ValidationRules); err != nil {
+		return nil, err
+	}
+	if _, err := ParseValidationExpressions(cfg.ValidationExpr); err != nil {
+		return nil, err
+	}
+	if _, err := sizeLimitPolicy(cfg.SizeLimitPolicy); err != nil {
+		return nil, err
+	}
+	if _, err := pathCheckMode(cfg.PathCheck); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(cfg.GlobalTransforms) != "" {
+		if _, err := transformer.ParsePipeline(cfg.GlobalTransforms); err != nil {
+			return nil, fmt.Errorf(errGlobalTransforms, err)
+		}
+	}
+	if _, err := transformer.ParseTransforms(cfg.Transforms); err != nil {
+		return nil, err
+	}
+	if _, err := interpolator.ParseNamePatterns(cfg.InterpolationAllow); err != nil {
+		return nil, fmt.Errorf(errInterpolationAllow, err)
+	}
+	if _, err := interpolator.ParseNamePatterns(cfg.InterpolationDeny); err != nil {
+		return nil, fmt.Errorf(errInterpolationDeny, err)
+	}
+	if !IsValidSeparator(cfg.JsonSeparator) {
+		return nil, fmt.Errorf(errInvalidSeparator, cfg.JsonSeparator)
+	}
+	if err := checkSelectionSources(cfg); err != nil {
+		return nil, err
+	}
+	if _, err := export.NormalizeFormat(cfg.ExportFormat); err != nil {
+		return nil, err
+	}
+	if _, err := export.ParseFileMode(cfg.ExportFileMode); err != nil {
+		return nil, err
+	}
+
+	step := NewStep(cfg)
+	if strings.TrimSpace(cfg.JsonSchema) != "" {
+		schemas, err := loadSchemas(cfg.JsonSchema)
+		if err != nil {
+			return nil, err
+		}
+		step.schemas = schemas
+	}
+	return step, nil
+}
@@ -0,0 +1,105 @@
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+func TestCheckConfig(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("FROM_FILE={\"a\":1}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		errPart string
+	}{
+		{name: "Defaults"},
+		{
+			name: "Valid settings",
+			cfg: config.Config{
+				ValidationMode:     ValidationModeCollect,
+				ValidationRules:    `{"*_PORT":{"type":"int"}}`,
+				ValidationExpr:     `defined(A) || defined(B)`,
+				Transforms:         "IMAGE_NAME: trim | slugify",
+				GlobalTransforms:   "trim",
+				InterpolationAllow: "GITHUB_*\nAPP_*",
+			},
+		},
+		{name: "Known path_check", cfg: config.Config{PathCheck: "FAIL"}},
+		{name: "Unknown path_check", cfg: config.Config{PathCheck: "strict"}, errPart: `unsupported path_check "strict" (expected warn, fail or off)`},
+		{name: "Unknown validation_mode", cfg: config.Config{ValidationMode: "all"}, errPart: `unsupported validation_mode "all"`},
+		{name: "Invalid rule", cfg: config.Config{ValidationRules: `{"PORT":{"type":"port"}}`}, errPart: `invalid validation rule for key "PORT"`},
+		{name: "Invalid expression", cfg: config.Config{ValidationExpr: `A ===`}, errPart: "validation_expressions[0]: invalid expression"},
+		{name: "Invalid global_transforms", cfg: config.Config{GlobalTransforms: "truncate(0)"}, errPart: "global_transforms: truncate: n must be a positive whole number"},
+		{name: "Unknown transform", cfg: config.Config{Transforms: "IMAGE_NAME: trim | shout"}, errPart: `transforms line 1: unknown transform function "shout"`},
+		{name: "Invalid interpolation_allow", cfg: config.Config{InterpolationAllow: "GITHUB_*,[A"}, errPart: `interpolation_allow: invalid variable pattern "[A"`},
+		{name: "Invalid interpolation_deny", cfg: config.Config{InterpolationDeny: "SECRET_[", InterpolationAllow: "GITHUB_*"}, errPart: `interpolation_deny: invalid variable pattern "SECRET_["`},
+		{name: "Double underscore json_separator", cfg: config.Config{JsonSeparator: "__"}},
+		{name: "Dot json_separator", cfg: config.Config{JsonSeparator: "."}, errPart: `invalid json_separator "."`},
+		{name: "Space json_separator", cfg: config.Config{JsonSeparator: " "}, errPart: `invalid json_separator " "`},
+		{name: "Equals json_separator", cfg: config.Config{JsonSeparator: "="}, errPart: `invalid json_separator "="`},
+		{name: "json_select source in env and output", cfg: config.Config{Delimiter: ",", EnvKeys: "RESP", OutputPairs: "DOC={}", JsonSelect: "X=RESP.a.b\nY=doc.c"}},
+		{name: "json_select source in env_file", cfg: config.Config{Delimiter: ",", EnvFile: envFile, JsonSelect: "X=FROM_FILE.a"}},
+		{name: "json_select source in state", cfg: config.Config{Delimiter: ",", StateKeys: "SAVED", JsonSelect: "X=SAVED[0]"}},
+		{name: "Unknown json_select source", cfg: config.Config{Delimiter: ",", EnvKeys: "RESP", JsonSelect: "X=RESPP.a.b"}, errPart: "json_select X: source key RESPP is not set by any env, output or state input"},
+		{name: "Case-sensitive json_select source", cfg: config.Config{Delimiter: ",", CaseSensitive: true, OutputKeys: "RESP", JsonSelect: "X=resp.a"}, errPart: "source key resp is not set"},
+		{name: "Valid json_schema", cfg: config.Config{JsonSchema: `{"DEPLOY":` + deploySchema + `}`}},
+		{name: "Malformed json_schema", cfg: config.Config{JsonSchema: `{"DEPLOY":`}, errPart: "json_schema must be a JSON object mapping keys to schemas"},
+		{name: "Invalid json_schema", cfg: config.Config{JsonSchema: `{"DEPLOY":{"type":"int"}}`}, errPart: `json_schema DEPLOY: invalid schema at "#/type": unknown type "int"`},
+		{name: "Unreadable json_schema", cfg: config.Config{JsonSchema: "file://" + filepath.Join(t.TempDir(), "missing.json")}, errPart: "json_schema: failed to read"},
+		{name: "Unknown export_format", cfg: config.Config{ExportFormat: "ini"}, errPart: `unsupported export_format "ini"`},
+		{name: "Invalid export_file_mode", cfg: config.Config{ExportFileMode: "999"}, errPart: `invalid export_file_mode "999"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := CheckConfig(&tt.cfg)
+			if tt.errPart == "" {
+				if err != nil || step == nil {
+					t.Errorf("CheckConfig() = %v, %v, want a step", step, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("CheckConfig() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestCheckConfigCompilesSchemasOnce(t *testing.T) {
+	t.Setenv(githubEnvVar, "")
+	schemaFile := filepath.Join(t.TempDir(), "schemas.json")
+	if err := os.WriteFile(schemaFile, []byte(`{"DEPLOY":`+deploySchema+`}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		EnvKeys:       "DEPLOY",
+		EnvValues:     `{"image":1}`,
+		Delimiter:     "|",
+		CaseSensitive: true,
+		JsonSchema:    "file://" + schemaFile,
+	}
+
+	step, err := CheckConfig(cfg)
+	if err != nil {
+		t.Fatalf("CheckConfig() unexpected error: %v", err)
+	}
+	// The step validates with the schemas CheckConfig compiled, not the file
+	if err := os.Remove(schemaFile); err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t, func() {
+		_, err = step.SetEnv()
+	})
+	if err == nil || !strings.Contains(err.Error(), "json_schema DEPLOY: #/image: expected string, got integer") {
+		t.Errorf("SetEnv() error = %v, want the schema violation", err)
+	}
+}
@@ -0,0 +1,73 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/export"
+	"github.com/somaz94/env-output-setter/internal/printer"
+)
+
+// ExportValues collects the env and output values a Step writes, in the order
+// they are written, for ExportFile.
+type ExportValues struct {
+	keys   []string
+	values map[string]string
+}
+
+// NewExportValues creates an empty ExportValues.
+func NewExportValues() *ExportValues {
+	return &ExportValues{values: make(map[string]string)}
+}
+
+// add records the value written for key. The first value recorded for a key
+// is kept, so an env value wins over an output of the same name.
+func (e *ExportValues) add(key, value string) {
+	if _, ok := e.values[key]; ok {
+		return
+	}
+	e.keys = append(e.keys, key)
+	e.values[key] = value
+}
+
+// ExportFile writes the env and output values recorded in exported to
+// export_file in export_format, for jobs and tools that read configuration
+// files rather than $GITHUB_ENV. The values are the ones actually written:
+// trimmed, transformed, replaced as size_limit_policy replaced them and with
+// keys renamed as key_autofix renames them. The file is replaced atomically
+// with export_file_mode permissions. It returns the number of keys exported,
+// 0 if export_file is not set; exported may be nil when nothing was written.
+func ExportFile(cfg *config.Config, exported *ExportValues) (int, error) {
+	path := strings.TrimSpace(cfg.ExportFile)
+	if path == "" {
+		return 0, nil
+	}
+	format, err := export.NormalizeFormat(cfg.ExportFormat)
+	if err != nil {
+		return 0, err
+	}
+	mode, err := export.ParseFileMode(cfg.ExportFileMode)
+	if err != nil {
+		return 0, err
+	}
+
+	if exported == nil {
+		exported = NewExportValues()
+	}
+	values := make([]string, len(exported.keys))
+	for i, key := range exported.keys {
+		values[i] = exported.values[key]
+	}
+
+	data, err := export.Serialize(format, exported.keys, values)
+	if err != nil {
+		return 0, err
+	}
+	if err := export.WriteFile(path, data, mode); err != nil {
+		return 0, err
+	}
+
+	printer.PrintInfo(fmt.Sprintf("Exported %d values to %s (%s)", len(exported.keys), path, format))
+	return len(exported.keys), nil
+}
@@ -0,0 +1,214 @@
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+// exportedValues sets the env and output values of cfg, without writing them
+// when $GITHUB_ENV and $GITHUB_OUTPUT are unset, and returns what they
+// recorded for export.
+func exportedValues(t *testing.T, cfg *config.Config) *ExportValues {
+	t.Helper()
+	exported := NewExportValues()
+	captureStdout(t, func() {
+		step := NewStep(cfg)
+		step.Record(nil, exported)
+		if _, err := step.SetEnv(); err != nil {
+			t.Fatalf("SetEnv() unexpected error: %v", err)
+		}
+		if _, err := step.SetOutput(); err != nil {
+			t.Fatalf("SetOutput() unexpected error: %v", err)
+		}
+	})
+	return exported
+}
+
+func TestExportFile(t *testing.T) {
+	t.Setenv(githubEnvVar, "")
+	t.Setenv(githubOutputVar, "")
+
+	t.Run("Not set", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnvKeys: "A", EnvValues: "1"}
+		count, err := ExportFile(cfg, exportedValues(t, cfg))
+		if count != 0 || err != nil {
+			t.Errorf("ExportFile() = %d, %v, want 0, nil", count, err)
+		}
+	})
+
+	t.Run("Env and output values", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "build", "config.env")
+		cfg := &config.Config{
+			Delimiter:      ",",
+			TrimWhitespace: true,
+			EnvKeys:        "REGISTRY,SHARED",
+			EnvValues:      " ghcr.io/acme , from env",
+			OutputKeys:     "SHARED,TAG",
+			OutputValues:   "from output,V1.2",
+			ToLower:        true,
+			ExportAsEnv:    true,
+			ExportFile:     path,
+			ExportFormat:   "dotenv",
+			ExportFileMode: "0600",
+		}
+		exported := exportedValues(t, cfg)
+		var count int
+		var err error
+		output := captureStdout(t, func() {
+			count, err = ExportFile(cfg, exported)
+		})
+		if err != nil || count != 3 {
+			t.Fatalf("ExportFile() = %d, %v, want 3, nil", count, err)
+		}
+		if !strings.Contains(output, "Exported 3 values to "+path+" (dotenv)") {
+			t.Errorf("output = %q, want the export message", output)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read export file: %v", err)
+		}
+		want := "REGISTRY=ghcr.io/acme\nSHARED=\"from env\"\nTAG=v1.2\n"
+		if string(data) != want {
+			t.Errorf("export file = %q, want %q", data, want)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+			t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		cfg := &config.Config{Delimiter: ",", OutputPairs: "URL=https://example.com/?a=1&b=2", ExportFile: path, ExportFormat: "json"}
+		exported := exportedValues(t, cfg)
+		captureStdout(t, func() {
+			if _, err := ExportFile(cfg, exported); err != nil {
+				t.Errorf("ExportFile() unexpected error: %v", err)
+			}
+		})
+		data, _ := os.ReadFile(path)
+		if want := "{\n  \"URL\": \"https://example.com/?a=1&b=2\"\n}\n"; string(data) != want {
+			t.Errorf("export file = %q, want %q", data, want)
+		}
+	})
+
+	t.Run("Nothing written", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.env")
+		captureStdout(t, func() {
+			if count, err := ExportFile(&config.Config{ExportFile: path}, nil); count != 0 || err != nil {
+				t.Errorf("ExportFile() = %d, %v, want 0, nil", count, err)
+			}
+		})
+		if data, err := os.ReadFile(path); err != nil || len(data) != 0 {
+			t.Errorf("export file = %q, %v, want an empty file", data, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		dir := t.TempDir()
+		tests := []struct {
+			name    string
+			cfg     config.Config
+			errPart string
+		}{
+			{"Format", config.Config{ExportFormat: "ini"}, `unsupported export_format "ini"`},
+			{"Mode", config.Config{ExportFileMode: "rw"}, `invalid export_file_mode "rw"`},
+			{"Multiline docker-env", config.Config{EnvPairs: "CERT<<EOF\na\nb\nEOF", ExportFormat: "docker-env"}, "docker-env format cannot hold the multiline value of CERT"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				cfg := tt.cfg
+				cfg.Delimiter = ","
+				cfg.ExportFile = filepath.Join(dir, tt.name)
+				if _, err := ExportFile(&cfg, exportedValues(t, &cfg)); err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("ExportFile() error = %v, want containing %q", err, tt.errPart)
+				}
+				if _, err := os.Stat(cfg.ExportFile); !os.IsNotExist(err) {
+					t.Errorf("export file exists after an error")
+				}
+			})
+		}
+	})
+}
+
+func TestExportFileHoldsWrittenValues(t *testing.T) {
+	big := strings.Repeat("x", maxValueSize+1)
+
+	setOutput := func(t *testing.T, cfg *config.Config) (*ExportValues, error) {
+		t.Helper()
+		dir := t.TempDir()
+		t.Setenv(githubOutputVar, filepath.Join(dir, "github_output"))
+		t.Setenv(runnerTempVar, dir)
+		// The success lines print the whole value, more than captureStdout's pipe holds
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer devNull.Close()
+		stdout := os.Stdout
+		os.Stdout = devNull
+		defer func() { os.Stdout = stdout }()
+
+		exported := NewExportValues()
+		step := NewStep(cfg)
+		step.Record(nil, exported)
+		_, err = step.SetOutput()
+		return exported, err
+	}
+
+	t.Run("Truncated value", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", OutputKeys: "BIG", OutputValues: big, SizeLimitPolicy: SizeLimitTruncate}
+		exported, err := setOutput(t, cfg)
+		if err != nil {
+			t.Fatalf("SetOutput() unexpected error: %v", err)
+		}
+		if got := exported.values["BIG"]; len(got) != maxValueSize {
+			t.Errorf("exported BIG = %d bytes, want %d", len(got), maxValueSize)
+		}
+	})
+
+	t.Run("Spilled value", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", OutputKeys: "BIG", OutputValues: big, SizeLimitPolicy: SizeLimitSpill}
+		exported, err := setOutput(t, cfg)
+		if err != nil {
+			t.Fatalf("SetOutput() unexpected error: %v", err)
+		}
+		got := exported.values["BIG"]
+		if !strings.HasPrefix(got, os.Getenv(runnerTempVar)) {
+			t.Errorf("exported BIG = %.100q, want the spill path", got)
+		}
+	})
+
+	t.Run("Failed validation", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", OutputKeys: "HOST,PORT", OutputValues: "localhost,http", ValidationRules: `{"PORT":{"type":"int"}}`}
+		exported, err := setOutput(t, cfg)
+		if err == nil {
+			t.Fatal("SetOutput() expected a validation error")
+		}
+		if len(exported.keys) != 0 {
+			t.Errorf("exported keys = %v, want none", exported.keys)
+		}
+	})
+
+	t.Run("Failed transform without $GITHUB_OUTPUT", func(t *testing.T) {
+		t.Setenv(githubOutputVar, "")
+		cfg := &config.Config{Delimiter: ",", OutputKeys: "TOKEN", OutputValues: "%%%", Transforms: "TOKEN: base64_decode"}
+		exported := NewExportValues()
+		step := NewStep(cfg)
+		step.Record(nil, exported)
+		var err error
+		captureStdout(t, func() {
+			_, err = step.SetOutput()
+		})
+		if err == nil || !strings.Contains(err.Error(), "base64_decode") {
+			t.Errorf("SetOutput() error = %v, want the transform error", err)
+		}
+		if len(exported.keys) != 0 {
+			t.Errorf("exported keys = %v, want none", exported.keys)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/expr"
+)
+
+// Error messages for validation_expressions
+const (
+	errExpressionsInput = "validation_expressions must be a JSON array or one expression per line: %v"
+	errExpressionEntry  = "validation_expressions[%d]: %v"
+	errExpressionFailed = "validation expression failed: %s"
+	errExpressionEval   = "validation expression %q could not be evaluated: %v"
+	errExpressionKey    = "validation expression %q: key %s matches %s; use the exact key name"
+)
+
+// CheckExpression identifies failed validation_expressions in validation errors.
+const CheckExpression = "expression"
+
+// ValidationExpression is a cross-key check from validation_expressions.
+type ValidationExpression struct {
+	Expr    string `json:"expr"`    // Expression that must be true
+	Message string `json:"message"` // Custom error message
+	parsed  *expr.Expr
+}
+
+// ParseValidationExpressions parses validation_expressions. The input is
+// either a JSON array of expressions, each a string or an object with expr
+// and message, or one expression per line; blank lines and lines starting
+// with '#' are skipped.
+func ParseValidationExpressions(input string) ([]ValidationExpression, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, nil
+	}
+
+	var exprs []ValidationExpression
+	if strings.HasPrefix(input, "[") {
+		var entries []json.RawMessage
+		if err := json.Unmarshal([]byte(input), &entries); err != nil {
+			return nil, fmt.Errorf(errExpressionsInput, err)
+		}
+		for i, raw := range entries {
+			var entry ValidationExpression
+			if err := json.Unmarshal(raw, &entry.Expr); err != nil {
+				if err := json.Unmarshal(raw, &entry); err != nil {
+					return nil, fmt.Errorf(errExpressionEntry, i, "must be a string or an object with expr and message")
+				}
+			}
+			exprs = append(exprs, entry)
+		}
+	} else {
+		for _, line := range strings.Split(input, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			exprs = append(exprs, ValidationExpression{Expr: line})
+		}
+	}
+
+	for i := range exprs {
+		parsed, err := expr.Parse(exprs[i].Expr)
+		if err != nil {
+			return nil, fmt.Errorf(errExpressionEntry, i, err)
+		}
+		exprs[i].parsed = parsed
+	}
+	return exprs, nil
+}
+
+// ValidateExpressions evaluates validation_expressions against values, the
+// final value of every key the step sets. A failed expression is reported
+// under the keys it refers to; in collect mode every failure is returned as
+// *ValidationErrors. Unless case_sensitive is on, a key that is not set as
+// written matches a key differing only in case; an expression whose key
+// matches several such keys fails.
+func (v *Validator) ValidateExpressions(values map[string]string) error {
+	exprs, err := ParseValidationExpressions(v.cfg.ValidationExpr)
+	if err != nil {
+		return err
+	}
+	collector, err := newErrorCollector(v.cfg.ValidationMode)
+	if err != nil {
+		return err
+	}
+
+	lookup := func(key string) (string, bool) {
+		if value, ok := values[key]; ok {
+			return value, true
+		}
+		if !v.cfg.CaseSensitive {
+			if names := foldedKeys(values, key); len(names) == 1 {
+				return values[names[0]], true
+			}
+		}
+		return "", false
+	}
+
+	for _, e := range exprs {
+		if !v.cfg.CaseSensitive {
+			if key, names := ambiguousKey(e.parsed.Keys(), values); key != "" {
+				msg := fmt.Sprintf(errExpressionKey, e.Expr, key, strings.Join(names, ", "))
+				if err := collector.add(key, CheckExpression, msg); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		ok, err := e.parsed.Eval(lookup)
+		var msg string
+		switch {
+		case err != nil:
+			msg = fmt.Sprintf(errExpressionEval, e.Expr, err)
+		case !ok && e.Message != "":
+			msg = e.Message
+		case !ok:
+			msg = fmt.Sprintf(errExpressionFailed, e.parsed)
+		default:
+			continue
+		}
+		if err := collector.add(strings.Join(e.parsed.Keys(), ","), CheckExpression, msg); err != nil {
+			return err
+		}
+	}
+	return collector.result()
+}
+
+// ambiguousKey returns the first of keys that is not set in values as written
+// but matches several keys of values differing only in case, and those keys.
+func ambiguousKey(keys []string, values map[string]string) (string, []string) {
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if names := foldedKeys(values, key); len(names) > 1 {
+			return key, names
+		}
+	}
+	return "", nil
+}
+
+// foldedKeys returns the keys of values equal to key under case folding, sorted.
+func foldedKeys(values map[string]string, key string) []string {
+	var names []string
+	for name := range values {
+		if strings.EqualFold(name, key) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckExpressions evaluates validation_expressions before anything is
+// written (see Step.CheckExpressions).
+func CheckExpressions(cfg *config.Config) error {
+	return NewStep(cfg).CheckExpressions()
+}
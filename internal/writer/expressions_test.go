@@ -0,0 +1,237 @@
+package writer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+func TestParseValidationExpressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		exprs    []string
+		messages []string
+		errPart  string
+	}{
+		{name: "Empty", input: "  "},
+		{
+			name:  "One per line with comments",
+			input: "# prod needs replicas\nDEPLOY_ENV != \"prod\" || REPLICAS >= 3\n\n  defined(TAG) != defined(DIGEST)  ",
+			exprs: []string{`DEPLOY_ENV != "prod" || REPLICAS >= 3`, "defined(TAG) != defined(DIGEST)"},
+		},
+		{
+			name:     "JSON strings and objects",
+			input:    `["A == 1", {"expr": "B == 2", "message": "B must be 2"}]`,
+			exprs:    []string{"A == 1", "B == 2"},
+			messages: []string{"", "B must be 2"},
+		},
+		{name: "Malformed JSON", input: `["A == 1"`, errPart: "validation_expressions must be a JSON array"},
+		{name: "Bad JSON entry", input: `[1]`, errPart: "validation_expressions[0]: must be a string or an object"},
+		{name: "Parse error", input: "A == 1\nlenght(B) > 1", errPart: `validation_expressions[1]: invalid expression "lenght(B) > 1": unknown function "lenght"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exprs, err := ParseValidationExpressions(tt.input)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("ParseValidationExpressions() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseValidationExpressions() unexpected error: %v", err)
+			}
+			if len(exprs) != len(tt.exprs) {
+				t.Fatalf("ParseValidationExpressions() got %d expressions, want %d", len(exprs), len(tt.exprs))
+			}
+			for i, e := range exprs {
+				if e.Expr != tt.exprs[i] {
+					t.Errorf("expression %d = %q, want %q", i, e.Expr, tt.exprs[i])
+				}
+				if tt.messages != nil && e.Message != tt.messages[i] {
+					t.Errorf("message %d = %q, want %q", i, e.Message, tt.messages[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateExpressions(t *testing.T) {
+	values := map[string]string{"DEPLOY_ENV": "prod", "REPLICAS": "2", "IMAGE_TAG": "v1"}
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		errPart string
+		key     string
+	}{
+		{
+			name: "All hold",
+			cfg:  config.Config{ValidationExpr: "defined(IMAGE_TAG) != defined(IMAGE_DIGEST)\nREPLICAS > 1"},
+		},
+		{
+			name:    "Generated message",
+			cfg:     config.Config{ValidationExpr: `DEPLOY_ENV != "prod" || REPLICAS >= 3`},
+			errPart: `validation expression failed: DEPLOY_ENV != "prod" || REPLICAS >= 3`,
+			key:     "DEPLOY_ENV,REPLICAS",
+		},
+		{
+			name:    "Custom message",
+			cfg:     config.Config{ValidationExpr: `[{"expr":"REPLICAS >= 3","message":"need 3 replicas"}]`},
+			errPart: "need 3 replicas",
+			key:     "REPLICAS",
+		},
+		{
+			name:    "Evaluation error",
+			cfg:     config.Config{ValidationExpr: `IMAGE_DIGEST =~ "^sha256:"`},
+			errPart: `validation expression "IMAGE_DIGEST =~ \"^sha256:\"" could not be evaluated: key IMAGE_DIGEST is not set`,
+			key:     "IMAGE_DIGEST",
+		},
+		{
+			name: "Case-insensitive lookup",
+			cfg:  config.Config{ValidationExpr: `deploy_env == "prod"`},
+		},
+		{
+			name:    "Case-sensitive lookup",
+			cfg:     config.Config{ValidationExpr: `deploy_env == "prod"`, CaseSensitive: true},
+			errPart: "key deploy_env is not set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(&tt.cfg).ValidateExpressions(values)
+			if tt.errPart == "" {
+				if err != nil {
+					t.Errorf("ValidateExpressions() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("ValidateExpressions() error = %v, want containing %q", err, tt.errPart)
+			}
+			var verr *ValidationError
+			if tt.key != "" && (!errors.As(err, &verr) || verr.Key != tt.key || verr.Check != CheckExpression) {
+				t.Errorf("ValidateExpressions() error = %#v, want key %q and check %q", err, tt.key, CheckExpression)
+			}
+		})
+	}
+}
+
+func TestValidateExpressionsAmbiguousKey(t *testing.T) {
+	values := map[string]string{"Deploy_Env": "prod", "DEPLOY_ENV": "dev"}
+
+	tests := []struct {
+		name    string
+		expr    string
+		errPart string
+	}{
+		{name: "Exact key", expr: `DEPLOY_ENV == "dev" && Deploy_Env == "prod"`},
+		{name: "Several keys differing in case", expr: `deploy_env == "prod"`, errPart: `validation expression "deploy_env == \"prod\"": key deploy_env matches DEPLOY_ENV, Deploy_Env; use the exact key name`},
+		{name: "Defined", expr: `defined(deploy_env)`, errPart: "key deploy_env matches DEPLOY_ENV, Deploy_Env"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat to catch results that depend on map order
+			for i := 0; i < 20; i++ {
+				err := NewValidator(&config.Config{ValidationExpr: tt.expr}).ValidateExpressions(values)
+				if tt.errPart == "" {
+					if err != nil {
+						t.Fatalf("ValidateExpressions() unexpected error: %v", err)
+					}
+					continue
+				}
+				var verr *ValidationError
+				if !errors.As(err, &verr) || !strings.Contains(err.Error(), tt.errPart) || verr.Key != "deploy_env" {
+					t.Fatalf("ValidateExpressions() error = %v, want key deploy_env and containing %q", err, tt.errPart)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateExpressionsCollect(t *testing.T) {
+	cfg := &config.Config{
+		ValidationExpr: "A == 1\nB == 2\nA < B",
+		ValidationMode: ValidationModeCollect,
+	}
+	err := NewValidator(cfg).ValidateExpressions(map[string]string{"A": "3", "B": "1"})
+
+	var collected *ValidationErrors
+	if !errors.As(err, &collected) || len(collected.Errors) != 3 {
+		t.Fatalf("ValidateExpressions() error = %v, want 3 collected errors", err)
+	}
+	if collected.Errors[2].Key != "A,B" {
+		t.Errorf("third error key = %q, want %q", collected.Errors[2].Key, "A,B")
+	}
+}
+
+func TestCheckExpressions(t *testing.T) {
+	base := config.Config{
+		EnvKeys:        " DEPLOY_ENV ",
+		EnvValues:      " Prod ",
+		OutputKeys:     "replicas,DEPLOY_ENV",
+		OutputValues:   "3,dev",
+		StateKeys:      "app.name",
+		StateValues:    "web",
+		Delimiter:      ",",
+		TrimWhitespace: true,
+		CaseSensitive:  true,
+		ToLower:        true,
+		KeyPolicy:      KeyPolicyPosix,
+		KeyAutofix:     true,
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		errPart string
+	}{
+		{name: "Unset", expr: ""},
+		{name: "Values are trimmed and transformed, env wins", expr: `DEPLOY_ENV == "prod" && replicas >= 3`},
+		{name: "State keys use autofixed names", expr: `app_name == "web" && !defined(` + "`app.name`" + `)`},
+		{name: "Failure", expr: `replicas > 3`, errPart: "validation expression failed: replicas > 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.ValidationExpr = tt.expr
+			err := CheckExpressions(&cfg)
+			if tt.errPart == "" {
+				if err != nil {
+					t.Errorf("CheckExpressions() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("CheckExpressions() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestCheckExpressionsAnnotatesCollected(t *testing.T) {
+	cfg := &config.Config{
+		EnvKeys:        "A",
+		EnvValues:      "1",
+		Delimiter:      ",",
+		ValidationExpr: "A == 2",
+		ValidationMode: ValidationModeCollect,
+	}
+	var err error
+	out := captureStdout(t, func() {
+		err = CheckExpressions(cfg)
+	})
+	if err == nil {
+		t.Fatal("CheckExpressions() expected error")
+	}
+	if !strings.Contains(out, "::error::A: validation expression failed: A == 2") {
+		t.Errorf("CheckExpressions() output %q missing annotation", out)
+	}
+}
@@ -3,110 +3,310 @@ package writer
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
-	"github.com/somaz94/env-output-setter/internal/jsonutil"
+	"github.com/somaz94/env-output-setter/internal/keyname"
 	"github.com/somaz94/env-output-setter/internal/printer"
+	"github.com/somaz94/env-output-setter/internal/structured"
 )
 
 // JSONHandler handles JSON value processing and extraction.
-type JSONHandler struct{}
+// Despite its name it also flattens YAML and TOML values when a structured
+// format other than JSON is configured; every format shares the same key
+// naming rules.
+type JSONHandler struct {
+	format      string
+	keyOrder    string
+	separator   string
+	maxDepth    int
+	leafOnly    bool
+	arrayLength bool
+	keyStyle    string
+	nullValue   string
+}
+
+// Supported json_key_order values
+const (
+	KeyOrderSource = "source"
+	KeyOrderSorted = "sorted"
+)
+
+// Supported json_null_value representations
+const (
+	NullAsEmpty   = "empty" // null leaves become empty strings
+	NullAsLiteral = "null"  // null leaves become the literal text "null"
+	NullSkip      = "skip"  // null leaves produce no key at all
+)
+
+// DefaultJSONSeparator joins a parent key and a child key or array index.
+const DefaultJSONSeparator = "_"
+
+// separatorPattern matches the separators that keep flattened keys valid
+// variable names; anything else ends up in names the runner rejects.
+var separatorPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// IsValidSeparator reports whether separator may join flattened key segments.
+// An empty separator selects DefaultJSONSeparator.
+func IsValidSeparator(separator string) bool {
+	return separator == "" || separatorPattern.MatchString(separator)
+}
 
-// NewJSONHandler creates a new JSONHandler instance.
+// JSONOptions holds the configuration for constructing a JSONHandler.
+type JSONOptions struct {
+	Format      string // structured_format: json, yaml, toml or auto (default json)
+	KeyOrder    string // json_key_order: source or sorted (default source)
+	Separator   string // json_separator placed between key segments (default "_")
+	MaxDepth    int    // json_max_depth: deeper subtrees are emitted as compact JSON (0 = unlimited)
+	LeafOnly    bool   // json_leaf_only: omit the raw JSON of the original key and of array elements
+	ArrayLength bool   // json_array_length: emit KEY_length for every flattened array
+	KeyStyle    string // json_key_style applied to property names (see keyname package)
+	NullValue   string // json_null_value: empty, null or skip (default null)
+}
+
+// NewJSONHandler creates a new JSONHandler instance that only flattens JSON.
 func NewJSONHandler() *JSONHandler {
-	return &JSONHandler{}
+	return NewJSONHandlerWithOptions(JSONOptions{})
+}
+
+// NewJSONHandlerWithOptions creates a new JSONHandler with the given options.
+func NewJSONHandlerWithOptions(opts JSONOptions) *JSONHandler {
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = structured.FormatJSON
+	}
+	keyOrder := strings.ToLower(opts.KeyOrder)
+	if keyOrder == "" {
+		keyOrder = KeyOrderSource
+	}
+	separator := opts.Separator
+	if separator == "" {
+		separator = DefaultJSONSeparator
+	}
+	nullValue := strings.ToLower(opts.NullValue)
+	if nullValue == "" {
+		nullValue = NullAsLiteral
+	}
+	maxDepth := opts.MaxDepth
+	if maxDepth < 0 {
+		maxDepth = 0
+	}
+	return &JSONHandler{
+		format:      format,
+		keyOrder:    keyOrder,
+		separator:   separator,
+		maxDepth:    maxDepth,
+		leafOnly:    opts.LeafOnly,
+		arrayLength: opts.ArrayLength,
+		keyStyle:    strings.ToLower(opts.KeyStyle),
+		nullValue:   nullValue,
+	}
 }
 
 // ProcessJSONValues extracts nested properties from JSON values.
 // It processes JSON objects and arrays, creating flattened key-value pairs.
 func (h *JSONHandler) ProcessJSONValues(keyList, valueList []string) ([]string, []string) {
-	// Make a copy of the original lists
-	originalKeyCount := len(keyList)
-	resultKeys := make([]string, len(keyList))
-	resultValues := make([]string, len(valueList))
-	copy(resultKeys, keyList)
-	copy(resultValues, valueList)
-
-	// Process each JSON value in the original list
-	for i := 0; i < originalKeyCount; i++ {
+	return h.ProcessStructuredValues(keyList, valueList, nil)
+}
+
+// ProcessStructuredValues flattens every structured value (JSON, YAML or TOML,
+// depending on the configured format) into additional key-value pairs. hints
+// optionally holds, per value, the format implied by the file the value was
+// read from (e.g. "yaml" for file://values.yaml); it may be nil or shorter
+// than valueList.
+func (h *JSONHandler) ProcessStructuredValues(keyList, valueList, hints []string) ([]string, []string) {
+	// Original entries come first, followed by every flattened key
+	var resultKeys, resultValues []string
+	var nestedKeys, nestedValues []string
+
+	// Process each structured value in the original list
+	for i := range keyList {
 		value := valueList[i]
 		key := keyList[i]
 
-		// Check if value looks like JSON
-		if !jsonutil.IsJSONLike(value) {
-			continue
+		hint := ""
+		if i < len(hints) {
+			hint = hints[i]
 		}
 
-		// Try to parse the JSON value
-		var jsonData interface{}
-		if err := json.Unmarshal([]byte(value), &jsonData); err != nil {
-			printer.PrintWarning(fmt.Sprintf("Warning: Invalid JSON for key '%s': %v", key, err))
-			continue
-		}
+		flattened := false
 
-		// Extract nested values based on the JSON type
-		switch typedData := jsonData.(type) {
-		case map[string]interface{}:
-			// Handle JSON object
-			nestedKeys, nestedValues := h.extractNestedJSON(key, typedData)
-			resultKeys = append(resultKeys, nestedKeys...)
-			resultValues = append(resultValues, nestedValues...)
-		case []interface{}:
-			// Handle JSON array
-			for idx, item := range typedData {
-				arrayKey := fmt.Sprintf("%s_%d", key, idx)
-				resultKeys = append(resultKeys, arrayKey)
-				resultValues = append(resultValues, fmt.Sprintf("%v", item))
-
-				// Process nested objects in arrays
-				if mapItem, ok := item.(map[string]interface{}); ok {
-					objKeys, objValues := h.extractNestedJSON(arrayKey, mapItem)
-					resultKeys = append(resultKeys, objKeys...)
-					resultValues = append(resultValues, objValues...)
+		// Decide whether (and how) the value should be parsed
+		if data, format, err := decodeStructured(value, h.format, hint); format != "" {
+			if err != nil {
+				printer.PrintWarning(fmt.Sprintf("Warning: Invalid %s for key '%s': %v", strings.ToUpper(format), key, err))
+			} else {
+				// Extract nested values based on the decoded type
+				var keys, values []string
+				switch typedData := data.(type) {
+				case *structured.Object:
+					// Handle JSON object
+					keys, values = h.flattenObject(key, typedData, 1)
+					flattened = true
+				case []interface{}:
+					// Handle JSON array
+					keys, values = h.flattenArray(key, typedData, 1)
+					flattened = true
 				}
+				nestedKeys = append(nestedKeys, keys...)
+				nestedValues = append(nestedValues, values...)
 			}
 		}
+
+		// In leaf-only mode the raw document is dropped once it has been flattened
+		if flattened && h.leafOnly {
+			continue
+		}
+		resultKeys = append(resultKeys, key)
+		resultValues = append(resultValues, value)
 	}
 
-	return resultKeys, resultValues
+	return append(resultKeys, nestedKeys...), append(resultValues, nestedValues...)
+}
+
+// decodeStructured decodes value the way ProcessStructuredValues does, with the
+// structured_format mode and the format hint of its source file. It returns
+// the format used, or "" if the value is not structured.
+func decodeStructured(value, mode, hint string) (interface{}, string, error) {
+	format := structured.Detect(value, mode, hint)
+	if format == "" {
+		return nil, "", nil
+	}
+	data, err := structured.Parse(value, format)
+	return data, format, err
 }
 
-// extractNestedJSON flattens a nested JSON object into key-value pairs.
-// It recursively processes nested objects and arrays, creating concatenated keys.
-func (h *JSONHandler) extractNestedJSON(prefix string, jsonObj map[string]interface{}) ([]string, []string) {
+// flattenObject emits the properties of jsonObj beneath prefix. depth is the
+// nesting level of those properties (1 for the top-level document); once it
+// reaches json_max_depth, nested collections are emitted as compact JSON
+// instead of being descended into.
+func (h *JSONHandler) flattenObject(prefix string, jsonObj *structured.Object, depth int) ([]string, []string) {
 	var keys []string
 	var values []string
 
 	// Process each property in the JSON object
-	for propKey, propValue := range jsonObj {
-		nestedKey := fmt.Sprintf("%s_%s", prefix, propKey)
+	for _, propKey := range h.objectKeys(jsonObj) {
+		propValue, _ := jsonObj.Get(propKey)
+		if propValue == nil && h.nullValue == NullSkip {
+			continue
+		}
+		nestedKey := h.joinKey(prefix, keyname.Convert(propKey, h.keyStyle))
+
+		// Subtrees below the depth limit are kept whole
+		if h.depthReached(depth) {
+			keys = append(keys, nestedKey)
+			values = append(values, h.formatValue(propValue))
+			continue
+		}
 
 		// Handle different value types
 		switch typedValue := propValue.(type) {
-		case map[string]interface{}:
+		case *structured.Object:
 			// Recursively process nested objects
-			nestedKeys, nestedValues := h.extractNestedJSON(nestedKey, typedValue)
+			nestedKeys, nestedValues := h.flattenObject(nestedKey, typedValue, depth+1)
 			keys = append(keys, nestedKeys...)
 			values = append(values, nestedValues...)
 		case []interface{}:
 			// Process arrays
-			for i, item := range typedValue {
-				arrayKey := fmt.Sprintf("%s_%d", nestedKey, i)
-				keys = append(keys, arrayKey)
-				values = append(values, fmt.Sprintf("%v", item))
-
-				// Process objects within arrays
-				if mapItem, ok := item.(map[string]interface{}); ok {
-					subKeys, subValues := h.extractNestedJSON(arrayKey, mapItem)
-					keys = append(keys, subKeys...)
-					values = append(values, subValues...)
-				}
-			}
+			nestedKeys, nestedValues := h.flattenArray(nestedKey, typedValue, depth+1)
+			keys = append(keys, nestedKeys...)
+			values = append(values, nestedValues...)
 		default:
 			// Handle primitive values
 			keys = append(keys, nestedKey)
-			values = append(values, fmt.Sprintf("%v", typedValue))
+			values = append(values, h.formatValue(typedValue))
+		}
+	}
+
+	return keys, values
+}
+
+// flattenArray emits the elements of items beneath prefix, at the given depth.
+// Each element is emitted under its index key; object elements are
+// additionally flattened beneath it unless the depth limit has been reached.
+func (h *JSONHandler) flattenArray(prefix string, items []interface{}, depth int) ([]string, []string) {
+	var keys []string
+	var values []string
+
+	if h.arrayLength {
+		keys = append(keys, h.joinKey(prefix, "length"))
+		values = append(values, strconv.Itoa(len(items)))
+	}
+
+	for i, item := range items {
+		// Skipped nulls leave a gap so the other indices stay stable
+		if item == nil && h.nullValue == NullSkip {
+			continue
+		}
+		arrayKey := h.joinKey(prefix, strconv.Itoa(i))
+		objItem, isObject := item.(*structured.Object)
+		descend := isObject && !h.depthReached(depth)
+
+		// Leaf-only mode keeps only the flattened form of object elements
+		if !descend || !h.leafOnly {
+			keys = append(keys, arrayKey)
+			values = append(values, h.formatValue(item))
+		}
+
+		// Process objects within arrays
+		if descend {
+			subKeys, subValues := h.flattenObject(arrayKey, objItem, depth+1)
+			keys = append(keys, subKeys...)
+			values = append(values, subValues...)
 		}
 	}
 
 	return keys, values
 }
+
+// depthReached reports whether values at depth must not be descended into.
+func (h *JSONHandler) depthReached(depth int) bool {
+	return h.maxDepth > 0 && depth >= h.maxDepth
+}
+
+// joinKey appends a child segment to a flattened key.
+func (h *JSONHandler) joinKey(prefix, segment string) string {
+	return prefix + h.separator + segment
+}
+
+// objectKeys returns the keys of obj in the configured order.
+func (h *JSONHandler) objectKeys(obj *structured.Object) []string {
+	if h.keyOrder == KeyOrderSorted {
+		return obj.SortedKeys()
+	}
+	return obj.Keys()
+}
+
+// formatValue renders a decoded value using the configured null representation.
+func (h *JSONHandler) formatValue(v interface{}) string {
+	return formatScalar(v, h.nullValue)
+}
+
+// formatScalar renders a decoded value for output. Numbers keep their source
+// text (1000000 stays 1000000 rather than 1e+06), booleans are "true"/"false",
+// null follows nullValue (the literal text "null" unless it is empty or
+// skip, which render it as an empty string) and collections are emitted as compact JSON (keys in
+// source order) so the result never depends on Go's map or slice formatting.
+func formatScalar(v interface{}, nullValue string) string {
+	switch typed := v.(type) {
+	case nil:
+		if nullValue == NullAsEmpty || nullValue == NullSkip {
+			return ""
+		}
+		return "null"
+	case string:
+		return typed
+	case json.Number:
+		return typed.String()
+	case bool:
+		return strconv.FormatBool(typed)
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case *structured.Object, []interface{}:
+		if b, err := structured.Marshal(typed); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/somaz94/env-output-setter/internal/jsonutil"
+	"github.com/somaz94/env-output-setter/internal/structured"
 )
 
 func TestNewJSONHandler(t *testing.T) {
@@ -150,7 +151,7 @@ func TestProcessJSONValues(t *testing.T) {
 	}
 }
 
-func TestExtractNestedJSON(t *testing.T) {
+func TestFlattenObject(t *testing.T) {
 	tests := []struct {
 		name      string
 		prefix    string
@@ -227,21 +228,21 @@ func TestExtractNestedJSON(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			handler := NewJSONHandler()
-			keys, values := handler.extractNestedJSON(tt.prefix, tt.jsonObj)
+			keys, values := handler.flattenObject(tt.prefix, structured.FromMap(tt.jsonObj), 1)
 
 			if len(keys) < tt.minKeys {
-				t.Errorf("extractNestedJSON() keys length = %d, want at least %d",
+				t.Errorf("flattenObject() keys length = %d, want at least %d",
 					len(keys), tt.minKeys)
 			}
 
 			if len(values) < tt.minValues {
-				t.Errorf("extractNestedJSON() values length = %d, want at least %d",
+				t.Errorf("flattenObject() values length = %d, want at least %d",
 					len(values), tt.minValues)
 			}
 
 			// Verify keys and values have same length
 			if len(keys) != len(values) {
-				t.Errorf("extractNestedJSON() keys length %d != values length %d",
+				t.Errorf("flattenObject() keys length %d != values length %d",
 					len(keys), len(values))
 			}
 		})
@@ -300,7 +301,7 @@ func TestProcessJSONValuesWithComplexStructures(t *testing.T) {
 	}
 }
 
-func TestExtractNestedJSONWithArrayOfObjects(t *testing.T) {
+func TestFlattenObjectWithArrayOfObjects(t *testing.T) {
 	handler := NewJSONHandler()
 	jsonObj := map[string]interface{}{
 		"servers": []interface{}{
@@ -309,33 +310,33 @@ func TestExtractNestedJSONWithArrayOfObjects(t *testing.T) {
 		},
 	}
 
-	keys, values := handler.extractNestedJSON("APP", jsonObj)
+	keys, values := handler.flattenObject("APP", structured.FromMap(jsonObj), 1)
 
 	if len(keys) < 4 {
-		t.Errorf("extractNestedJSON() expected at least 4 keys for array of objects, got %d: %v", len(keys), keys)
+		t.Errorf("flattenObject() expected at least 4 keys for array of objects, got %d: %v", len(keys), keys)
 	}
 
 	if len(keys) != len(values) {
-		t.Errorf("extractNestedJSON() keys/values length mismatch: %d vs %d", len(keys), len(values))
+		t.Errorf("flattenObject() keys/values length mismatch: %d vs %d", len(keys), len(values))
 	}
 }
 
-func TestExtractNestedJSONWithGroupPrefixAlreadyPresent(t *testing.T) {
+func TestFlattenObjectWithGroupPrefixAlreadyPresent(t *testing.T) {
 	handler := NewJSONHandler()
 	jsonObj := map[string]interface{}{
 		"key": "value",
 	}
 
 	// prefix already starts with groupPrefix
-	keys, values := handler.extractNestedJSON("GRP_CONFIG", jsonObj)
+	keys, values := handler.flattenObject("GRP_CONFIG", structured.FromMap(jsonObj), 1)
 
 	if len(keys) != 1 {
-		t.Errorf("extractNestedJSON() expected 1 key, got %d: %v", len(keys), keys)
+		t.Errorf("flattenObject() expected 1 key, got %d: %v", len(keys), keys)
 	}
 
 	// Should not double-prefix
 	if len(keys) > 0 && strings.HasPrefix(keys[0], "GRP_GRP_") {
-		t.Errorf("extractNestedJSON() double-prefixed key: %s", keys[0])
+		t.Errorf("flattenObject() double-prefixed key: %s", keys[0])
 	}
 
 	_ = values
@@ -355,9 +356,9 @@ func BenchmarkProcessJSONValues(b *testing.B) {
 	}
 }
 
-func BenchmarkExtractNestedJSON(b *testing.B) {
+func BenchmarkFlattenObject(b *testing.B) {
 	handler := NewJSONHandler()
-	jsonObj := map[string]interface{}{
+	jsonObj := structured.FromMap(map[string]interface{}{
 		"server": map[string]interface{}{
 			"host": "localhost",
 			"port": 8080,
@@ -366,11 +367,11 @@ func BenchmarkExtractNestedJSON(b *testing.B) {
 			"host": "db.local",
 			"port": 5432,
 		},
-	}
+	})
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		handler.extractNestedJSON("CONFIG", jsonObj)
+		handler.flattenObject("CONFIG", jsonObj, 1)
 	}
 }
 
@@ -399,3 +400,321 @@ func TestProcessJSONValuesPreservesOriginal(t *testing.T) {
 		t.Errorf("ProcessJSONValues() first value = %v, want %v", resultValues[0], originalValues[0])
 	}
 }
+
+func TestProcessStructuredValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		keys     []string
+		values   []string
+		hints    []string
+		expected map[string]string
+		absent   []string
+	}{
+		{
+			name:   "YAML mapping and sequence",
+			format: "yaml",
+			keys:   []string{"VALUES"},
+			values: []string{"image:\n  repository: nginx\n  tag: \"1.25\"\nports:\n  - 80\n  - 443\n"},
+			expected: map[string]string{
+				"VALUES_image_repository": "nginx",
+				"VALUES_image_tag":        "1.25",
+				"VALUES_ports_0":          "80",
+				"VALUES_ports_1":          "443",
+			},
+		},
+		{
+			name:   "TOML tables",
+			format: "toml",
+			keys:   []string{"CARGO"},
+			values: []string{"[package]\nname = \"app\"\nversion = \"0.1.0\"\n"},
+			expected: map[string]string{
+				"CARGO_package_name":    "app",
+				"CARGO_package_version": "0.1.0",
+			},
+		},
+		{
+			name:     "JSON still handled in yaml mode",
+			format:   "yaml",
+			keys:     []string{"CONFIG"},
+			values:   []string{`{"host":"db"}`},
+			expected: map[string]string{"CONFIG_host": "db"},
+		},
+		{
+			name:     "Auto mode uses file hint",
+			format:   "auto",
+			keys:     []string{"VALUES"},
+			values:   []string{"replicas: 3"},
+			hints:    []string{"yaml"},
+			expected: map[string]string{"VALUES_replicas": "3"},
+		},
+		{
+			name:   "Auto mode leaves single-line text alone",
+			format: "auto",
+			keys:   []string{"NOTE"},
+			values: []string{"Note: done"},
+			absent: []string{"NOTE_Note"},
+		},
+		{
+			name:   "JSON mode ignores YAML",
+			format: "json",
+			keys:   []string{"VALUES"},
+			values: []string{"replicas: 3\nimage: x"},
+			absent: []string{"VALUES_replicas"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewJSONHandlerWithOptions(JSONOptions{Format: tt.format})
+			keys, values := handler.ProcessStructuredValues(tt.keys, tt.values, tt.hints)
+
+			got := make(map[string]string)
+			for i, key := range keys {
+				got[key] = values[i]
+			}
+			for key, want := range tt.expected {
+				if v, ok := got[key]; !ok || v != want {
+					t.Errorf("ProcessStructuredValues()[%s] = %q (present: %v), want %q", key, v, ok, want)
+				}
+			}
+			for _, key := range tt.absent {
+				if _, ok := got[key]; ok {
+					t.Errorf("ProcessStructuredValues() unexpectedly produced key %s", key)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessJSONValuesKeyOrder(t *testing.T) {
+	value := `{"zeta":"z","alpha":{"two":2,"one":1},"list":[{"b":"b","a":"a"}]}`
+
+	tests := []struct {
+		name     string
+		keyOrder string
+		expected []string
+	}{
+		{
+			name:     "Source order by default",
+			keyOrder: "",
+			expected: []string{"CFG", "CFG_zeta", "CFG_alpha_two", "CFG_alpha_one", "CFG_list_0", "CFG_list_0_b", "CFG_list_0_a"},
+		},
+		{
+			name:     "Sorted order",
+			keyOrder: KeyOrderSorted,
+			expected: []string{"CFG", "CFG_alpha_one", "CFG_alpha_two", "CFG_list_0", "CFG_list_0_a", "CFG_list_0_b", "CFG_zeta"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewJSONHandlerWithOptions(JSONOptions{KeyOrder: tt.keyOrder})
+
+			// Repeat to catch any dependence on map iteration order
+			for run := 0; run < 20; run++ {
+				keys, _ := handler.ProcessJSONValues([]string{"CFG"}, []string{value})
+				if strings.Join(keys, ",") != strings.Join(tt.expected, ",") {
+					t.Fatalf("ProcessJSONValues() keys = %v, want %v", keys, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessJSONValuesArrayElementObjectsAsJSON(t *testing.T) {
+	handler := NewJSONHandler()
+	keys, values := handler.ProcessJSONValues([]string{"SERVERS"}, []string{`[{"name":"s1","port":80}]`})
+
+	for i, key := range keys {
+		if key == "SERVERS_0" && values[i] != `{"name":"s1","port":80}` {
+			t.Errorf("SERVERS_0 = %q, want compact JSON in source order", values[i])
+		}
+	}
+}
+
+func TestProcessJSONValuesFlattenOptions(t *testing.T) {
+	value := `{"server-host":"example.com","db":{"maxConn":10,"tls":{"enabled":true}},"tags":["a","b"],"nodes":[{"id":1}]}`
+
+	tests := []struct {
+		name         string
+		opts         JSONOptions
+		expectedKeys []string
+		expected     map[string]string
+	}{
+		{
+			name: "Defaults",
+			opts: JSONOptions{},
+			expectedKeys: []string{
+				"CFG", "CFG_server-host", "CFG_db_maxConn", "CFG_db_tls_enabled",
+				"CFG_tags_0", "CFG_tags_1", "CFG_nodes_0", "CFG_nodes_0_id",
+			},
+		},
+		{
+			name: "Custom separator",
+			opts: JSONOptions{Separator: "__"},
+			expectedKeys: []string{
+				"CFG", "CFG__server-host", "CFG__db__maxConn", "CFG__db__tls__enabled",
+				"CFG__tags__0", "CFG__tags__1", "CFG__nodes__0", "CFG__nodes__0__id",
+			},
+		},
+		{
+			name: "Max depth keeps subtrees as JSON",
+			opts: JSONOptions{MaxDepth: 1},
+			expectedKeys: []string{
+				"CFG", "CFG_server-host", "CFG_db", "CFG_tags", "CFG_nodes",
+			},
+			expected: map[string]string{
+				"CFG_db":    `{"maxConn":10,"tls":{"enabled":true}}`,
+				"CFG_tags":  `["a","b"]`,
+				"CFG_nodes": `[{"id":1}]`,
+			},
+		},
+		{
+			name: "Max depth two",
+			opts: JSONOptions{MaxDepth: 2},
+			expectedKeys: []string{
+				"CFG", "CFG_server-host", "CFG_db_maxConn", "CFG_db_tls",
+				"CFG_tags_0", "CFG_tags_1", "CFG_nodes_0",
+			},
+			expected: map[string]string{
+				"CFG_db_tls":  `{"enabled":true}`,
+				"CFG_nodes_0": `{"id":1}`,
+			},
+		},
+		{
+			name: "Leaf only",
+			opts: JSONOptions{LeafOnly: true},
+			expectedKeys: []string{
+				"CFG_server-host", "CFG_db_maxConn", "CFG_db_tls_enabled",
+				"CFG_tags_0", "CFG_tags_1", "CFG_nodes_0_id",
+			},
+		},
+		{
+			name: "Array length",
+			opts: JSONOptions{ArrayLength: true},
+			expectedKeys: []string{
+				"CFG", "CFG_server-host", "CFG_db_maxConn", "CFG_db_tls_enabled",
+				"CFG_tags_length", "CFG_tags_0", "CFG_tags_1",
+				"CFG_nodes_length", "CFG_nodes_0", "CFG_nodes_0_id",
+			},
+			expected: map[string]string{"CFG_tags_length": "2", "CFG_nodes_length": "1"},
+		},
+		{
+			name: "Upper snake key style",
+			opts: JSONOptions{KeyStyle: "upper_snake"},
+			expectedKeys: []string{
+				"CFG", "CFG_SERVER_HOST", "CFG_DB_MAX_CONN", "CFG_DB_TLS_ENABLED",
+				"CFG_TAGS_0", "CFG_TAGS_1", "CFG_NODES_0", "CFG_NODES_0_ID",
+			},
+		},
+		{
+			name: "Sanitize key style",
+			opts: JSONOptions{KeyStyle: "sanitize"},
+			expectedKeys: []string{
+				"CFG", "CFG_server_host", "CFG_db_maxConn", "CFG_db_tls_enabled",
+				"CFG_tags_0", "CFG_tags_1", "CFG_nodes_0", "CFG_nodes_0_id",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewJSONHandlerWithOptions(tt.opts)
+			keys, values := handler.ProcessJSONValues([]string{"CFG"}, []string{value})
+
+			if strings.Join(keys, ",") != strings.Join(tt.expectedKeys, ",") {
+				t.Fatalf("ProcessJSONValues() keys = %v, want %v", keys, tt.expectedKeys)
+			}
+			for i, key := range keys {
+				if want, ok := tt.expected[key]; ok && values[i] != want {
+					t.Errorf("ProcessJSONValues()[%s] = %q, want %q", key, values[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessJSONValuesLeafOnlyKeepsPlainValues(t *testing.T) {
+	handler := NewJSONHandlerWithOptions(JSONOptions{LeafOnly: true})
+	keys, values := handler.ProcessJSONValues([]string{"PLAIN", "DOC"}, []string{"text", `{"a":"b"}`})
+
+	if strings.Join(keys, ",") != "PLAIN,DOC_a" {
+		t.Fatalf("ProcessJSONValues() keys = %v, want [PLAIN DOC_a]", keys)
+	}
+	if values[0] != "text" || values[1] != "b" {
+		t.Errorf("ProcessJSONValues() values = %v, want [text b]", values)
+	}
+}
+
+func TestProcessJSONValuesScalarFormatting(t *testing.T) {
+	value := `{"big":1000000,"price":1.50,"exp":1e3,"neg":-7,"on":true,"off":false,"none":null,"matrix":[[1,2],[3]],"list":["a",null,"c"],"html":[["x&y","<b>"]]}`
+
+	tests := []struct {
+		name      string
+		nullValue string
+		expected  map[string]string
+		absent    []string
+	}{
+		{
+			name: "Default null is literal",
+			expected: map[string]string{
+				"CFG_big":      "1000000",
+				"CFG_price":    "1.50",
+				"CFG_exp":      "1e3",
+				"CFG_neg":      "-7",
+				"CFG_on":       "true",
+				"CFG_off":      "false",
+				"CFG_none":     "null",
+				"CFG_matrix_0": "[1,2]",
+				"CFG_matrix_1": "[3]",
+				"CFG_list_1":   "null",
+				"CFG_html_0":   `["x&y","<b>"]`,
+			},
+		},
+		{
+			name:      "Empty null",
+			nullValue: NullAsEmpty,
+			expected:  map[string]string{"CFG_none": "", "CFG_list_1": ""},
+		},
+		{
+			name:      "Skip null",
+			nullValue: NullSkip,
+			expected:  map[string]string{"CFG_list_0": "a", "CFG_list_2": "c"},
+			absent:    []string{"CFG_none", "CFG_list_1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewJSONHandlerWithOptions(JSONOptions{NullValue: tt.nullValue})
+			keys, values := handler.ProcessJSONValues([]string{"CFG"}, []string{value})
+
+			got := make(map[string]string)
+			for i, key := range keys {
+				got[key] = values[i]
+			}
+			for key, want := range tt.expected {
+				if value, ok := got[key]; !ok || value != want {
+					t.Errorf("ProcessJSONValues()[%s] = %q (present %v), want %q", key, value, ok, want)
+				}
+			}
+			for _, key := range tt.absent {
+				if _, ok := got[key]; ok {
+					t.Errorf("ProcessJSONValues() unexpectedly produced key %s", key)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessJSONValuesMaxDepthKeepsNumbers(t *testing.T) {
+	handler := NewJSONHandlerWithOptions(JSONOptions{MaxDepth: 1})
+	keys, values := handler.ProcessJSONValues([]string{"CFG"}, []string{`{"limits":{"bytes":10000000,"ratio":0.10}}`})
+
+	for i, key := range keys {
+		if key == "CFG_limits" && values[i] != `{"bytes":10000000,"ratio":0.10}` {
+			t.Errorf("CFG_limits = %q, want numbers kept verbatim", values[i])
+		}
+	}
+}
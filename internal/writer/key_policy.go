@@ -0,0 +1,241 @@
+package writer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/somaz94/env-output-setter/internal/keyname"
+	"github.com/somaz94/env-output-setter/internal/printer"
+)
+
+// Supported key_policy values
+const (
+	KeyPolicyPosix   = "posix"   // names must match [A-Za-z_][A-Za-z0-9_]*
+	KeyPolicyGitHub  = "github"  // posix names, runner-reserved env names rejected
+	KeyPolicyRelaxed = "relaxed" // only names that corrupt the command files are rejected
+)
+
+// Error messages for key names
+const (
+	errInvalidKeyPolicy    = "unsupported key_policy %q (expected posix, github or relaxed)"
+	errInvalidDenyPattern  = "invalid key_denylist pattern %q: %v"
+	errKeyUnsafeChars      = "key %q contains \"=\", whitespace or a control character"
+	errKeyHeredoc          = "key %q contains \"<<\", which the runner reads as the start of a multiline value"
+	errKeyNotPosix         = "key %q is not a valid POSIX name (letters, digits and _, not starting with a digit)"
+	errOutputNameNotGitHub = "output name %q must start with a letter or _ and contain only letters, digits, - and _"
+	errKeyReserved         = "key %q is reserved: %s"
+	errKeyDenied           = "key %q is denied by key_denylist pattern %q"
+)
+
+// CheckKeyName identifies key name violations in validation errors.
+const CheckKeyName = "key_name"
+
+var (
+	posixNamePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	githubNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+)
+
+// reservedEnvPrefixes are set by the runner; GITHUB_ and RUNNER_ names written
+// to GITHUB_ENV are ignored, and ACTIONS_ names carry runner credentials.
+var reservedEnvPrefixes = []string{"GITHUB_", "RUNNER_", "ACTIONS_"}
+
+// reservedEnvNames change what later steps execute when overridden.
+var reservedEnvNames = []string{
+	"PATH", "NODE_OPTIONS", "LD_PRELOAD", "LD_LIBRARY_PATH", "LD_AUDIT",
+	"DYLD_INSERT_LIBRARIES", "DYLD_LIBRARY_PATH", "BASH_ENV", "ENV",
+	"PROMPT_COMMAND", "IFS", "SHELLOPTS", "BASHOPTS", "PS4",
+}
+
+// CheckKeyNames applies key_policy and key_denylist to the keys written to
+// destination (env, output or state). With key_autofix, names that break the
+// policy's syntax are rewritten and a warning is printed; reserved and denied
+// names always fail. The returned keys replace the given ones, even when an
+// error is returned. In collect mode every problem is returned as
+// *ValidationErrors.
+func (v *Validator) CheckKeyNames(keys []string, destination string) ([]string, error) {
+	policy, err := v.keyPolicy()
+	if err != nil {
+		return keys, err
+	}
+	denylist, err := parseKeyDenylist(v.cfg.KeyDenylist)
+	if err != nil {
+		return keys, err
+	}
+	collector, err := newErrorCollector(v.cfg.ValidationMode)
+	if err != nil {
+		return keys, err
+	}
+
+	checked := make([]string, len(keys))
+	copy(checked, keys)
+	for i, key := range keys {
+		name := key
+		if v.cfg.TrimWhitespace {
+			name = strings.TrimSpace(name)
+		}
+		if name == "" {
+			continue
+		}
+
+		if msg := keySyntaxError(name, policy, destination); msg != "" {
+			if !v.cfg.KeyAutofix {
+				if err := collector.add(name, CheckKeyName, msg); err != nil {
+					return checked, err
+				}
+				continue
+			}
+			fixed := fixKeyName(name, policy)
+			printer.PrintWarning(fmt.Sprintf("Warning: renamed key %q to %q to satisfy key_policy %s", name, fixed, policy))
+			name = fixed
+			checked[i] = fixed
+		}
+
+		if policy == KeyPolicyGitHub && destination == envFileType {
+			if reason := reservedReason(name); reason != "" {
+				if err := collector.add(name, CheckKeyName, fmt.Sprintf(errKeyReserved, name, reason)); err != nil {
+					return checked, err
+				}
+				continue
+			}
+		}
+
+		for _, pattern := range denylist {
+			if matched, _ := path.Match(strings.ToUpper(pattern), strings.ToUpper(name)); matched {
+				if err := collector.add(name, CheckKeyName, fmt.Sprintf(errKeyDenied, name, pattern)); err != nil {
+					return checked, err
+				}
+				break
+			}
+		}
+	}
+
+	return checked, collector.result()
+}
+
+// fixedKeyNames returns keys renamed the way CheckKeyNames renames them with
+// key_autofix, without reporting anything.
+func (v *Validator) fixedKeyNames(keys []string, destination string) []string {
+	policy, err := v.keyPolicy()
+	if err != nil || !v.cfg.KeyAutofix {
+		return keys
+	}
+	fixed := make([]string, len(keys))
+	for i, key := range keys {
+		fixed[i] = key
+		if v.cfg.TrimWhitespace {
+			key = strings.TrimSpace(key)
+		}
+		if key != "" && keySyntaxError(key, policy, destination) != "" {
+			fixed[i] = fixKeyName(key, policy)
+		}
+	}
+	return fixed
+}
+
+// keyPolicy returns the configured key_policy, relaxed if unset.
+func (v *Validator) keyPolicy() (string, error) {
+	policy := strings.ToLower(v.cfg.KeyPolicy)
+	switch policy {
+	case "":
+		return KeyPolicyRelaxed, nil
+	case KeyPolicyPosix, KeyPolicyGitHub, KeyPolicyRelaxed:
+		return policy, nil
+	}
+	return "", fmt.Errorf(errInvalidKeyPolicy, v.cfg.KeyPolicy)
+}
+
+// keySyntaxError describes why name is not allowed by policy, or returns "".
+// Under the github policy output names may also contain '-'.
+func keySyntaxError(name, policy, destination string) string {
+	if strings.ContainsRune(name, '=') || strings.IndexFunc(name, isUnsafeKeyRune) >= 0 {
+		return fmt.Sprintf(errKeyUnsafeChars, name)
+	}
+	if strings.Contains(name, "<<") {
+		return fmt.Sprintf(errKeyHeredoc, name)
+	}
+	switch {
+	case policy == KeyPolicyGitHub && destination == outputFileType:
+		if !githubNamePattern.MatchString(name) {
+			return fmt.Sprintf(errOutputNameNotGitHub, name)
+		}
+	case policy != KeyPolicyRelaxed:
+		if !posixNamePattern.MatchString(name) {
+			return fmt.Sprintf(errKeyNotPosix, name)
+		}
+	}
+	return ""
+}
+
+// isUnsafeKeyRune reports whether r would corrupt a command file entry.
+func isUnsafeKeyRune(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsControl(r)
+}
+
+// fixKeyName rewrites name so it satisfies policy: relaxed replaces each run
+// of unsafe characters, '=' and "<<" with '_', the other policies sanitize the
+// name with keyname and prefix a leading digit with '_'.
+func fixKeyName(name, policy string) string {
+	if policy == KeyPolicyRelaxed {
+		runes := []rune(name)
+		var b strings.Builder
+		inUnsafe := false
+		for i, r := range runes {
+			heredoc := r == '<' && ((i+1 < len(runes) && runes[i+1] == '<') || (i > 0 && runes[i-1] == '<'))
+			if r == '=' || heredoc || isUnsafeKeyRune(r) {
+				if !inUnsafe {
+					b.WriteByte('_')
+				}
+				inUnsafe = true
+				continue
+			}
+			b.WriteRune(r)
+			inUnsafe = false
+		}
+		return b.String()
+	}
+
+	fixed := keyname.Convert(name, keyname.StyleSanitize)
+	if fixed[0] >= '0' && fixed[0] <= '9' {
+		fixed = "_" + fixed
+	}
+	return fixed
+}
+
+// reservedReason explains why name is reserved for env, or returns "".
+func reservedReason(name string) string {
+	upper := strings.ToUpper(name)
+	for _, prefix := range reservedEnvPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return fmt.Sprintf("names starting with %s belong to the runner", prefix)
+		}
+	}
+	for _, reserved := range reservedEnvNames {
+		if upper == reserved {
+			if reserved == "PATH" {
+				return "overriding it changes what later steps execute (use path_entries instead)"
+			}
+			return "overriding it changes what later steps execute"
+		}
+	}
+	return ""
+}
+
+// parseKeyDenylist splits key_denylist on commas and newlines into glob
+// patterns, which match names case-insensitively. Malformed ones are rejected.
+func parseKeyDenylist(input string) ([]string, error) {
+	var patterns []string
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '\n' }) {
+		pattern := strings.TrimSpace(field)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf(errInvalidDenyPattern, pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
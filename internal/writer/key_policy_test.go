@@ -0,0 +1,301 @@
+package writer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+func TestCheckKeyNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.Config
+		keys        []string
+		destination string
+		want        []string
+		errPart     string
+	}{
+		{
+			name:        "Relaxed allows dots and dashes",
+			cfg:         config.Config{KeyPolicy: KeyPolicyRelaxed},
+			keys:        []string{"app.name", "my-key", "1ST"},
+			destination: envFileType,
+			want:        []string{"app.name", "my-key", "1ST"},
+		},
+		{
+			name:        "Empty policy behaves like relaxed",
+			keys:        []string{"GITHUB_TOKEN", "PATH"},
+			destination: envFileType,
+			want:        []string{"GITHUB_TOKEN", "PATH"},
+		},
+		{
+			name:        "Relaxed rejects equals sign",
+			cfg:         config.Config{KeyPolicy: KeyPolicyRelaxed},
+			keys:        []string{"A=B"},
+			destination: envFileType,
+			errPart:     `key "A=B" contains "=", whitespace or a control character`,
+		},
+		{
+			name:        "Relaxed rejects inner whitespace",
+			cfg:         config.Config{KeyPolicy: KeyPolicyRelaxed, TrimWhitespace: true},
+			keys:        []string{"  MY KEY  "},
+			destination: outputFileType,
+			errPart:     `key "MY KEY" contains`,
+		},
+		{
+			name:        "Relaxed rejects newline",
+			cfg:         config.Config{KeyPolicy: KeyPolicyRelaxed},
+			keys:        []string{"KEY\nEVIL"},
+			destination: envFileType,
+			errPart:     `contains "=", whitespace or a control character`,
+		},
+		{
+			name:        "Relaxed rejects heredoc marker",
+			cfg:         config.Config{KeyPolicy: KeyPolicyRelaxed},
+			keys:        []string{"A<<B", "a<b"},
+			destination: outputFileType,
+			errPart:     `key "A<<B" contains "<<", which the runner reads as the start of a multiline value`,
+		},
+		{
+			name:        "Posix rejects leading digit",
+			cfg:         config.Config{KeyPolicy: KeyPolicyPosix},
+			keys:        []string{"1KEY"},
+			destination: envFileType,
+			errPart:     `key "1KEY" is not a valid POSIX name`,
+		},
+		{
+			name:        "Posix rejects dash in outputs",
+			cfg:         config.Config{KeyPolicy: KeyPolicyPosix},
+			keys:        []string{"my-output"},
+			destination: outputFileType,
+			errPart:     `key "my-output" is not a valid POSIX name`,
+		},
+		{
+			name:        "GitHub allows dash in outputs",
+			cfg:         config.Config{KeyPolicy: KeyPolicyGitHub},
+			keys:        []string{"my-output", "GITHUB_REF", "PATH"},
+			destination: outputFileType,
+			want:        []string{"my-output", "GITHUB_REF", "PATH"},
+		},
+		{
+			name:        "GitHub rejects dash in env",
+			cfg:         config.Config{KeyPolicy: KeyPolicyGitHub},
+			keys:        []string{"my-var"},
+			destination: envFileType,
+			errPart:     `key "my-var" is not a valid POSIX name`,
+		},
+		{
+			name:        "GitHub rejects runner prefix",
+			cfg:         config.Config{KeyPolicy: "GitHub"},
+			keys:        []string{"github_token"},
+			destination: envFileType,
+			errPart:     `key "github_token" is reserved: names starting with GITHUB_ belong to the runner`,
+		},
+		{
+			name:        "GitHub rejects PATH",
+			cfg:         config.Config{KeyPolicy: KeyPolicyGitHub},
+			keys:        []string{"PATH"},
+			destination: envFileType,
+			errPart:     "use path_entries instead",
+		},
+		{
+			name:        "GitHub rejects NODE_OPTIONS",
+			cfg:         config.Config{KeyPolicy: KeyPolicyGitHub},
+			keys:        []string{"NODE_OPTIONS"},
+			destination: envFileType,
+			errPart:     `key "NODE_OPTIONS" is reserved: overriding it changes what later steps execute`,
+		},
+		{
+			name:        "GitHub reserved names do not apply to state",
+			cfg:         config.Config{KeyPolicy: KeyPolicyGitHub},
+			keys:        []string{"PATH"},
+			destination: stateFileType,
+			want:        []string{"PATH"},
+		},
+		{
+			name:        "Denylist glob matches case-insensitively",
+			cfg:         config.Config{KeyPolicy: KeyPolicyRelaxed, KeyDenylist: "AWS_*\nsecret"},
+			keys:        []string{"APP", "aws_region"},
+			destination: outputFileType,
+			errPart:     `key "aws_region" is denied by key_denylist pattern "AWS_*"`,
+		},
+		{
+			name:        "Autofix relaxed",
+			cfg:         config.Config{KeyPolicy: KeyPolicyRelaxed, KeyAutofix: true},
+			keys:        []string{"A = B", "OK", "A<<B", "A<<<B", "a<b"},
+			destination: envFileType,
+			want:        []string{"A_B", "OK", "A_B", "A_B", "a<b"},
+		},
+		{
+			name:        "Autofix posix",
+			cfg:         config.Config{KeyPolicy: KeyPolicyPosix, KeyAutofix: true},
+			keys:        []string{"app.name", "9lives", "server-host"},
+			destination: envFileType,
+			want:        []string{"app_name", "_9lives", "server_host"},
+		},
+		{
+			name:        "Autofix does not bypass the denylist",
+			cfg:         config.Config{KeyPolicy: KeyPolicyPosix, KeyAutofix: true, KeyDenylist: "LD_*"},
+			keys:        []string{"LD-PRELOAD"},
+			destination: envFileType,
+			errPart:     `key "LD_PRELOAD" is denied by key_denylist pattern "LD_*"`,
+		},
+		{
+			name:        "Empty keys are left to empty value checks",
+			cfg:         config.Config{KeyPolicy: KeyPolicyPosix},
+			keys:        []string{""},
+			destination: envFileType,
+			want:        []string{""},
+		},
+		{
+			name:        "Unknown policy",
+			cfg:         config.Config{KeyPolicy: "strict"},
+			keys:        []string{"KEY"},
+			destination: envFileType,
+			errPart:     `unsupported key_policy "strict"`,
+		},
+		{
+			name:        "Malformed denylist pattern",
+			cfg:         config.Config{KeyDenylist: "KEY_["},
+			keys:        []string{"KEY"},
+			destination: envFileType,
+			errPart:     `invalid key_denylist pattern "KEY_["`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			var err error
+			captureStdout(t, func() {
+				got, err = NewValidator(&tt.cfg).CheckKeyNames(tt.keys, tt.destination)
+			})
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("CheckKeyNames() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckKeyNames() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckKeyNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckKeyNamesAutofixWarns(t *testing.T) {
+	cfg := &config.Config{KeyPolicy: KeyPolicyPosix, KeyAutofix: true}
+	keys := []string{"app.name"}
+	out := captureStdout(t, func() {
+		if _, err := NewValidator(cfg).CheckKeyNames(keys, envFileType); err != nil {
+			t.Errorf("CheckKeyNames() unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(out, `renamed key "app.name" to "app_name" to satisfy key_policy posix`) {
+		t.Errorf("CheckKeyNames() output %q missing rename warning", out)
+	}
+	if keys[0] != "app.name" {
+		t.Errorf("CheckKeyNames() mutated caller slice: %q", keys)
+	}
+}
+
+func TestCheckKeyNamesCollect(t *testing.T) {
+	cfg := &config.Config{
+		KeyPolicy:      KeyPolicyGitHub,
+		KeyDenylist:    "SECRET_*",
+		ValidationMode: ValidationModeCollect,
+	}
+	_, err := NewValidator(cfg).CheckKeyNames([]string{"1A", "GITHUB_SHA", "SECRET_X", "OK"}, envFileType)
+
+	var collected *ValidationErrors
+	if !errors.As(err, &collected) {
+		t.Fatalf("CheckKeyNames() error = %v, want *ValidationErrors", err)
+	}
+	var keys []string
+	for _, verr := range collected.Errors {
+		if verr.Check != CheckKeyName {
+			t.Errorf("error check = %q, want %q", verr.Check, CheckKeyName)
+		}
+		keys = append(keys, verr.Key)
+	}
+	if want := []string{"1A", "GITHUB_SHA", "SECRET_X"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("failing keys = %q, want %q", keys, want)
+	}
+}
+
+func TestSetEnvKeyPolicy(t *testing.T) {
+	t.Run("Autofixed keys are written", func(t *testing.T) {
+		envFile := filepath.Join(t.TempDir(), "github_env")
+		t.Setenv(githubEnvVar, envFile)
+		cfg := &config.Config{
+			EnvKeys:    "app.name,app-version",
+			EnvValues:  "web,1.0",
+			Delimiter:  ",",
+			KeyPolicy:  KeyPolicyPosix,
+			KeyAutofix: true,
+		}
+		captureStdout(t, func() {
+			if _, err := SetEnv(cfg); err != nil {
+				t.Fatalf("SetEnv() unexpected error: %v", err)
+			}
+		})
+		content, err := os.ReadFile(envFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), "app_name<<") || !strings.Contains(string(content), "app_version<<") {
+			t.Errorf("SetEnv() file content = %q, want renamed keys", content)
+		}
+	})
+
+	t.Run("Key name errors are collected with other checks", func(t *testing.T) {
+		t.Setenv(githubEnvVar, "")
+		cfg := &config.Config{
+			EnvKeys:         "GITHUB_X,PORT",
+			EnvValues:       "a,abc",
+			Delimiter:       ",",
+			KeyPolicy:       KeyPolicyGitHub,
+			ValidationMode:  ValidationModeCollect,
+			ValidationRules: `{"PORT":{"type":"int"}}`,
+		}
+		var err error
+		out := captureStdout(t, func() {
+			_, err = SetEnv(cfg)
+		})
+		var collected *ValidationErrors
+		if !errors.As(err, &collected) || len(collected.Errors) != 2 {
+			t.Fatalf("SetEnv() error = %v, want 2 collected errors", err)
+		}
+		if !strings.Contains(out, "::error::GITHUB_X: key \"GITHUB_X\" is reserved") {
+			t.Errorf("SetEnv() output %q missing key name annotation", out)
+		}
+	})
+
+	t.Run("Exported outputs follow env rules", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv(githubOutputVar, filepath.Join(dir, "output"))
+		t.Setenv(githubEnvVar, filepath.Join(dir, "env"))
+		cfg := &config.Config{
+			OutputKeys:   "deploy-target",
+			OutputValues: "prod",
+			Delimiter:    ",",
+			KeyPolicy:    KeyPolicyGitHub,
+			ExportAsEnv:  true,
+		}
+		var err error
+		captureStdout(t, func() {
+			_, err = SetOutput(cfg)
+		})
+		if err == nil || !strings.Contains(err.Error(), `key "deploy-target" is not a valid POSIX name`) {
+			t.Errorf("SetOutput() error = %v, want POSIX name error", err)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package writer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/printer"
+	"github.com/somaz94/env-output-setter/internal/structured"
+	"github.com/somaz94/env-output-setter/internal/transformer"
+)
+
+// minLeafMaskLength is the shortest leaf of a structured value that is
+// registered on its own. Masking shorter leaves such as "1" or "on" would
+// redact every matching digit or word in the log.
+const minLeafMaskLength = 4
+
+// Masker registers sensitive values with the GitHub Actions runner via
+// ::add-mask:: so they are redacted from every later log line, not just from
+// this action's own success messages.
+type Masker struct {
+	cfg         *config.Config
+	transformer *transformer.Transformer
+	registered  map[string]struct{}
+}
+
+// NewMasker creates a new Masker instance.
+func NewMasker(cfg *config.Config, t *transformer.Transformer) *Masker {
+	return &Masker{
+		cfg:         cfg,
+		transformer: t,
+		registered:  make(map[string]struct{}),
+	}
+}
+
+// Register emits ::add-mask:: for every value that should be masked. Both the
+// processed value and its transformed form (the one actually written) are
+// registered, as is each line of a multiline value and, for JSON values, each
+// scalar leaf so the flattened keys never leak a secret nested in a masked parent.
+func (m *Masker) Register(values []string) {
+	m.RegisterPairs(nil, values)
+}
+
+// RegisterPairs is Register for values set under keys, which also registers
+// the form each value takes after the transforms pipeline of its key, e.g. a
+// base64-encoded secret.
+func (m *Masker) RegisterPairs(keys, values []string) {
+	for i, value := range values {
+		if !m.transformer.ShouldMask(value) {
+			continue
+		}
+		m.registerValue(value)
+		m.registerValue(m.transformer.TransformValue(value, m.cfg.JsonSupport))
+		if i < len(keys) {
+			key := keys[i]
+			if m.cfg.TrimWhitespace {
+				key = strings.TrimSpace(key)
+			}
+			if written, err := m.transformer.TransformKeyValue(key, value, m.cfg.JsonSupport); err == nil {
+				m.registerValue(written)
+			}
+		}
+
+		m.registerStructuredLeaves(value, "")
+	}
+}
+
+// RegisterStructured registers the leaves of every value that should be masked
+// and is a structured document, decoded as ProcessStructuredValues decodes it:
+// with structured_format and, per value, the format hint of its source file
+// (hints may be nil or shorter than values). The processor calls it before
+// flattening, so YAML and TOML leaves are masked too, even when json_leaf_only
+// drops the document itself.
+func (m *Masker) RegisterStructured(values, hints []string) {
+	for i, value := range values {
+		if !m.transformer.ShouldMask(value) {
+			continue
+		}
+		hint := ""
+		if i < len(hints) {
+			hint = hints[i]
+		}
+		m.registerStructuredLeaves(value, hint)
+	}
+}
+
+// registerStructuredLeaves registers the leaves of value if json_support is on
+// and it decodes as a structured document.
+func (m *Masker) registerStructuredLeaves(value, hint string) {
+	if !m.cfg.JsonSupport {
+		return
+	}
+	if data, format, err := decodeStructured(value, m.cfg.StructuredFormat, hint); format != "" && err == nil {
+		m.registerJSONLeaves(data)
+	}
+}
+
+// registerValue registers value and each of its non-blank lines, skipping
+// anything already registered by this Masker.
+func (m *Masker) registerValue(value string) {
+	candidates := []string{value}
+	if strings.ContainsAny(value, "\r\n") {
+		candidates = append(candidates, strings.FieldsFunc(value, func(r rune) bool {
+			return r == '\n' || r == '\r'
+		})...)
+	}
+
+	for _, candidate := range candidates {
+		if strings.TrimSpace(candidate) == "" {
+			continue
+		}
+		if _, ok := m.registered[candidate]; ok {
+			continue
+		}
+		m.registered[candidate] = struct{}{}
+		printer.PrintAddMask(candidate)
+	}
+}
+
+// registerJSONLeaves walks a decoded JSON, YAML or TOML document and registers every scalar
+// leaf of at least minLeafMaskLength characters, except booleans and nulls.
+func (m *Masker) registerJSONLeaves(data interface{}) {
+	switch typed := data.(type) {
+	case *structured.Object:
+		for _, key := range typed.Keys() {
+			child, _ := typed.Get(key)
+			m.registerJSONLeaves(child)
+		}
+	case []interface{}:
+		for _, child := range typed {
+			m.registerJSONLeaves(child)
+		}
+	case string:
+		m.registerLeaf(typed)
+	case nil, bool:
+		// Masking "true"/"false" would redact unrelated log output
+		return
+	default:
+		// Render numbers exactly as the flattened keys will contain them
+		m.registerLeaf(formatScalar(typed, NullAsEmpty))
+	}
+}
+
+// registerLeaf registers a leaf unless it is too short or reads as a boolean
+// or null, which would mask unrelated log output.
+func (m *Masker) registerLeaf(leaf string) {
+	if utf8.RuneCountInString(strings.TrimSpace(leaf)) < minLeafMaskLength {
+		return
+	}
+	switch strings.ToLower(strings.TrimSpace(leaf)) {
+	case "true", "false", "null":
+		return
+	}
+	m.registerValue(leaf)
+}
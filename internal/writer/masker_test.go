@@ -0,0 +1,276 @@
+package writer
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+
+	f()
+
+	w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String()
+}
+
+func TestMaskerRegister(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *config.Config
+		values    []string
+		wantMasks []string
+		noMasks   []string
+	}{
+		{
+			name:      "Masking disabled",
+			cfg:       &config.Config{},
+			values:    []string{"secret123"},
+			noMasks:   []string{"secret123"},
+			wantMasks: nil,
+		},
+		{
+			name:      "Mask all values",
+			cfg:       &config.Config{MaskAll: true},
+			values:    []string{"value1", "value2"},
+			wantMasks: []string{"value1", "value2"},
+		},
+		{
+			name:      "Only pattern matches are masked",
+			cfg:       &config.Config{MaskSecrets: true, MaskPattern: "^secret_"},
+			values:    []string{"secret_token", "public_value"},
+			wantMasks: []string{"secret_token"},
+			noMasks:   []string{"public_value"},
+		},
+		{
+			name:      "Pattern requires mask_secrets",
+			cfg:       &config.Config{MaskPattern: "^secret_"},
+			values:    []string{"secret_token"},
+			noMasks:   []string{"secret_token"},
+			wantMasks: nil,
+		},
+		{
+			name:      "Each line of a multiline value",
+			cfg:       &config.Config{MaskAll: true},
+			values:    []string{"line-one\nline-two"},
+			wantMasks: []string{"line-one", "line-two", "line-one%0Aline-two"},
+		},
+		{
+			name:      "Transformed value is registered",
+			cfg:       &config.Config{MaskAll: true, ToUpper: true},
+			values:    []string{"token"},
+			wantMasks: []string{"token", "TOKEN"},
+		},
+		{
+			name:      "JSON leaves are registered",
+			cfg:       &config.Config{MaskSecrets: true, MaskPattern: "password", JsonSupport: true},
+			values:    []string{`{"db":{"password":"hunter2","port":5432,"tls":true}}`},
+			wantMasks: []string{"hunter2", "5432"},
+			noMasks:   []string{"true"},
+		},
+		{
+			name:      "JSON number leaves keep their source text",
+			cfg:       &config.Config{MaskAll: true, JsonSupport: true},
+			values:    []string{`{"pin":10000000,"rate":0.50,"none":null}`},
+			wantMasks: []string{"10000000", "0.50"},
+			noMasks:   []string{"1e+07", "0.5", "<nil>"},
+		},
+		{
+			name:      "YAML leaves are registered with structured_format",
+			cfg:       &config.Config{MaskSecrets: true, MaskPattern: "password", JsonSupport: true, StructuredFormat: "yaml"},
+			values:    []string{"db:\n  password: hunter2\n  port: 5432\n"},
+			wantMasks: []string{"hunter2", "5432"},
+		},
+		{
+			name:      "TOML leaves are registered with structured_format",
+			cfg:       &config.Config{MaskAll: true, JsonSupport: true, StructuredFormat: "toml"},
+			values:    []string{"[db]\npassword = \"hunter2\"\n"},
+			wantMasks: []string{"hunter2"},
+		},
+		{
+			name:    "YAML is not decoded without structured_format",
+			cfg:     &config.Config{MaskAll: true, JsonSupport: true},
+			values:  []string{"db:\n  password: hunter2\n"},
+			noMasks: []string{"hunter2"},
+		},
+		{
+			name:      "Short and boolean-like leaves are not registered",
+			cfg:       &config.Config{MaskAll: true, JsonSupport: true},
+			values:    []string{`{"id":1,"flag":"on","port":443,"enabled":"false","token":"abcd"}`},
+			wantMasks: []string{"abcd"},
+			noMasks:   []string{"1", "on", "443", "false"},
+		},
+		{
+			name:      "Percent signs are escaped",
+			cfg:       &config.Config{MaskAll: true},
+			values:    []string{"100%"},
+			wantMasks: []string{"100%25"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMasker(tt.cfg, newTransformer(tt.cfg))
+			output := captureStdout(t, func() {
+				m.Register(tt.values)
+			})
+
+			lines := strings.Split(strings.TrimSpace(output), "\n")
+			has := func(v string) bool {
+				for _, line := range lines {
+					if line == "::add-mask::"+v {
+						return true
+					}
+				}
+				return false
+			}
+
+			for _, want := range tt.wantMasks {
+				if !has(want) {
+					t.Errorf("Register() missing mask for %q, output: %q", want, output)
+				}
+			}
+			for _, notWant := range tt.noMasks {
+				if has(notWant) {
+					t.Errorf("Register() unexpectedly masked %q", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestMaskerRegisterDeduplicates(t *testing.T) {
+	cfg := &config.Config{MaskAll: true}
+	m := NewMasker(cfg, newTransformer(cfg))
+
+	output := captureStdout(t, func() {
+		m.Register([]string{"same"})
+		m.Register([]string{"same"})
+	})
+
+	if count := strings.Count(output, "::add-mask::same\n"); count != 1 {
+		t.Errorf("Register() emitted %d masks for the same value, want 1", count)
+	}
+}
+
+func TestMaskerRegisterPairs(t *testing.T) {
+	cfg := &config.Config{MaskAll: true, Transforms: "TOKEN: upper | truncate(6)"}
+	m := NewMasker(cfg, newTransformer(cfg))
+
+	output := captureStdout(t, func() {
+		m.RegisterPairs([]string{"TOKEN", "OTHER"}, []string{"secret-value", "plain-value"})
+	})
+
+	for _, want := range []string{"secret-value", "SECRET", "plain-value"} {
+		if !strings.Contains(output, "::add-mask::"+want+"\n") {
+			t.Errorf("RegisterPairs() missing mask for %q, output: %q", want, output)
+		}
+	}
+	if strings.Contains(output, "PLAIN") {
+		t.Errorf("RegisterPairs() applied the TOKEN pipeline to OTHER: %q", output)
+	}
+}
+
+func TestSetEnvRegistersMasksBeforeWriting(t *testing.T) {
+	envFile := t.TempDir() + "/github_env"
+	t.Setenv(githubEnvVar, envFile)
+
+	cfg := &config.Config{
+		EnvKeys:        "API_TOKEN",
+		EnvValues:      "s3cr3t-value",
+		Delimiter:      ",",
+		TrimWhitespace: true,
+		MaskAll:        true,
+	}
+
+	output := captureStdout(t, func() {
+		if _, err := SetEnv(cfg); err != nil {
+			t.Errorf("SetEnv() error = %v", err)
+		}
+	})
+
+	maskIdx := strings.Index(output, "::add-mask::s3cr3t-value")
+	if maskIdx < 0 {
+		t.Fatalf("SetEnv() did not register mask, output: %q", output)
+	}
+	if successIdx := strings.Index(output, "API_TOKEN"); successIdx >= 0 && successIdx < maskIdx {
+		t.Error("SetEnv() logged the key before registering its mask")
+	}
+}
+
+func TestSetEnvRegistersMasksBeforeDebugLogging(t *testing.T) {
+	t.Setenv(githubEnvVar, t.TempDir()+"/github_env")
+
+	cfg := &config.Config{
+		EnvKeys:        "API_TOKEN",
+		EnvValues:      "s3cr3t-value",
+		Delimiter:      ",",
+		TrimWhitespace: true,
+		MaskAll:        true,
+		DebugMode:      true,
+	}
+
+	output := captureStdout(t, func() {
+		if _, err := SetEnv(cfg); err != nil {
+			t.Errorf("SetEnv() error = %v", err)
+		}
+	})
+
+	maskIdx := strings.Index(output, "::add-mask::s3cr3t-value")
+	if maskIdx < 0 {
+		t.Fatalf("SetEnv() did not register mask, output: %q", output)
+	}
+	if first := strings.Index(output, "s3cr3t-value"); first < maskIdx {
+		t.Errorf("SetEnv() logged the value before registering its mask, output: %q", output)
+	}
+}
+
+func TestSetEnvRegistersStructuredFileLeaves(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(githubEnvVar, dir+"/github_env")
+	configFile := dir + "/config.yaml"
+	if err := os.WriteFile(configFile, []byte("password: hunter2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		EnvKeys:          "CFG",
+		EnvValues:        "file://" + configFile,
+		Delimiter:        ",",
+		TrimWhitespace:   true,
+		JsonSupport:      true,
+		JsonLeafOnly:     true,
+		StructuredFormat: "auto",
+		MaskSecrets:      true,
+		MaskPattern:      "password",
+	}
+
+	output := captureStdout(t, func() {
+		if _, err := SetEnv(cfg); err != nil {
+			t.Errorf("SetEnv() error = %v", err)
+		}
+	})
+
+	maskIdx := strings.Index(output, "::add-mask::hunter2\n")
+	if maskIdx < 0 {
+		t.Fatalf("SetEnv() did not register the YAML leaf, output: %q", output)
+	}
+	if idx := strings.Index(output, "CFG_password"); idx >= 0 && idx < maskIdx {
+		t.Errorf("SetEnv() logged the flattened key before registering its mask, output: %q", output)
+	}
+}
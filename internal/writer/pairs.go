@@ -0,0 +1,79 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error messages for pairs parsing
+const (
+	errPairSyntax       = "pairs line %d: expected KEY=value or KEY<<DELIMITER, got %q"
+	errPairEmptyKey     = "pairs line %d: empty key"
+	errPairUnterminated = "pairs line %d: heredoc for key %q is missing closing delimiter %q"
+)
+
+// heredocMarker introduces a multiline value in pairs input (KEY<<DELIMITER).
+const heredocMarker = "<<"
+
+// ParsePairs parses the env_pairs/output_pairs input into parallel key and value
+// slices. Each non-blank line holds one KEY=value entry (split on the first '=',
+// surrounding whitespace trimmed). A KEY<<DELIMITER line starts a multiline value
+// made of every following line up to a line consisting solely of DELIMITER; those
+// lines are kept verbatim. Lines starting with '#' are comments.
+func ParsePairs(input string) ([]string, []string, error) {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+
+	var keys, values []string
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		eqIdx := strings.Index(line, "=")
+		hdIdx := strings.Index(line, heredocMarker)
+
+		// Heredoc form: KEY<<DELIM with no '=' before the marker
+		if hdIdx >= 0 && (eqIdx < 0 || hdIdx < eqIdx) {
+			key := strings.TrimSpace(line[:hdIdx])
+			delimiter := strings.TrimSpace(line[hdIdx+len(heredocMarker):])
+			if key == "" {
+				return nil, nil, fmt.Errorf(errPairEmptyKey, lineNo)
+			}
+			if delimiter == "" {
+				return nil, nil, fmt.Errorf(errPairSyntax, lineNo, line)
+			}
+
+			var body []string
+			closed := false
+			for i++; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) == delimiter {
+					closed = true
+					break
+				}
+				body = append(body, lines[i])
+			}
+			if !closed {
+				return nil, nil, fmt.Errorf(errPairUnterminated, lineNo, key, delimiter)
+			}
+
+			keys = append(keys, key)
+			values = append(values, strings.Join(body, "\n"))
+			continue
+		}
+
+		if eqIdx < 0 {
+			return nil, nil, fmt.Errorf(errPairSyntax, lineNo, line)
+		}
+
+		key := strings.TrimSpace(line[:eqIdx])
+		if key == "" {
+			return nil, nil, fmt.Errorf(errPairEmptyKey, lineNo)
+		}
+		keys = append(keys, key)
+		values = append(values, strings.TrimSpace(line[eqIdx+1:]))
+	}
+
+	return keys, values, nil
+}
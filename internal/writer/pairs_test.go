@@ -0,0 +1,174 @@
+package writer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+func TestParsePairs(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expectedKeys   []string
+		expectedValues []string
+		wantError      bool
+		errMsg         string
+	}{
+		{
+			name:           "Simple pairs",
+			input:          "KEY1=value1\nKEY2=value2",
+			expectedKeys:   []string{"KEY1", "KEY2"},
+			expectedValues: []string{"value1", "value2"},
+		},
+		{
+			name:           "Blank lines, comments and surrounding whitespace",
+			input:          "\n  KEY1 = value1  \n# comment\n\nKEY2=value2\n",
+			expectedKeys:   []string{"KEY1", "KEY2"},
+			expectedValues: []string{"value1", "value2"},
+		},
+		{
+			name:           "Value containing equals and commas",
+			input:          "QUERY=a=1,b=2",
+			expectedKeys:   []string{"QUERY"},
+			expectedValues: []string{"a=1,b=2"},
+		},
+		{
+			name:           "Empty value",
+			input:          "EMPTY=",
+			expectedKeys:   []string{"EMPTY"},
+			expectedValues: []string{""},
+		},
+		{
+			name:           "Heredoc multiline value",
+			input:          "CERT<<EOF\n-----BEGIN-----\n  indented\n-----END-----\nEOF\nNEXT=1",
+			expectedKeys:   []string{"CERT", "NEXT"},
+			expectedValues: []string{"-----BEGIN-----\n  indented\n-----END-----", "1"},
+		},
+		{
+			name:           "Heredoc marker inside a plain value",
+			input:          "CMD=cat <<EOF",
+			expectedKeys:   []string{"CMD"},
+			expectedValues: []string{"cat <<EOF"},
+		},
+		{
+			name:           "Empty heredoc",
+			input:          "EMPTY<<END\nEND",
+			expectedKeys:   []string{"EMPTY"},
+			expectedValues: []string{""},
+		},
+		{
+			name:           "CRLF line endings",
+			input:          "KEY1=a\r\nKEY2=b\r\n",
+			expectedKeys:   []string{"KEY1", "KEY2"},
+			expectedValues: []string{"a", "b"},
+		},
+		{
+			name:      "Missing equals sign",
+			input:     "KEY1=a\nBROKEN",
+			wantError: true,
+			errMsg:    "pairs line 2",
+		},
+		{
+			name:      "Empty key",
+			input:     "=value",
+			wantError: true,
+			errMsg:    "empty key",
+		},
+		{
+			name:      "Unterminated heredoc",
+			input:     "CERT<<EOF\nline",
+			wantError: true,
+			errMsg:    "missing closing delimiter",
+		},
+		{
+			name:      "Heredoc without delimiter",
+			input:     "CERT<<",
+			wantError: true,
+			errMsg:    "expected KEY=value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, values, err := ParsePairs(tt.input)
+
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("ParsePairs() expected error, got keys %v", keys)
+				}
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("ParsePairs() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParsePairs() unexpected error: %v", err)
+			}
+			if strings.Join(keys, "|") != strings.Join(tt.expectedKeys, "|") {
+				t.Errorf("ParsePairs() keys = %q, want %q", keys, tt.expectedKeys)
+			}
+			if len(values) != len(tt.expectedValues) {
+				t.Fatalf("ParsePairs() values = %q, want %q", values, tt.expectedValues)
+			}
+			for i := range values {
+				if values[i] != tt.expectedValues[i] {
+					t.Errorf("ParsePairs() values[%d] = %q, want %q", i, values[i], tt.expectedValues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProcessInputsWithPairs(t *testing.T) {
+	t.Setenv("PAIRS_REGION", "eu-west-1")
+
+	cfg := &config.Config{
+		Delimiter:           ",",
+		EnableInterpolation: true,
+		JsonSupport:         true,
+	}
+	processor := NewProcessor(cfg)
+
+	keys, values, err := processor.ProcessInputs(Inputs{
+		Keys:   "INLINE",
+		Values: "inline_value",
+		Pairs:  "REGION=${PAIRS_REGION}\nCONFIG={\"host\":\"db\"}\nNOTES<<EOF\na\nb\nEOF",
+	})
+	if err != nil {
+		t.Fatalf("ProcessInputs() unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"INLINE":      "inline_value",
+		"REGION":      "eu-west-1",
+		"CONFIG_host": "db",
+		"NOTES":       "a\nb",
+	}
+	got := make(map[string]string)
+	for i, key := range keys {
+		got[key] = values[i]
+	}
+	for key, want := range expected {
+		if got[key] != want {
+			t.Errorf("ProcessInputs()[%s] = %q, want %q", key, got[key], want)
+		}
+	}
+
+	if err := NewValidator(cfg).ValidatePairs(keys, values); err != nil {
+		t.Errorf("ValidatePairs() unexpected error: %v", err)
+	}
+}
+
+func TestGetInputsPairs(t *testing.T) {
+	w := NewWriter(&config.Config{EnvPairs: "A=1", OutputPairs: "B=2"})
+
+	if got := w.getInputs(githubEnvVar).Pairs; got != "A=1" {
+		t.Errorf("getInputs(env).Pairs = %q, want %q", got, "A=1")
+	}
+	if got := w.getInputs(githubOutputVar).Pairs; got != "B=2" {
+		t.Errorf("getInputs(output).Pairs = %q, want %q", got, "B=2")
+	}
+}
@@ -0,0 +1,266 @@
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/filereader"
+	"github.com/somaz94/env-output-setter/internal/printer"
+	"github.com/somaz94/env-output-setter/internal/summary"
+)
+
+// Error messages for path entries
+const (
+	errInvalidPathCheck = "unsupported path_check %q (expected warn, fail or off)"
+	errPathNotFound     = "path entry %q does not exist"
+	errPathNotDirectory = "path entry %q is not a directory"
+)
+
+// Supported path_check modes
+const (
+	PathCheckWarn = "warn"
+	PathCheckFail = "fail"
+	PathCheckOff  = "off"
+)
+
+// pathKey is the key under which path entries are logged and reported.
+const pathKey = "PATH"
+
+// SetPath prepends the path_entries directories to PATH via the GITHUB_PATH file.
+func SetPath(cfg *config.Config) (int, error) {
+	return NewStep(cfg).SetPath()
+}
+
+// setPathEntries runs path_entries through the processing and validation
+// steps and appends the result to the GITHUB_PATH file. Directories are
+// written as given: value transformations do not apply to them. The runner
+// prepends every line to PATH, so entries are written in reverse to give the
+// first listed directory the highest precedence.
+func (w *Writer) setPathEntries() (int, error) {
+	entries, sources, err := w.processor.ProcessPathEntries(w.cfg.PathEntries)
+	if err != nil {
+		return 0, err
+	}
+
+	// Drop duplicates and the directories PATH already holds
+	entries, sources = dedupePathEntries(entries, sources)
+	entries, sources, skipped := skipExistingPathEntries(entries, sources, os.Getenv("PATH"))
+
+	// Register sensitive values with the runner before anything is logged or written
+	w.masker.Register(entries)
+
+	w.processor.LogInputValues(pathFileType, pathKey, w.cfg.PathEntries)
+	if w.cfg.DebugMode {
+		printer.PrintDebugInfo("Processed Path Entries:\n")
+		printer.PrintDebugInfo("  * Entries: %v\n", entries)
+		printer.PrintDebugInfo("  * Already in PATH: %v\n\n", skipped)
+	}
+
+	if err := w.validator.ValidatePathEntries(entries); err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(entries))
+	for i := range keys {
+		keys[i] = pathKey
+	}
+
+	filePath := os.Getenv(githubPathVar)
+	if filePath == "" {
+		count, err := w.handleLocalExecution(githubPathVar, pathFileType, keys, entries)
+		if err == nil {
+			w.recordWritten(summary.DestinationPath, keys, entries, sources)
+		}
+		return count, err
+	}
+
+	count, err := w.writePathFile(filePath, entries)
+	if err != nil {
+		return 0, err
+	}
+	w.recordWritten(summary.DestinationPath, keys, entries, sources)
+	return count, nil
+}
+
+// writePathFile appends entries to the GITHUB_PATH file, one per line in
+// reverse order, with the retries of writeToFile.
+func (w *Writer) writePathFile(filePath string, entries []string) (int, error) {
+	var buf bytes.Buffer
+	for i := len(entries) - 1; i >= 0; i-- {
+		buf.WriteString(entries[i])
+		buf.WriteByte('\n')
+	}
+
+	_, err := withRetries(func() (int, error) {
+		return len(entries), appendPayload(filePath, buf.Bytes())
+	})
+	if err != nil {
+		return 0, writeError(err, maxWriteRetries)
+	}
+	for _, entry := range entries {
+		printer.PrintSuccess(pathFileType, pathKey, w.masker.transformer.MaskValue(entry))
+	}
+	return len(entries), nil
+}
+
+// ProcessPathEntries splits the path_entries input into directories. Entries
+// are separated by newlines only, since directory names may hold the
+// delimiter; file:// references are read (one directory per line) and ${VAR}
+// references are expanded when interpolation is enabled. It also returns the
+// source of each entry.
+func (p *Processor) ProcessPathEntries(input string) ([]string, []string, error) {
+	entries := splitPathEntries(input)
+	sources := p.valueSources(entries)
+
+	// Read file references; a file may list several directories
+	fileReader := filereader.New(p.cfg.FileEncoding)
+	var expanded, expandedSources []string
+	for i, entry := range entries {
+		if !filereader.IsFileReference(entry) {
+			expanded = append(expanded, entry)
+			expandedSources = append(expandedSources, sources[i])
+			continue
+		}
+		content, err := fileReader.ReadValue(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, line := range splitPathEntries(content) {
+			expanded = append(expanded, line)
+			expandedSources = append(expandedSources, sources[i])
+		}
+	}
+	entries, sources = expanded, expandedSources
+
+	// Interpolate variables if enabled
+	if p.cfg.EnableInterpolation {
+		ip, err := p.newInterpolator(nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		interpolated, err := ip.InterpolateList(entries)
+		p.logInterpolation(ip)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range interpolated {
+			if interpolated[i] != entries[i] {
+				sources[i] += ", " + summary.SourceInterpolated
+			}
+		}
+		entries = interpolated
+	}
+
+	return entries, sources, nil
+}
+
+// splitPathEntries splits s on newlines, trimming each entry and dropping
+// blanks.
+func splitPathEntries(s string) []string {
+	var entries []string
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+// dedupePathEntries cleans every entry (filepath.Clean) and keeps only the
+// first occurrence of each directory.
+func dedupePathEntries(entries, sources []string) ([]string, []string) {
+	seen := make(map[string]struct{}, len(entries))
+	var result, resultSources []string
+	for i, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		cleaned := filepath.Clean(entry)
+		if _, ok := seen[cleaned]; ok {
+			continue
+		}
+		seen[cleaned] = struct{}{}
+		result = append(result, cleaned)
+		resultSources = append(resultSources, sources[i])
+	}
+	return result, resultSources
+}
+
+// skipExistingPathEntries drops the entries that are already directories of
+// pathList, the current PATH, and returns them separately. Both sides are
+// compared cleaned (filepath.Clean).
+func skipExistingPathEntries(entries, sources []string, pathList string) ([]string, []string, []string) {
+	existing := make(map[string]struct{})
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir != "" {
+			existing[filepath.Clean(dir)] = struct{}{}
+		}
+	}
+
+	var result, resultSources, skipped []string
+	for i, entry := range entries {
+		if _, ok := existing[filepath.Clean(entry)]; ok {
+			skipped = append(skipped, entry)
+			continue
+		}
+		result = append(result, entry)
+		resultSources = append(resultSources, sources[i])
+	}
+	return result, resultSources, skipped
+}
+
+// pathCheckMode returns the normalized path_check mode, warn if unset.
+func pathCheckMode(mode string) (string, error) {
+	switch normalized := strings.ToLower(mode); normalized {
+	case "":
+		return PathCheckWarn, nil
+	case PathCheckWarn, PathCheckFail, PathCheckOff:
+		return normalized, nil
+	}
+	return "", fmt.Errorf(errInvalidPathCheck, mode)
+}
+
+// ValidatePathEntries checks that every entry is a single-line directory
+// path. Missing directories are reported according to path_check: warn
+// (default) prints a warning, fail returns an error and off skips the check.
+// Relative entries are checked against the working directory.
+func (v *Validator) ValidatePathEntries(entries []string) error {
+	// CheckConfig rejects an invalid path_check first; this guards direct callers
+	mode, err := pathCheckMode(v.cfg.PathCheck)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if strings.ContainsAny(entry, "\r\n") {
+			return fmt.Errorf(errValidationFailed, pathKey, "path entries must not contain newlines")
+		}
+		if mode == PathCheckOff {
+			continue
+		}
+
+		var problem string
+		info, err := os.Stat(entry)
+		switch {
+		case err != nil:
+			problem = fmt.Sprintf(errPathNotFound, entry)
+		case !info.IsDir():
+			problem = fmt.Sprintf(errPathNotDirectory, entry)
+		default:
+			continue
+		}
+
+		if mode == PathCheckFail {
+			return fmt.Errorf("%s", problem)
+		}
+		printer.PrintWarning("Warning: " + problem)
+	}
+	return nil
+}
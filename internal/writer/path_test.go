@@ -0,0 +1,280 @@
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/summary"
+)
+
+func TestProcessPathEntries(t *testing.T) {
+	dir := t.TempDir()
+	listFile := filepath.Join(dir, "paths.txt")
+	if err := os.WriteFile(listFile, []byte("/opt/a\n\n/opt/b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOOL_HOME", "/opt/tool")
+
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		input    string
+		expected []string
+		sources  []string
+		errPart  string
+	}{
+		{
+			name:     "Newlines only",
+			cfg:      &config.Config{Delimiter: ","},
+			input:    " /usr/local/bin \n./bin\n\n/opt/tools,v2  \n",
+			expected: []string{"/usr/local/bin", "./bin", "/opt/tools,v2"},
+			sources:  []string{"literal", "literal", "literal"},
+		},
+		{
+			name:     "File reference lists several directories",
+			cfg:      &config.Config{Delimiter: ","},
+			input:    "/first\nfile://" + listFile,
+			expected: []string{"/first", "/opt/a", "/opt/b"},
+			sources:  []string{"literal", "file://", "file://"},
+		},
+		{
+			name:     "Interpolation",
+			cfg:      &config.Config{Delimiter: ",", EnableInterpolation: true},
+			input:    "${TOOL_HOME}/bin",
+			expected: []string{"/opt/tool/bin"},
+			sources:  []string{"literal, interpolated"},
+		},
+		{
+			name:    "Missing file",
+			cfg:     &config.Config{Delimiter: ","},
+			input:   "file://" + filepath.Join(dir, "missing.txt"),
+			errPart: "missing.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, sources, err := NewProcessor(tt.cfg).ProcessPathEntries(tt.input)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Fatalf("ProcessPathEntries() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessPathEntries() unexpected error: %v", err)
+			}
+			if strings.Join(entries, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("ProcessPathEntries() entries = %v, want %v", entries, tt.expected)
+			}
+			if strings.Join(sources, "|") != strings.Join(tt.sources, "|") {
+				t.Errorf("ProcessPathEntries() sources = %v, want %v", sources, tt.sources)
+			}
+		})
+	}
+}
+
+func TestDedupePathEntries(t *testing.T) {
+	entries, sources := dedupePathEntries(
+		[]string{"/opt/bin/", "./bin", "/opt//bin", "bin", "/usr/bin"},
+		[]string{"a", "b", "c", "d", "e"},
+	)
+	if strings.Join(entries, "|") != "/opt/bin|bin|/usr/bin" {
+		t.Errorf("dedupePathEntries() entries = %v", entries)
+	}
+	if strings.Join(sources, "|") != "a|b|e" {
+		t.Errorf("dedupePathEntries() sources = %v", sources)
+	}
+}
+
+func TestSkipExistingPathEntries(t *testing.T) {
+	pathList := strings.Join([]string{"/usr/bin", "/opt/tool/bin/", ""}, string(os.PathListSeparator))
+	entries, sources, skipped := skipExistingPathEntries(
+		[]string{"/opt/tool/bin", "/usr/local/bin", "/usr/bin"},
+		[]string{"a", "b", "c"},
+		pathList,
+	)
+	if strings.Join(entries, "|") != "/usr/local/bin" {
+		t.Errorf("skipExistingPathEntries() entries = %v", entries)
+	}
+	if strings.Join(sources, "|") != "b" {
+		t.Errorf("skipExistingPathEntries() sources = %v", sources)
+	}
+	if strings.Join(skipped, "|") != "/opt/tool/bin|/usr/bin" {
+		t.Errorf("skipExistingPathEntries() skipped = %v", skipped)
+	}
+}
+
+func TestValidatePathEntries(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tool")
+	if err := os.WriteFile(file, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name    string
+		check   string
+		entries []string
+		errPart string
+		warning string
+	}{
+		{name: "Existing directory", check: "fail", entries: []string{dir}},
+		{name: "Default mode warns", check: "", entries: []string{missing}, warning: "does not exist"},
+		{name: "Warn mode", check: "warn", entries: []string{file}, warning: "is not a directory"},
+		{name: "Fail mode missing", check: "fail", entries: []string{dir, missing}, errPart: "does not exist"},
+		{name: "Fail mode file", check: "FAIL", entries: []string{file}, errPart: "is not a directory"},
+		{name: "Off mode", check: "off", entries: []string{missing}},
+		{name: "Invalid mode", check: "strict", entries: []string{dir}, errPart: `unsupported path_check "strict"`},
+		{name: "Newline in entry", check: "off", entries: []string{"/opt\n/bin"}, errPart: "must not contain newlines"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			out := captureStdout(t, func() {
+				err = NewValidator(&config.Config{PathCheck: tt.check}).ValidatePathEntries(tt.entries)
+			})
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Fatalf("ValidatePathEntries() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidatePathEntries() unexpected error: %v", err)
+			}
+			if tt.warning != "" && !strings.Contains(out, tt.warning) {
+				t.Errorf("ValidatePathEntries() output %q, want warning containing %q", out, tt.warning)
+			}
+			if tt.warning == "" && strings.Contains(out, "Warning") {
+				t.Errorf("ValidatePathEntries() unexpected warning: %q", out)
+			}
+		})
+	}
+}
+
+func TestSetPathWithFile(t *testing.T) {
+	dir := t.TempDir()
+	pathFile := filepath.Join(dir, "github_path")
+	if err := os.WriteFile(pathFile, []byte("/existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(githubPathVar, pathFile)
+
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	for _, d := range []string{first, second} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		PathEntries: first + "\n" + second + "\n" + first + "/",
+		PathCheck:   "fail",
+		Delimiter:   ",",
+	}
+	report := summary.NewReport()
+	step := NewStep(cfg)
+	step.Record(report, nil)
+	count, err := step.SetPath()
+	if err != nil {
+		t.Fatalf("SetPath() unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("SetPath() count = %d, want 2", count)
+	}
+
+	content, err := os.ReadFile(pathFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Entries are reversed so the first one ends up first on PATH
+	want := "/existing\n" + second + "\n" + first + "\n"
+	if string(content) != want {
+		t.Errorf("SetPath() file content = %q, want %q", content, want)
+	}
+
+	entries := report.Entries()
+	if len(entries) != 2 || entries[0].Key != "PATH" || entries[0].Destination != summary.DestinationPath || entries[0].Value != first {
+		t.Errorf("SetPath() report entries = %+v", entries)
+	}
+}
+
+func TestSetPathWritesEntriesAsGiven(t *testing.T) {
+	dir := t.TempDir()
+	pathFile := filepath.Join(dir, "github_path")
+	t.Setenv(githubPathVar, pathFile)
+	t.Setenv("PATH", "/usr/bin"+string(os.PathListSeparator)+"/already/there")
+
+	cfg := &config.Config{
+		PathEntries: "/opt/Tools,v2/bin\n/usr/bin/\n/already/there\n/opt/other",
+		PathCheck:   "off",
+		Delimiter:   ",",
+		ToUpper:     true,
+		MaxLength:   5,
+	}
+	count, err := SetPath(cfg)
+	if err != nil {
+		t.Fatalf("SetPath() unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("SetPath() count = %d, want 2", count)
+	}
+
+	content, err := os.ReadFile(pathFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/opt/other\n/opt/Tools,v2/bin\n"; string(content) != want {
+		t.Errorf("SetPath() file content = %q, want %q", content, want)
+	}
+}
+
+func TestSetPathErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(githubPathVar, filepath.Join(dir, "github_path"))
+
+	t.Run("Empty input", func(t *testing.T) {
+		count, err := SetPath(&config.Config{Delimiter: ","})
+		if err != nil || count != 0 {
+			t.Errorf("SetPath() = %d, %v, want 0, nil", count, err)
+		}
+	})
+
+	t.Run("Missing directory with fail check", func(t *testing.T) {
+		_, err := SetPath(&config.Config{PathEntries: filepath.Join(dir, "missing"), PathCheck: "fail", Delimiter: ","})
+		if err == nil || !strings.Contains(err.Error(), "does not exist") {
+			t.Errorf("SetPath() error = %v, want missing directory error", err)
+		}
+	})
+
+	t.Run("Unwritable path file", func(t *testing.T) {
+		t.Setenv(githubPathVar, filepath.Join(dir, "missing", "github_path"))
+		_, err := SetPath(&config.Config{PathEntries: dir, PathCheck: "off", Delimiter: ","})
+		if err == nil || !strings.Contains(err.Error(), "failed to write after 3 retries") {
+			t.Errorf("SetPath() error = %v, want retry error", err)
+		}
+	})
+}
+
+func TestSetPathLocalExecution(t *testing.T) {
+	t.Setenv(githubPathVar, "")
+
+	var count int
+	var err error
+	out := captureStdout(t, func() {
+		count, err = SetPath(&config.Config{PathEntries: "/opt/bin", PathCheck: "off", Delimiter: ","})
+	})
+	if err != nil || count != 1 {
+		t.Fatalf("SetPath() = %d, %v, want 1, nil", count, err)
+	}
+	if !strings.Contains(out, "/opt/bin") {
+		t.Errorf("SetPath() local output %q missing entry", out)
+	}
+}
@@ -1,17 +1,39 @@
 package writer
 
 import (
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/dotenv"
 	"github.com/somaz94/env-output-setter/internal/filereader"
 	"github.com/somaz94/env-output-setter/internal/interpolator"
+	"github.com/somaz94/env-output-setter/internal/jsonschema"
+	"github.com/somaz94/env-output-setter/internal/keyname"
 	"github.com/somaz94/env-output-setter/internal/printer"
+	"github.com/somaz94/env-output-setter/internal/render"
+	"github.com/somaz94/env-output-setter/internal/structured"
+	"github.com/somaz94/env-output-setter/internal/summary"
+)
+
+// Error messages for structured value options
+const (
+	errInvalidStructuredFormat = "unsupported structured_format %q (expected json, yaml, toml or auto)"
+	errInvalidKeyOrder         = "unsupported json_key_order %q (expected source or sorted)"
+	errInvalidKeyStyle         = "unsupported json_key_style %q (expected preserve, sanitize, upper_snake or lower_snake)"
+	errInvalidMaxDepth         = "json_max_depth must not be negative, got %d"
+	errInvalidNullValue        = "unsupported json_null_value %q (expected empty, null or skip)"
+	errInvalidSeparator        = "invalid json_separator %q (expected letters, digits and underscores)"
+	errInterpolationAllow      = "interpolation_allow: %v"
+	errInterpolationDeny       = "interpolation_deny: %v"
 )
 
 // Processor handles input processing and transformation.
 type Processor struct {
-	cfg *config.Config
+	cfg     *config.Config
+	masker  *Masker                       // Optional; registers the leaves of masked structured values before they are flattened
+	schemas map[string]*jsonschema.Schema // Compiled json_schema; compiled from the input when nil
 }
 
 // NewProcessor creates a new Processor instance.
@@ -19,16 +41,50 @@ func NewProcessor(cfg *config.Config) *Processor {
 	return &Processor{cfg: cfg}
 }
 
+// Inputs groups the raw action inputs that feed a single destination.
+type Inputs struct {
+	Keys    string // Delimiter-separated key list (env_key / output_key)
+	Values  string // Delimiter-separated value list (env_value / output_value)
+	Pairs   string // KEY=value lines (env_pairs / output_pairs)
+	EnvFile string // Optional dotenv file whose entries are merged in
+
+	// Variables are extra ${VAR} values for interpolation, such as the env
+	// keys set by the same step when building outputs
+	Variables map[string]string
+}
+
 // ProcessInputValues processes the input strings into lists with proper formatting.
 // It handles splitting, trimming, and processing JSON values if json_support is enabled.
 func (p *Processor) ProcessInputValues(keys, values string) ([]string, []string, error) {
+	return p.ProcessInputs(Inputs{Keys: keys, Values: values})
+}
+
+// ProcessInputs processes all inputs for one destination. Entries from the
+// dotenv file come first, followed by the delimiter-separated key/value lists
+// and then the KEY=value pairs; all of them share the json_select, JSON and
+// group prefix handling.
+func (p *Processor) ProcessInputs(in Inputs) ([]string, []string, error) {
+	keyList, valueList, _, err := p.ProcessInputsWithSources(in)
+	return keyList, valueList, err
+}
+
+// ProcessInputsWithSources is ProcessInputs that additionally returns, per
+// key, where its value came from (see the summary package's Source constants),
+// e.g. "file://, interpolated" or "JSON-flattened".
+func (p *Processor) ProcessInputsWithSources(in Inputs) ([]string, []string, []string, error) {
+	// Parse json_select up front so syntax errors surface before any file is read
+	selections, err := ParseSelections(p.cfg.JsonSelect)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Split input strings by delimiter (JSON-aware if json_support is enabled)
-	keyList := strings.Split(keys, p.cfg.Delimiter)
+	keyList := strings.Split(in.Keys, p.cfg.Delimiter)
 	var valueList []string
 	if p.cfg.JsonSupport {
-		valueList = p.splitJSONAware(values, p.cfg.Delimiter)
+		valueList = p.splitJSONAware(in.Values, p.cfg.Delimiter)
 	} else {
-		valueList = strings.Split(values, p.cfg.Delimiter)
+		valueList = strings.Split(in.Values, p.cfg.Delimiter)
 	}
 
 	// Process keys and values for whitespace
@@ -39,26 +95,158 @@ func (p *Processor) ProcessInputValues(keys, values string) ([]string, []string,
 	keyList = p.removeEmptyEntries(keyList)
 	valueList = p.removeEmptyEntries(valueList)
 
+	// Append KEY=value pairs after whitespace normalization so heredoc values
+	// keep their newlines, but before file reading and interpolation so those
+	// features apply to pairs too.
+	if strings.TrimSpace(in.Pairs) != "" {
+		pairKeys, pairValues, err := ParsePairs(in.Pairs)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		keyList = append(keyList, pairKeys...)
+		valueList = append(valueList, pairValues...)
+	}
+
+	// Remember which structured format each file reference implies
+	// (file://values.yaml -> yaml) before the references are replaced by content
+	formatHints := p.structuredFormatHints(valueList)
+	sources := p.valueSources(valueList)
+
 	// Read values from files if any use file:// references
 	fileReader := filereader.New(p.cfg.FileEncoding)
-	valueList, err := fileReader.ReadValues(valueList)
+	valueList, err = fileReader.ReadValues(valueList)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	// Parse the dotenv file before interpolation so values can refer to its keys
+	var envEntries []dotenv.Entry
+	if in.EnvFile != "" {
+		opts, err := p.dotenvOptions()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if envEntries, err = dotenv.ParseFile(in.EnvFile, opts); err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
-	// Interpolate variables if enabled
+	// Interpolate variables if enabled. ${VAR} may name another key being
+	// set, a dotenv entry, one of in.Variables or an environment variable,
+	// in that order.
 	if p.cfg.EnableInterpolation {
-		ip := interpolator.New()
-		valueList, err = ip.InterpolateList(valueList)
+		ip, err := p.newInterpolator(interpolationVariables(envEntries, in.Variables))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		original := valueList
+		valueList, err = ip.InterpolatePairs(keyList, valueList)
+		p.logInterpolation(ip)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for i := range valueList {
+			if i < len(original) && i < len(sources) && valueList[i] != original[i] {
+				sources[i] += ", " + summary.SourceInterpolated
+			}
+		}
+	}
+
+	// Merge dotenv entries. They are added after file reading and interpolation
+	// because the dotenv parser already performs its own ${VAR} expansion, and
+	// their whitespace (including multiline values) is preserved as written.
+	if in.EnvFile != "" {
+		inlineCount := len(valueList)
+		keyList, valueList = mergeEnvEntries(envEntries, keyList, valueList)
+		formatHints = append(make([]string, len(valueList)-inlineCount), formatHints...)
+		envFileSources := make([]string, len(valueList)-inlineCount)
+		for i := range envFileSources {
+			envFileSources[i] = summary.SourceEnvFile
+		}
+		sources = append(envFileSources, sources...)
+	}
+
+	// Render {{ }} templates once every inline value is read and interpolated.
+	// Templates can refer to the dotenv entries, which are not rendered
+	// themselves.
+	if p.cfg.EnableTemplates {
+		templates := make([]bool, len(valueList))
+		for i := range templates {
+			templates[i] = i >= len(sources) || sources[i] != summary.SourceEnvFile
+		}
+		renderer, err := p.newRenderer()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		original := valueList
+		valueList, err = renderer.Render(keyList, valueList, templates)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for i := range valueList {
+			if i < len(sources) && valueList[i] != original[i] {
+				sources[i] += ", " + summary.SourceTemplated
+			}
+		}
+	}
+
+	// Check documents against json_schema before anything is selected from
+	// or flattened out of them
+	if strings.TrimSpace(p.cfg.JsonSchema) != "" {
+		if err := p.validateSchemas(keyList, valueList, formatHints); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// Replace json_select sources with the fields selected from them, before
+	// flattening so only the selected fields are expanded
+	if len(selections) > 0 {
+		known := sourcesByKey(keyList, sources)
+		keyList, valueList, formatHints, err = p.applySelections(selections, keyList, valueList, formatHints)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
+		sources = resolveSources(keyList, known, summary.SourceSelected)
 	}
 
-	// Process JSON values if enabled
+	// Process structured (JSON/YAML/TOML) values if enabled
 	if p.cfg.JsonSupport {
-		jsonHandler := NewJSONHandler()
-		keyList, valueList = jsonHandler.ProcessJSONValues(keyList, valueList)
+		if p.cfg.StructuredFormat != "" && !structured.IsValidFormat(p.cfg.StructuredFormat) {
+			return nil, nil, nil, fmt.Errorf(errInvalidStructuredFormat, p.cfg.StructuredFormat)
+		}
+		if order := strings.ToLower(p.cfg.JsonKeyOrder); order != "" && order != KeyOrderSource && order != KeyOrderSorted {
+			return nil, nil, nil, fmt.Errorf(errInvalidKeyOrder, p.cfg.JsonKeyOrder)
+		}
+		if p.cfg.JsonKeyStyle != "" && !keyname.IsValidStyle(p.cfg.JsonKeyStyle) {
+			return nil, nil, nil, fmt.Errorf(errInvalidKeyStyle, p.cfg.JsonKeyStyle)
+		}
+		switch strings.ToLower(p.cfg.JsonNullValue) {
+		case "", NullAsEmpty, NullAsLiteral, NullSkip:
+		default:
+			return nil, nil, nil, fmt.Errorf(errInvalidNullValue, p.cfg.JsonNullValue)
+		}
+		if p.cfg.JsonMaxDepth < 0 {
+			return nil, nil, nil, fmt.Errorf(errInvalidMaxDepth, p.cfg.JsonMaxDepth)
+		}
+		if !IsValidSeparator(p.cfg.JsonSeparator) {
+			return nil, nil, nil, fmt.Errorf(errInvalidSeparator, p.cfg.JsonSeparator)
+		}
+		jsonHandler := NewJSONHandlerWithOptions(JSONOptions{
+			Format:      p.cfg.StructuredFormat,
+			KeyOrder:    p.cfg.JsonKeyOrder,
+			Separator:   p.cfg.JsonSeparator,
+			MaxDepth:    p.cfg.JsonMaxDepth,
+			LeafOnly:    p.cfg.JsonLeafOnly,
+			ArrayLength: p.cfg.JsonArrayLength,
+			KeyStyle:    p.cfg.JsonKeyStyle,
+			NullValue:   p.cfg.JsonNullValue,
+		})
+		if p.masker != nil {
+			p.masker.RegisterStructured(valueList, formatHints)
+		}
+		known := sourcesByKey(keyList, sources)
+		keyList, valueList = jsonHandler.ProcessStructuredValues(keyList, valueList, formatHints)
+		sources = resolveSources(keyList, known, summary.SourceFlattened)
 	}
 
 	// Prepend the group prefix to every generated key name (including
@@ -67,7 +255,173 @@ func (p *Processor) ProcessInputValues(keys, values string) ([]string, []string,
 		keyList = p.applyGroupPrefix(keyList)
 	}
 
-	return keyList, valueList, nil
+	return keyList, valueList, sources, nil
+}
+
+// valueSources returns the initial source of each value: file:// for file
+// references and literal for everything else.
+func (p *Processor) valueSources(values []string) []string {
+	sources := make([]string, len(values))
+	for i, value := range values {
+		if filereader.IsFileReference(value) {
+			sources[i] = summary.SourceFile
+		} else {
+			sources[i] = summary.SourceLiteral
+		}
+	}
+	return sources
+}
+
+// sourcesByKey maps each key to its recorded source; the first occurrence of
+// a repeated key wins.
+func sourcesByKey(keys, sources []string) map[string]string {
+	known := make(map[string]string, len(keys))
+	for i, key := range keys {
+		if _, ok := known[key]; ok || i >= len(sources) {
+			continue
+		}
+		known[key] = sources[i]
+	}
+	return known
+}
+
+// resolveSources returns the source of each key after a step that adds keys:
+// keys that existed before keep their source, new keys get fallback.
+func resolveSources(keys []string, known map[string]string, fallback string) []string {
+	sources := make([]string, len(keys))
+	for i, key := range keys {
+		if source, ok := known[key]; ok {
+			sources[i] = source
+		} else {
+			sources[i] = fallback
+		}
+	}
+	return sources
+}
+
+// structuredFormatHints returns, per value, the structured format implied by
+// the extension of a file:// reference ("" for inline values and unknown
+// extensions).
+func (p *Processor) structuredFormatHints(values []string) []string {
+	hints := make([]string, len(values))
+	for i, value := range values {
+		if filereader.IsFileReference(value) {
+			hints[i] = structured.FormatFromPath(filereader.GetFilePath(value))
+		}
+	}
+	return hints
+}
+
+// mergeEnvEntries prepends the dotenv entries, in file order, to the
+// key/value lists.
+func mergeEnvEntries(entries []dotenv.Entry, keyList, valueList []string) ([]string, []string) {
+	keys := make([]string, 0, len(entries)+len(keyList))
+	values := make([]string, 0, len(entries)+len(valueList))
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+		values = append(values, entry.Value)
+	}
+	return append(keys, keyList...), append(values, valueList...)
+}
+
+// newInterpolator creates an Interpolator that resolves variables, then the
+// environment, following interpolation_strict, interpolation_allow and
+// interpolation_deny.
+func (p *Processor) newInterpolator(variables map[string]string) (*interpolator.Interpolator, error) {
+	allow, deny, err := p.interpolationPatterns()
+	if err != nil {
+		return nil, err
+	}
+	return interpolator.NewWithOptions(interpolator.Options{
+		Variables: variables,
+		Strict:    p.cfg.InterpolationStrict,
+		Allow:     allow,
+		Deny:      deny,
+	}), nil
+}
+
+// newRenderer creates the template renderer. Its env and env_or functions
+// read the environment under the same allow and deny lists as ${VAR}
+// references, so templates cannot read what interpolation blocks.
+func (p *Processor) newRenderer() (*render.Renderer, error) {
+	allow, deny, err := p.interpolationPatterns()
+	if err != nil {
+		return nil, err
+	}
+	return render.NewWithLookup(func(name string) (string, bool, error) {
+		if err := interpolator.CheckAllowed(name, allow, deny); err != nil {
+			return "", false, err
+		}
+		value, ok := os.LookupEnv(name)
+		return value, ok, nil
+	}), nil
+}
+
+// dotenvOptions returns the env_file parser options: ${VAR} references are
+// expanded only with enable_interpolation, under the same strict mode and
+// allow and deny lists as every other reference.
+func (p *Processor) dotenvOptions() (dotenv.Options, error) {
+	if !p.cfg.EnableInterpolation {
+		return dotenv.Options{}, nil
+	}
+	allow, deny, err := p.interpolationPatterns()
+	if err != nil {
+		return dotenv.Options{}, err
+	}
+	return dotenv.Options{
+		Expand: true,
+		Strict: p.cfg.InterpolationStrict,
+		Allow:  allow,
+		Deny:   deny,
+	}, nil
+}
+
+// interpolationPatterns parses interpolation_allow and interpolation_deny.
+func (p *Processor) interpolationPatterns() ([]string, []string, error) {
+	allow, err := interpolator.ParseNamePatterns(p.cfg.InterpolationAllow)
+	if err != nil {
+		return nil, nil, fmt.Errorf(errInterpolationAllow, err)
+	}
+	deny, err := interpolator.ParseNamePatterns(p.cfg.InterpolationDeny)
+	if err != nil {
+		return nil, nil, fmt.Errorf(errInterpolationDeny, err)
+	}
+	return allow, deny, nil
+}
+
+// logInterpolation prints, in debug mode, every variable ip resolved, where
+// its value came from and which variables were not set.
+func (p *Processor) logInterpolation(ip *interpolator.Interpolator) {
+	resolutions := ip.Resolutions()
+	if !p.cfg.DebugMode || len(resolutions) == 0 {
+		return
+	}
+
+	printer.PrintDebugInfo("Interpolation:\n")
+	for _, r := range resolutions {
+		if r.Key != "" {
+			printer.PrintDebugInfo("  * %s: ${%s} <- %s\n", r.Key, r.Name, r.Source)
+		} else {
+			printer.PrintDebugInfo("  * ${%s} <- %s\n", r.Name, r.Source)
+		}
+	}
+	if undefined := ip.Undefined(); len(undefined) > 0 {
+		printer.PrintDebugInfo("  * Undefined: %s\n", strings.Join(undefined, ", "))
+	}
+	printer.PrintDebugInfo("\n")
+}
+
+// interpolationVariables merges the dotenv entries (the first entry of a key
+// wins) over variables.
+func interpolationVariables(entries []dotenv.Entry, variables map[string]string) map[string]string {
+	merged := make(map[string]string, len(entries)+len(variables))
+	for name, value := range variables {
+		merged[name] = value
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		merged[entries[i].Key] = entries[i].Value
+	}
+	return merged
 }
 
 // applyGroupPrefix prepends the configured group prefix and an underscore
@@ -154,6 +508,29 @@ func (p *Processor) splitJSONAware(s, delimiter string) []string {
 	return result
 }
 
+// inputKeys returns the keys in sets before any value is read or processed:
+// the key list, the keys of the KEY=value pairs and those of the dotenv file.
+func (p *Processor) inputKeys(in Inputs) ([]string, error) {
+	keys := p.removeEmptyEntries(p.processWhitespace(strings.Split(in.Keys, p.cfg.Delimiter)))
+	if strings.TrimSpace(in.Pairs) != "" {
+		pairKeys, _, err := ParsePairs(in.Pairs)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pairKeys...)
+	}
+	if in.EnvFile != "" {
+		entries, err := dotenv.ParseFile(in.EnvFile, dotenv.Options{})
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			keys = append(keys, entry.Key)
+		}
+	}
+	return keys, nil
+}
+
 // processWhitespace normalizes and trims whitespace from all entries in a list.
 func (p *Processor) processWhitespace(entries []string) []string {
 	result := make([]string, len(entries))
@@ -198,7 +575,11 @@ func (p *Processor) LogInputValues(varType, keys, values string) {
 	printer.PrintDebugInfo("Input Values:\n")
 	printer.PrintDebugInfo("  * Keys:      %q\n", keys)
 	printer.PrintDebugInfo("  * Values:    %q\n", values)
-	printer.PrintDebugInfo("  * Delimiter: %q\n\n", p.cfg.Delimiter)
+	printer.PrintDebugInfo("  * Delimiter: %q\n", p.cfg.Delimiter)
+	if p.cfg.EnvFile != "" && varType == envFileType {
+		printer.PrintDebugInfo("  * Env File:  %q\n", p.cfg.EnvFile)
+	}
+	printer.PrintDebugInfo("\n")
 }
 
 // titleCase upper-cases the first ASCII byte of s and returns the rest unchanged.
@@ -219,4 +600,23 @@ func (p *Processor) LogProcessedValues(keyList, valueList []string) {
 	printer.PrintDebugInfo("Processed Values:\n")
 	printer.PrintDebugInfo("  * Keys:   %v\n", keyList)
 	printer.PrintDebugInfo("  * Values: %v\n\n", valueList)
+
+	// Show which validation rule applies to each key; invalid rules are
+	// reported by the validator instead
+	if p.cfg.ValidationRules == "" {
+		return
+	}
+	rules, err := ParseRuleSet(p.cfg.ValidationRules)
+	if err != nil {
+		return
+	}
+	printer.PrintDebugInfo("Validation Rules:\n")
+	for _, key := range keyList {
+		if _, ruleKey, kind, ok := rules.Match(key); ok {
+			printer.PrintDebugInfo("  * %s <- %q (%s)\n", key, ruleKey, kind)
+		} else {
+			printer.PrintDebugInfo("  * %s: no rule\n", key)
+		}
+	}
+	printer.PrintDebugInfo("\n")
 }
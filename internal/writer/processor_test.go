@@ -3,6 +3,7 @@ package writer
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -538,6 +539,240 @@ func TestProcessInputValuesWithInterpolation(t *testing.T) {
 	})
 }
 
+func TestProcessInputsWithTemplates(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("REGISTRY=ghcr.io/acme\nRAW={{ not rendered }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEMPLATE_TEST_SHA", "0123456789abcdef")
+
+	t.Run("Templates enabled", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableTemplates: true, EnableInterpolation: true}
+		keys, values, sources, err := NewProcessor(cfg).ProcessInputsWithSources(Inputs{
+			Keys:    "REF,IMAGE,TAG",
+			Values:  "{{ .REGISTRY }}/{{ .IMAGE | lower }}:{{ .TAG | trunc 7 }},Web,${TEMPLATE_TEST_SHA}",
+			EnvFile: envFile,
+		})
+		if err != nil {
+			t.Fatalf("ProcessInputsWithSources() unexpected error: %v", err)
+		}
+
+		expected := []string{"ghcr.io/acme", "{{ not rendered }}", "ghcr.io/acme/web:0123456", "Web", "0123456789abcdef"}
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("values = %q, want %q", values, expected)
+		}
+		if keys[2] != "REF" || sources[2] != "literal, templated" || sources[4] != "literal, interpolated" {
+			t.Errorf("keys = %v, sources = %q", keys, sources)
+		}
+	})
+
+	t.Run("Templates disabled", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ","}
+		_, values, err := NewProcessor(cfg).ProcessInputValues("REF", "{{ .IMAGE }}")
+		if err != nil || values[0] != "{{ .IMAGE }}" {
+			t.Errorf("ProcessInputValues() = %q, %v, want the raw value", values, err)
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableTemplates: true}
+		_, _, err := NewProcessor(cfg).ProcessInputValues("A,B", "{{ .B }},{{ .A }}")
+		if err == nil || !strings.Contains(err.Error(), "cycle: A -> B -> A") {
+			t.Errorf("ProcessInputValues() error = %v, want the cycle", err)
+		}
+	})
+
+	t.Run("Environment policy", func(t *testing.T) {
+		t.Setenv("ACTIONS_RUNTIME_TOKEN", "supersecret")
+		t.Setenv("TEMPLATE_TEST_SECRET", "hidden")
+		tests := []struct {
+			name    string
+			deny    string
+			allow   string
+			value   string
+			errPart string
+		}{
+			{"env default denylist", "", "", `{{ env "ACTIONS_RUNTIME_TOKEN" }}`, "variable ACTIONS_RUNTIME_TOKEN is blocked by the interpolation denylist"},
+			{"env_or default denylist", "", "", `{{ env_or "ACTIONS_RUNTIME_TOKEN" "x" }}`, "variable ACTIONS_RUNTIME_TOKEN is blocked by the interpolation denylist"},
+			{"env interpolation_deny", "TEMPLATE_TEST_*", "", `{{ env "TEMPLATE_TEST_SECRET" }}`, "variable TEMPLATE_TEST_SECRET is blocked by the interpolation denylist (pattern TEMPLATE_TEST_*)"},
+			{"env_or interpolation_allow", "", "GITHUB_*", `{{ env_or "TEMPLATE_TEST_SECRET" "x" }}`, "variable TEMPLATE_TEST_SECRET is not in the interpolation allowlist"},
+			{"env allowed", "", "TEMPLATE_TEST_*", `{{ env "TEMPLATE_TEST_SECRET" }}`, ""},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				cfg := &config.Config{Delimiter: ",", EnableTemplates: true, InterpolationAllow: tt.allow, InterpolationDeny: tt.deny}
+				_, values, err := NewProcessor(cfg).ProcessInputValues("A", tt.value)
+				if tt.errPart == "" {
+					if err != nil || values[0] != "hidden" {
+						t.Errorf("ProcessInputValues() = %q, %v, want \"hidden\"", values, err)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("ProcessInputValues() = %q, %v, want containing %q", values, err, tt.errPart)
+				}
+			})
+		}
+	})
+}
+
+func TestProcessInputsCrossKeyInterpolation(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("DOMAIN=example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CROSS_KEY_TAG", "v1")
+
+	t.Run("Keys, dotenv entries, variables and environment", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableInterpolation: true}
+		_, values, err := NewProcessor(cfg).ProcessInputs(Inputs{
+			Keys:      "IMAGE,REGISTRY",
+			Values:    "${REGISTRY}/app:${CROSS_KEY_TAG},registry.${DOMAIN}/${TEAM}",
+			Pairs:     "URL=https://${REGISTRY}",
+			EnvFile:   envFile,
+			Variables: map[string]string{"TEAM": "web", "DOMAIN": "ignored.example"},
+		})
+		if err != nil {
+			t.Fatalf("ProcessInputs() unexpected error: %v", err)
+		}
+		expected := []string{"example.com", "registry.example.com/web/app:v1", "registry.example.com/web", "https://registry.example.com/web"}
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("values = %q, want %q", values, expected)
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableInterpolation: true}
+		_, _, err := NewProcessor(cfg).ProcessInputValues("A,B", "${B},${A}")
+		if err == nil || !strings.Contains(err.Error(), "variables refer to each other in a cycle: A -> B -> A") {
+			t.Errorf("ProcessInputValues() error = %v, want the cycle", err)
+		}
+	})
+}
+
+func TestProcessInputsStrictInterpolation(t *testing.T) {
+	t.Setenv("STRICT_TAG", "v1")
+
+	t.Run("Undefined variable fails", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableInterpolation: true, InterpolationStrict: true}
+		_, _, err := NewProcessor(cfg).ProcessInputValues("IMAGE", "app:${STRICT_TGA}")
+		if err == nil || !strings.Contains(err.Error(), "interpolation failed for IMAGE: variable STRICT_TGA is not set and has no default") {
+			t.Errorf("ProcessInputValues() error = %v, want the undefined variable", err)
+		}
+	})
+
+	t.Run("Defaults and keys pass", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableInterpolation: true, InterpolationStrict: true}
+		_, values, err := NewProcessor(cfg).ProcessInputValues("IMAGE,NAME", "${NAME}:${STRICT_TAG}-${STRICT_SUFFIX:-x},app")
+		if err != nil {
+			t.Fatalf("ProcessInputValues() unexpected error: %v", err)
+		}
+		if values[0] != "app:v1-x" {
+			t.Errorf("values[0] = %q, want %q", values[0], "app:v1-x")
+		}
+	})
+
+	t.Run("Debug report", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableInterpolation: true, DebugMode: true}
+		output := captureStdout(t, func() {
+			if _, _, err := NewProcessor(cfg).ProcessInputValues("IMAGE,NAME", "${NAME}:${STRICT_TAG}-${STRICT_TGA}${STRICT_SUFFIX:-x},app"); err != nil {
+				t.Errorf("ProcessInputValues() unexpected error: %v", err)
+			}
+		})
+		for _, want := range []string{
+			"Interpolation:",
+			"IMAGE: ${NAME} <- key",
+			"IMAGE: ${STRICT_TAG} <- env",
+			"IMAGE: ${STRICT_TGA} <- undefined",
+			"IMAGE: ${STRICT_SUFFIX} <- default",
+			"Undefined: STRICT_TGA",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("debug output missing %q:\n%s", want, output)
+			}
+		}
+	})
+}
+
+func TestProcessInputsInterpolationPolicy(t *testing.T) {
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", "runner-secret")
+	t.Setenv("POLICY_SECRET", "secret")
+	t.Setenv("GITHUB_REF_NAME", "main")
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("DOMAIN=example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		values  string
+		errPart string
+	}{
+		{name: "Default denylist", values: "${ACTIONS_RUNTIME_TOKEN:-x}", errPart: "interpolation failed for A: variable ACTIONS_RUNTIME_TOKEN is blocked by the interpolation denylist (default pattern ACTIONS_RUNTIME_*)"},
+		{name: "Deny", cfg: config.Config{InterpolationDeny: "*_SECRET"}, values: "$POLICY_SECRET", errPart: "variable POLICY_SECRET is blocked by the interpolation denylist (pattern *_SECRET)"},
+		{name: "Allow", cfg: config.Config{InterpolationAllow: "GITHUB_*"}, values: "$POLICY_SECRET", errPart: "variable POLICY_SECRET is not in the interpolation allowlist"},
+		{name: "Allowed", cfg: config.Config{InterpolationAllow: "GITHUB_*"}, values: "${GITHUB_REF_NAME}.${DOMAIN}-${B}"},
+		{name: "Invalid pattern", cfg: config.Config{InterpolationDeny: "[A"}, values: "x", errPart: `interpolation_deny: invalid variable pattern "[A"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Delimiter = "|"
+			cfg.EnableInterpolation = true
+			_, values, err := NewProcessor(&cfg).ProcessInputs(Inputs{Keys: "A|B", Values: tt.values + "|b", EnvFile: envFile})
+			if tt.errPart == "" {
+				if err != nil {
+					t.Fatalf("ProcessInputs() unexpected error: %v", err)
+				}
+				if values[1] != "main.example.com-b" {
+					t.Errorf("values[1] = %q, want %q", values[1], "main.example.com-b")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("ProcessInputs() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestProcessInputsEnvFileInterpolationPolicy(t *testing.T) {
+	t.Setenv("POLICY_SECRET", "secret")
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("K=${POLICY_SECRET}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Blocked by the allowlist", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableInterpolation: true, InterpolationAllow: "GITHUB_*"}
+		_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{EnvFile: envFile})
+		if err == nil || !strings.Contains(err.Error(), "variable POLICY_SECRET is not in the interpolation allowlist") {
+			t.Errorf("ProcessInputs() error = %v, want allowlist error", err)
+		}
+	})
+
+	t.Run("Blocked by the denylist", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", EnableInterpolation: true, InterpolationDeny: "*_SECRET"}
+		_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{EnvFile: envFile})
+		if err == nil || !strings.Contains(err.Error(), "variable POLICY_SECRET is blocked by the interpolation denylist (pattern *_SECRET)") {
+			t.Errorf("ProcessInputs() error = %v, want denylist error", err)
+		}
+	})
+
+	t.Run("Not expanded without enable_interpolation", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", InterpolationAllow: "GITHUB_*"}
+		_, values, err := NewProcessor(cfg).ProcessInputs(Inputs{EnvFile: envFile})
+		if err != nil {
+			t.Fatalf("ProcessInputs() unexpected error: %v", err)
+		}
+		if len(values) != 1 || values[0] != "${POLICY_SECRET}" {
+			t.Errorf("ProcessInputs() values = %q, want [${POLICY_SECRET}]", values)
+		}
+	})
+}
+
 func TestProcessInputValuesWithFileReading(t *testing.T) {
 	t.Run("Read value from file", func(t *testing.T) {
 		tmpFile := filepath.Join(t.TempDir(), "val.txt")
@@ -779,3 +1014,223 @@ func TestApplyGroupPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessInputsWithEnvFile(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	content := "# settings\nexport REGION=us-east-1\nCERT=\"line1\nline2\"\nURL=https://${REGION}.example.com\n"
+	if err := os.WriteFile(envFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		inputs         Inputs
+		groupPrefix    string
+		interpolation  bool
+		expectedKeys   []string
+		expectedValues []string
+		wantError      bool
+	}{
+		{
+			name:           "File only",
+			inputs:         Inputs{EnvFile: envFile},
+			interpolation:  true,
+			expectedKeys:   []string{"REGION", "CERT", "URL"},
+			expectedValues: []string{"us-east-1", "line1\nline2", "https://us-east-1.example.com"},
+		},
+		{
+			name:           "References are kept without enable_interpolation",
+			inputs:         Inputs{EnvFile: envFile},
+			expectedKeys:   []string{"REGION", "CERT", "URL"},
+			expectedValues: []string{"us-east-1", "line1\nline2", "https://${REGION}.example.com"},
+		},
+		{
+			name:           "File entries precede inline pairs",
+			inputs:         Inputs{Keys: "EXTRA", Values: "value", EnvFile: envFile},
+			interpolation:  true,
+			expectedKeys:   []string{"REGION", "CERT", "URL", "EXTRA"},
+			expectedValues: []string{"us-east-1", "line1\nline2", "https://us-east-1.example.com", "value"},
+		},
+		{
+			name:           "Group prefix applies to file entries",
+			inputs:         Inputs{EnvFile: envFile},
+			groupPrefix:    "APP",
+			interpolation:  true,
+			expectedKeys:   []string{"APP_REGION", "APP_CERT", "APP_URL"},
+			expectedValues: []string{"us-east-1", "line1\nline2", "https://us-east-1.example.com"},
+		},
+		{
+			name:      "Missing file",
+			inputs:    Inputs{EnvFile: filepath.Join(t.TempDir(), "missing.env")},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewProcessor(&config.Config{Delimiter: ",", GroupPrefix: tt.groupPrefix, EnableInterpolation: tt.interpolation})
+			keys, values, err := processor.ProcessInputs(tt.inputs)
+
+			if tt.wantError {
+				if err == nil {
+					t.Error("ProcessInputs() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessInputs() unexpected error: %v", err)
+			}
+			if strings.Join(keys, "|") != strings.Join(tt.expectedKeys, "|") {
+				t.Errorf("ProcessInputs() keys = %q, want %q", keys, tt.expectedKeys)
+			}
+			if strings.Join(values, "|") != strings.Join(tt.expectedValues, "|") {
+				t.Errorf("ProcessInputs() values = %q, want %q", values, tt.expectedValues)
+			}
+		})
+	}
+}
+
+func TestGetInputsEnvFileOnlyForEnv(t *testing.T) {
+	w := NewWriter(&config.Config{EnvFile: ".env"})
+
+	if got := w.getInputs(githubEnvVar).EnvFile; got != ".env" {
+		t.Errorf("getInputs(env).EnvFile = %q, want %q", got, ".env")
+	}
+	if got := w.getInputs(githubOutputVar).EnvFile; got != "" {
+		t.Errorf("getInputs(output).EnvFile = %q, want empty", got)
+	}
+}
+
+func TestProcessInputsStructuredFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "values.yaml")
+	if err := os.WriteFile(yamlFile, []byte("service:\n  port: 8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("file:// yaml flattened in auto mode", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", JsonSupport: true, StructuredFormat: "auto"}
+		keys, values, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "VALUES", Values: "file://" + yamlFile})
+		if err != nil {
+			t.Fatalf("ProcessInputs() unexpected error: %v", err)
+		}
+
+		found := false
+		for i, key := range keys {
+			if key == "VALUES_service_port" {
+				found = values[i] == "8080"
+			}
+		}
+		if !found {
+			t.Errorf("ProcessInputs() keys = %v, want VALUES_service_port=8080", keys)
+		}
+	})
+
+	t.Run("unknown json key order", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", JsonSupport: true, JsonKeyOrder: "random"}
+		_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "A", Values: "b"})
+		if err == nil || !strings.Contains(err.Error(), "unsupported json_key_order") {
+			t.Errorf("ProcessInputs() error = %v, want unsupported json_key_order", err)
+		}
+	})
+
+	t.Run("unknown json key style", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", JsonSupport: true, JsonKeyStyle: "camel"}
+		_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "A", Values: "b"})
+		if err == nil || !strings.Contains(err.Error(), "unsupported json_key_style") {
+			t.Errorf("ProcessInputs() error = %v, want unsupported json_key_style", err)
+		}
+	})
+
+	t.Run("unknown json null value", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", JsonSupport: true, JsonNullValue: "nil"}
+		_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "A", Values: "b"})
+		if err == nil || !strings.Contains(err.Error(), "unsupported json_null_value") {
+			t.Errorf("ProcessInputs() error = %v, want unsupported json_null_value", err)
+		}
+	})
+
+	t.Run("negative json max depth", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", JsonSupport: true, JsonMaxDepth: -1}
+		_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "A", Values: "b"})
+		if err == nil || !strings.Contains(err.Error(), "json_max_depth") {
+			t.Errorf("ProcessInputs() error = %v, want json_max_depth error", err)
+		}
+	})
+
+	t.Run("flatten options are passed to the handler", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: "|", JsonSupport: true, JsonSeparator: "__", JsonLeafOnly: true, JsonKeyStyle: "upper_snake"}
+		keys, _, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "CFG", Values: `{"serverHost":"h"}`})
+		if err != nil {
+			t.Fatalf("ProcessInputs() unexpected error: %v", err)
+		}
+		if strings.Join(keys, ",") != "CFG__SERVER_HOST" {
+			t.Errorf("ProcessInputs() keys = %v, want [CFG__SERVER_HOST]", keys)
+		}
+	})
+
+	t.Run("invalid json separator", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", JsonSupport: true, JsonSeparator: "-"}
+		_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "A", Values: "b"})
+		if err == nil || !strings.Contains(err.Error(), "invalid json_separator") {
+			t.Errorf("ProcessInputs() error = %v, want invalid json_separator", err)
+		}
+	})
+
+	t.Run("unknown structured format", func(t *testing.T) {
+		cfg := &config.Config{Delimiter: ",", JsonSupport: true, StructuredFormat: "xml"}
+		_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "A", Values: "b"})
+		if err == nil || !strings.Contains(err.Error(), "unsupported structured_format") {
+			t.Errorf("ProcessInputs() error = %v, want unsupported structured_format", err)
+		}
+	})
+}
+
+func TestProcessInputsWithSources(t *testing.T) {
+	dir := t.TempDir()
+	valueFile := filepath.Join(dir, "value.txt")
+	if err := os.WriteFile(valueFile, []byte("from-file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(dir, ".env")
+	if err := os.WriteFile(envFile, []byte("DOTENV=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOURCES_TEST_VAR", "expanded")
+
+	cfg := &config.Config{
+		Delimiter:           "|",
+		JsonSupport:         true,
+		EnableInterpolation: true,
+		GroupPrefix:         "APP",
+		JsonSelect:          "PICKED=DOC.name",
+	}
+	keys, _, sources, err := NewProcessor(cfg).ProcessInputsWithSources(Inputs{
+		Keys:    "PLAIN|FILE|INTERP|DOC|CFG",
+		Values:  `plain|file://` + valueFile + `|${SOURCES_TEST_VAR}|{"name":"x"}|{"port":1}`,
+		Pairs:   "PAIR=p",
+		EnvFile: envFile,
+	})
+	if err != nil {
+		t.Fatalf("ProcessInputsWithSources() unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"APP_DOTENV":   "env_file",
+		"APP_PLAIN":    "literal",
+		"APP_FILE":     "file://",
+		"APP_INTERP":   "literal, interpolated",
+		"APP_PICKED":   "json_select",
+		"APP_CFG":      "literal",
+		"APP_CFG_port": "JSON-flattened",
+		"APP_PAIR":     "literal",
+	}
+	if len(keys) != len(sources) || len(keys) != len(expected) {
+		t.Fatalf("ProcessInputsWithSources() keys = %v, sources = %v", keys, sources)
+	}
+	for i, key := range keys {
+		if sources[i] != expected[key] {
+			t.Errorf("source of %s = %q, want %q", key, sources[i], expected[key])
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package writer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/structured"
+)
+
+// errInvalidRuleKey reports a rule key that is not a valid glob or regex.
+const errInvalidRuleKey = "invalid validation rule key %q: %v"
+
+// regexRulePrefix marks a rule key as a regular expression.
+const regexRulePrefix = "re:"
+
+// Kinds of rule keys, in order of precedence
+const (
+	RuleMatchExact = "exact"
+	RuleMatchGlob  = "glob"
+	RuleMatchRegex = "regex"
+)
+
+// patternRule is a validation rule keyed by a glob or regular expression.
+type patternRule struct {
+	key      string // Rule key as written
+	kind     string // RuleMatchGlob or RuleMatchRegex
+	regex    *regexp.Regexp
+	literals int // Non-wildcard characters of a glob, for precedence
+	order    int // Position of the rule in validation_rules
+	rule     ValidationRule
+}
+
+// matches reports whether the pattern matches key.
+func (r *patternRule) matches(key string) bool {
+	if r.kind == RuleMatchRegex {
+		return r.regex.MatchString(key)
+	}
+	matched, _ := path.Match(r.key, key)
+	return matched
+}
+
+// RuleSet finds the validation rule for a key. Rule keys are exact names,
+// globs (*, ? and [...]) or "re:" regular expressions matched against the
+// whole key. When several rules match, the first of these wins:
+//
+//  1. an exact name;
+//  2. the glob with the most literal characters (earlier rule on a tie);
+//  3. the first matching regular expression in rule order.
+type RuleSet struct {
+	exact    map[string]ValidationRule
+	patterns []*patternRule
+}
+
+// ParseRuleSet parses validation_rules into a RuleSet.
+func ParseRuleSet(rulesJSON string) (*RuleSet, error) {
+	rules, err := ParseValidationRules(rulesJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &RuleSet{exact: make(map[string]ValidationRule)}
+	for i, key := range ruleKeyOrder(rulesJSON, rules) {
+		rule := rules[key]
+		switch {
+		case strings.HasPrefix(key, regexRulePrefix):
+			re, err := regexp.Compile("^(?:" + strings.TrimPrefix(key, regexRulePrefix) + ")$")
+			if err != nil {
+				return nil, fmt.Errorf(errInvalidRuleKey, key, err)
+			}
+			rs.patterns = append(rs.patterns, &patternRule{key: key, kind: RuleMatchRegex, regex: re, order: i, rule: rule})
+		case strings.ContainsAny(key, "*?["):
+			if _, err := path.Match(key, ""); err != nil {
+				return nil, fmt.Errorf(errInvalidRuleKey, key, err)
+			}
+			rs.patterns = append(rs.patterns, &patternRule{key: key, kind: RuleMatchGlob, literals: globLiterals(key), order: i, rule: rule})
+		default:
+			rs.exact[key] = rule
+		}
+	}
+
+	sort.SliceStable(rs.patterns, func(i, j int) bool {
+		a, b := rs.patterns[i], rs.patterns[j]
+		if a.kind != b.kind {
+			return a.kind == RuleMatchGlob
+		}
+		if a.literals != b.literals {
+			return a.literals > b.literals
+		}
+		return a.order < b.order
+	})
+	return rs, nil
+}
+
+// Match returns the rule that applies to key, the rule key it was found
+// under and how that key matched. ok is false when no rule applies.
+func (rs *RuleSet) Match(key string) (rule ValidationRule, ruleKey, kind string, ok bool) {
+	if rule, ok := rs.exact[key]; ok {
+		return rule, key, RuleMatchExact, true
+	}
+	for _, p := range rs.patterns {
+		if p.matches(key) {
+			return p.rule, p.key, p.kind, true
+		}
+	}
+	return ValidationRule{}, "", "", false
+}
+
+// ruleKeyOrder returns the rule keys in the order they are written, falling
+// back to sorted order if the document cannot be re-read.
+func ruleKeyOrder(rulesJSON string, rules map[string]ValidationRule) []string {
+	if doc, err := structured.DecodeJSON(rulesJSON); err == nil {
+		if obj, ok := doc.(*structured.Object); ok && obj.Len() == len(rules) {
+			return obj.Keys()
+		}
+	}
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// globLiterals counts the characters of a glob that are not wildcards.
+func globLiterals(pattern string) int {
+	count := 0
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '*' || c == '?':
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			count++
+		default:
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,137 @@
+package writer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+func TestRuleSetMatch(t *testing.T) {
+	rules := `{
+		"re:.*_(HOST|ADDR)": {"message": "regex host"},
+		"*_port": {"message": "any port"},
+		"APP_CONFIG_server_port": {"message": "exact"},
+		"APP_*_port": {"message": "app port"},
+		"re:APP_.*": {"message": "regex app"},
+		"CONFIG_servers_*_host": {"message": "server host"},
+		"LEVEL_?": {"message": "single char"}
+	}`
+	rs, err := ParseRuleSet(rules)
+	if err != nil {
+		t.Fatalf("ParseRuleSet() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		key     string
+		ruleKey string
+		kind    string
+	}{
+		{"APP_CONFIG_server_port", "APP_CONFIG_server_port", RuleMatchExact},
+		{"APP_CONFIG_admin_port", "APP_*_port", RuleMatchGlob},
+		{"DB_port", "*_port", RuleMatchGlob},
+		{"CONFIG_servers_0_host", "CONFIG_servers_*_host", RuleMatchGlob},
+		{"LEVEL_1", "LEVEL_?", RuleMatchGlob},
+		{"DB_HOST", "re:.*_(HOST|ADDR)", RuleMatchRegex},
+		{"APP_HOST", "re:.*_(HOST|ADDR)", RuleMatchRegex},
+		{"APP_NAME", "re:APP_.*", RuleMatchRegex},
+		{"LEVEL_10", "", ""},
+		{"XAPP_NAME", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			_, ruleKey, kind, ok := rs.Match(tt.key)
+			if ok != (tt.ruleKey != "") || ruleKey != tt.ruleKey || kind != tt.kind {
+				t.Errorf("Match(%q) = %q %q %v, want %q %q", tt.key, ruleKey, kind, ok, tt.ruleKey, tt.kind)
+			}
+		})
+	}
+}
+
+func TestParseRuleSetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		errPart string
+	}{
+		{"Bad regex", `{"re:(":{}}`, `invalid validation rule key "re:("`},
+		{"Bad glob", `{"KEY_[":{}}`, `invalid validation rule key "KEY_["`},
+		{"Bad rule", `{"*_PORT":{"type":"port"}}`, `invalid validation rule for key "*_PORT"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRuleSet(tt.rules)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("ParseRuleSet() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestGlobLiterals(t *testing.T) {
+	tests := map[string]int{
+		"*_PORT":      5,
+		"A?B*":        2,
+		"KEY_[0-9]":   4,
+		`A\*B`:        3,
+		"*":           0,
+		"APP_*_port*": 9,
+	}
+	for pattern, want := range tests {
+		if got := globLiterals(pattern); got != want {
+			t.Errorf("globLiterals(%q) = %d, want %d", pattern, got, want)
+		}
+	}
+}
+
+func TestValidateOutputsWithPatternKeys(t *testing.T) {
+	cfg := &config.Config{
+		ValidationRules: `{"*_port":{"type":"int","max":65535},"re:.*_host":{"type":"url"},"APP_CONFIG_server_port":{"type":"int","max":8999}}`,
+		ValidationMode:  ValidationModeCollect,
+	}
+	keys := []string{"APP_CONFIG_server_port", "APP_CONFIG_admin_port", "APP_CONFIG_server_host", "OTHER"}
+	values := []string{"9000", "70000", "not a url", "anything"}
+
+	err := NewValidator(cfg).ValidateOutputs(keys, values)
+	collected, ok := err.(*ValidationErrors)
+	if !ok {
+		t.Fatalf("ValidateOutputs() error = %T %v, want *ValidationErrors", err, err)
+	}
+	var got []string
+	for _, verr := range collected.Errors {
+		got = append(got, verr.Message)
+	}
+	want := []string{
+		`validation failed for key "APP_CONFIG_server_port": value 9000 is greater than max 8999`,
+		`validation failed for key "APP_CONFIG_admin_port": value 70000 is greater than max 65535`,
+		`validation failed for key "APP_CONFIG_server_host": value "not a url" is not a valid url (expected an absolute URL such as https://example.com)`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ValidateOutputs() = %q, want %q", got, want)
+	}
+}
+
+func TestLogProcessedValuesRuleTrace(t *testing.T) {
+	cfg := &config.Config{
+		DebugMode:       true,
+		ValidationRules: `{"*_PORT":{"type":"int"},"HOST":{"type":"url"}}`,
+	}
+	out := captureStdout(t, func() {
+		NewProcessor(cfg).LogProcessedValues([]string{"HOST", "DB_PORT", "NAME"}, []string{"a", "b", "c"})
+	})
+	for _, want := range []string{`HOST <- "HOST" (exact)`, `DB_PORT <- "*_PORT" (glob)`, "NAME: no rule"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("LogProcessedValues() output missing %q:\n%s", want, out)
+		}
+	}
+
+	cfg.ValidationRules = `{broken`
+	out = captureStdout(t, func() {
+		NewProcessor(cfg).LogProcessedValues([]string{"HOST"}, []string{"a"})
+	})
+	if strings.Contains(out, "Validation Rules") {
+		t.Errorf("LogProcessedValues() printed a trace for invalid rules:\n%s", out)
+	}
+}
@@ -0,0 +1,139 @@
+package writer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value types supported by the type field of a validation rule
+const (
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeBool     = "bool"
+	TypeURL      = "url"
+	TypeEmail    = "email"
+	TypeSemver   = "semver"
+	TypeDuration = "duration"
+	TypeIPv4     = "ipv4"
+	TypeIPv6     = "ipv6"
+	TypeCIDR     = "cidr"
+	TypeJSON     = "json"
+	TypeBase64   = "base64"
+	TypeUUID     = "uuid"
+)
+
+var (
+	// semverPattern follows semver.org, with an optional leading "v".
+	semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// valueType describes how values of a rule type are recognised.
+type valueType struct {
+	valid   func(value string) bool
+	expects string // What a valid value looks like, used in error messages
+}
+
+// valueTypes holds the checks for every supported rule type.
+var valueTypes = map[string]valueType{
+	TypeInt: {
+		valid:   func(v string) bool { _, err := strconv.ParseInt(v, 10, 64); return err == nil },
+		expects: "a whole number",
+	},
+	TypeFloat: {
+		valid:   func(v string) bool { _, ok := parseFloat(v); return ok },
+		expects: "a number",
+	},
+	TypeBool: {
+		valid:   func(v string) bool { _, err := strconv.ParseBool(strings.ToLower(v)); return err == nil },
+		expects: "true or false",
+	},
+	TypeURL: {
+		valid: func(v string) bool {
+			u, err := url.Parse(v)
+			return err == nil && u.Scheme != "" && u.Host != ""
+		},
+		expects: "an absolute URL such as https://example.com",
+	},
+	TypeEmail: {
+		valid: func(v string) bool {
+			addr, err := mail.ParseAddress(v)
+			return err == nil && addr.Address == v
+		},
+		expects: "an address such as user@example.com",
+	},
+	TypeSemver: {
+		valid:   semverPattern.MatchString,
+		expects: "a semantic version such as 1.2.3",
+	},
+	TypeDuration: {
+		valid:   func(v string) bool { _, err := time.ParseDuration(v); return err == nil },
+		expects: "a duration such as 90s or 1h30m",
+	},
+	TypeIPv4: {
+		valid: func(v string) bool {
+			ip := net.ParseIP(v)
+			return ip != nil && ip.To4() != nil && !strings.Contains(v, ":")
+		},
+		expects: "an IPv4 address",
+	},
+	TypeIPv6: {
+		valid:   func(v string) bool { return net.ParseIP(v) != nil && strings.Contains(v, ":") },
+		expects: "an IPv6 address",
+	},
+	TypeCIDR: {
+		valid:   func(v string) bool { _, _, err := net.ParseCIDR(v); return err == nil },
+		expects: "a CIDR block such as 10.0.0.0/16",
+	},
+	TypeJSON: {
+		valid:   func(v string) bool { return json.Valid([]byte(v)) },
+		expects: "a JSON document",
+	},
+	TypeBase64: {
+		valid: func(v string) bool {
+			_, err := base64.StdEncoding.Strict().DecodeString(v)
+			return err == nil
+		},
+		expects: "padded standard base64",
+	},
+	TypeUUID: {
+		valid:   uuidPattern.MatchString,
+		expects: "a UUID such as 123e4567-e89b-12d3-a456-426614174000",
+	},
+}
+
+// supportedTypes returns the names of the rule types in sorted order.
+func supportedTypes() string {
+	names := make([]string, 0, len(valueTypes))
+	for name := range valueTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseFloat parses a finite number.
+func parseFloat(v string) (float64, bool) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// formatNumber renders a rule bound without a trailing ".0" or exponent for
+// whole numbers.
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,54 @@
+package writer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValueTypes(t *testing.T) {
+	tests := []struct {
+		typ     string
+		valid   []string
+		invalid []string
+	}{
+		{TypeInt, []string{"0", "-42", "65535"}, []string{"1.5", "abc", "99999999999999999999", " 1"}},
+		{TypeFloat, []string{"1.5", "-2", "1e3"}, []string{"abc", "NaN", "Inf", "1,5"}},
+		{TypeBool, []string{"true", "FALSE", "1", "f"}, []string{"yes", "on", ""}},
+		{TypeURL, []string{"https://example.com", "http://localhost:8080/path?q=1"}, []string{"example.com", "/relative/path", "https://"}},
+		{TypeEmail, []string{"user@example.com", "first.last+tag@sub.example.org"}, []string{"user", "User <user@example.com>", "@example.com"}},
+		{TypeSemver, []string{"1.2.3", "v0.1.0", "1.0.0-rc.1+build.5"}, []string{"1.2", "01.2.3", "1.2.3-", "latest"}},
+		{TypeDuration, []string{"90s", "1h30m", "250ms"}, []string{"90", "1 hour", "1d"}},
+		{TypeIPv4, []string{"10.0.0.1", "255.255.255.255"}, []string{"256.0.0.1", "::ffff:10.0.0.1", "10.0.0"}},
+		{TypeIPv6, []string{"::1", "2001:db8::8a2e:370:7334", "::ffff:10.0.0.1"}, []string{"10.0.0.1", "2001:db8:::1"}},
+		{TypeCIDR, []string{"10.0.0.0/16", "2001:db8::/32"}, []string{"10.0.0.0", "10.0.0.0/33"}},
+		{TypeJSON, []string{`{"a":1}`, "[1,2]", `"s"`, "3"}, []string{"{a:1}", "[1,"}},
+		{TypeBase64, []string{"aGVsbG8=", "aGk="}, []string{"aGVsbG8", "not base64!"}},
+		{TypeUUID, []string{"123e4567-e89b-12d3-a456-426614174000", "123E4567-E89B-12D3-A456-426614174000"}, []string{"123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			vt, ok := valueTypes[tt.typ]
+			if !ok {
+				t.Fatalf("type %q is not registered", tt.typ)
+			}
+			for _, v := range tt.valid {
+				if !vt.valid(v) {
+					t.Errorf("%s: %q should be valid", tt.typ, v)
+				}
+			}
+			for _, v := range tt.invalid {
+				if vt.valid(v) {
+					t.Errorf("%s: %q should be invalid", tt.typ, v)
+				}
+			}
+		})
+	}
+}
+
+func TestSupportedTypes(t *testing.T) {
+	got := supportedTypes()
+	if !strings.HasPrefix(got, "base64, bool, cidr,") || strings.Count(got, ",") != len(valueTypes)-1 {
+		t.Errorf("supportedTypes() = %q", got)
+	}
+}
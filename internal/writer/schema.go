@@ -0,0 +1,137 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/filereader"
+	"github.com/somaz94/env-output-setter/internal/jsonschema"
+)
+
+// Error messages for json_schema
+const (
+	errSchemaInput     = "json_schema must be a JSON object mapping keys to schemas: %v"
+	errSchemaRead      = "json_schema: failed to read %s: %v"
+	errSchemaEntry     = "json_schema %s: %v"
+	errSchemaDecode    = "json_schema %s: value is not a valid %s document: %v"
+	errSchemaViolation = "json_schema %s: %s"
+)
+
+// CheckSchema identifies json_schema violations in validation errors.
+const CheckSchema = "schema"
+
+// loadSchemas compiles the json_schema input. The input, inline or a file://
+// reference, is a JSON object whose keys name values and whose entries are
+// schemas, either inline or as "file://" references to schema files.
+func loadSchemas(input string) (map[string]*jsonschema.Schema, error) {
+	data, err := readSchemaSource(strings.TrimSpace(input))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf(errSchemaInput, err)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	schemas := make(map[string]*jsonschema.Schema, len(entries))
+	for _, key := range keys {
+		raw := []byte(entries[key])
+		var ref string
+		if json.Unmarshal(raw, &ref) == nil {
+			if !filereader.IsFileReference(ref) {
+				return nil, fmt.Errorf(errSchemaEntry, key, "a schema must be an object, a boolean or a file:// reference")
+			}
+			if raw, err = readSchemaSource(ref); err != nil {
+				return nil, err
+			}
+		}
+
+		schema, err := jsonschema.Compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf(errSchemaEntry, key, err)
+		}
+		schemas[key] = schema
+	}
+	return schemas, nil
+}
+
+// readSchemaSource returns s, or the content of the file it references.
+func readSchemaSource(s string) ([]byte, error) {
+	if !filereader.IsFileReference(s) {
+		return []byte(s), nil
+	}
+	path := filereader.GetFilePath(s)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(errSchemaRead, path, err)
+	}
+	return data, nil
+}
+
+// validateSchemas checks every value that has a json_schema entry. Values are
+// decoded like json_select sources, so with json_support on YAML and TOML
+// documents are checked too. Violations follow validation_mode.
+func (p *Processor) validateSchemas(keyList, valueList, hints []string) error {
+	schemas := p.schemas
+	if schemas == nil {
+		var err error
+		if schemas, err = loadSchemas(p.cfg.JsonSchema); err != nil {
+			return err
+		}
+	}
+	collector, err := newErrorCollector(p.cfg.ValidationMode)
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keyList {
+		schema := p.schemaFor(schemas, key)
+		if schema == nil {
+			continue
+		}
+
+		hint := ""
+		if i < len(hints) {
+			hint = hints[i]
+		}
+		doc, format, err := p.decodeDocument(valueList[i], hint)
+		if err != nil {
+			if err := collector.add(key, CheckSchema, fmt.Sprintf(errSchemaDecode, key, strings.ToUpper(format), err)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, violation := range schema.Validate(doc) {
+			if err := collector.add(key, CheckSchema, fmt.Sprintf(errSchemaViolation, key, violation.Error())); err != nil {
+				return err
+			}
+		}
+	}
+	return collector.result()
+}
+
+// schemaFor returns the schema for key, honoring case_sensitive.
+func (p *Processor) schemaFor(schemas map[string]*jsonschema.Schema, key string) *jsonschema.Schema {
+	if schema, ok := schemas[key]; ok {
+		return schema
+	}
+	if !p.cfg.CaseSensitive {
+		for name, schema := range schemas {
+			if strings.EqualFold(name, key) {
+				return schema
+			}
+		}
+	}
+	return nil
+}
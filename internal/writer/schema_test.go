@@ -0,0 +1,179 @@
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+const deploySchema = `{"type":"object","required":["image"],"additionalProperties":false,"properties":{"image":{"type":"string"},"replicas":{"type":"integer"}}}`
+
+func TestLoadSchemas(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile := filepath.Join(dir, "deploy.schema.json")
+	if err := os.WriteFile(schemaFile, []byte(deploySchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mapFile := filepath.Join(dir, "schemas.json")
+	if err := os.WriteFile(mapFile, []byte(`{"DEPLOY":"file://`+schemaFile+`","FLAG":true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		wantKeys []string
+		errPart  string
+	}{
+		{name: "Inline schemas", input: `{"DEPLOY":` + deploySchema + `}`, wantKeys: []string{"DEPLOY"}},
+		{name: "Schema file per key", input: `{"DEPLOY":"file://` + schemaFile + `"}`, wantKeys: []string{"DEPLOY"}},
+		{name: "Input from a file", input: "file://" + mapFile, wantKeys: []string{"DEPLOY", "FLAG"}},
+		{name: "Not an object", input: `[1]`, errPart: "json_schema must be a JSON object mapping keys to schemas"},
+		{name: "String that is not a file reference", input: `{"DEPLOY":"object"}`, errPart: "json_schema DEPLOY: a schema must be an object, a boolean or a file:// reference"},
+		{name: "Missing schema file", input: `{"DEPLOY":"file://` + filepath.Join(dir, "missing.json") + `"}`, errPart: "json_schema: failed to read"},
+		{name: "Invalid schema", input: `{"DEPLOY":{"type":"int"}}`, errPart: `json_schema DEPLOY: invalid schema at "#/type": unknown type "int"`},
+		{name: "Looping $ref", input: `{"DEPLOY":{"$ref":"#"}}`, errPart: `json_schema DEPLOY: invalid schema at "#/$ref": $ref loops back`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemas, err := loadSchemas(tt.input)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Fatalf("loadSchemas() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadSchemas() unexpected error: %v", err)
+			}
+			if len(schemas) != len(tt.wantKeys) {
+				t.Errorf("loadSchemas() = %d schemas, want %v", len(schemas), tt.wantKeys)
+			}
+			for _, key := range tt.wantKeys {
+				if schemas[key] == nil {
+					t.Errorf("loadSchemas() missing schema for %s", key)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessInputsWithJSONSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		values   string
+		expected []string
+		errPart  string
+	}{
+		{
+			name:     "Valid document is flattened",
+			cfg:      &config.Config{Delimiter: "|", JsonSupport: true, CaseSensitive: true},
+			values:   `{"image":"nginx","replicas":2}|x`,
+			expected: []string{`DEPLOY={"image":"nginx","replicas":2}`, "OTHER=x", "DEPLOY_image=nginx", "DEPLOY_replicas=2"},
+		},
+		{
+			name:    "Violation stops before flattening",
+			cfg:     &config.Config{Delimiter: "|", JsonSupport: true, CaseSensitive: true},
+			values:  `{"image":"nginx","replcias":2}|x`,
+			errPart: `json_schema DEPLOY: #/replcias: property "replcias" is not allowed`,
+		},
+		{
+			name:    "Collect mode reports every violation",
+			cfg:     &config.Config{Delimiter: "|", JsonSupport: true, CaseSensitive: true, ValidationMode: ValidationModeCollect},
+			values:  `{"replicas":"2"}|x`,
+			errPart: "2 validation errors:\n  DEPLOY:\n    - json_schema DEPLOY: #: missing required property \"image\"\n    - json_schema DEPLOY: #/replicas: expected integer, got string",
+		},
+		{
+			name:    "Value is not a document",
+			cfg:     &config.Config{Delimiter: "|", CaseSensitive: true},
+			values:  `not json|x`,
+			errPart: "json_schema DEPLOY: value is not a valid JSON document",
+		},
+		{
+			name:    "Case-insensitive key match",
+			cfg:     &config.Config{Delimiter: "|", CaseSensitive: false},
+			values:  `{"image":1}|x`,
+			errPart: "json_schema DEPLOY: #/image: expected string, got integer",
+		},
+		{
+			name:     "Validated without json_support",
+			cfg:      &config.Config{Delimiter: "|", CaseSensitive: true},
+			values:   `{"image":"nginx"}|x`,
+			expected: []string{`DEPLOY={"image":"nginx"}`, "OTHER=x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.JsonSchema = `{"DEPLOY":` + deploySchema + `}`
+			if !tt.cfg.CaseSensitive {
+				tt.cfg.JsonSchema = `{"deploy":` + deploySchema + `}`
+			}
+			keys, values, err := NewProcessor(tt.cfg).ProcessInputs(Inputs{Keys: "DEPLOY|OTHER", Values: tt.values})
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Fatalf("ProcessInputs() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessInputs() unexpected error: %v", err)
+			}
+
+			var got []string
+			for i, key := range keys {
+				got = append(got, key+"="+values[i])
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("ProcessInputs() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProcessInputsJSONSchemaYAMLFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(file, []byte("image: nginx\nreplicas: two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Delimiter:        ",",
+		JsonSupport:      true,
+		StructuredFormat: "auto",
+		CaseSensitive:    true,
+		JsonSchema:       `{"VALUES":` + deploySchema + `}`,
+	}
+	_, _, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "VALUES", Values: "file://" + file})
+	if err == nil || !strings.Contains(err.Error(), "json_schema VALUES: #/replicas: expected integer, got string") {
+		t.Errorf("ProcessInputs() error = %v, want replicas type error", err)
+	}
+}
+
+func TestSetEnvAnnotatesSchemaViolations(t *testing.T) {
+	t.Setenv(githubEnvVar, "")
+	cfg := &config.Config{
+		EnvKeys:        "DEPLOY",
+		EnvValues:      `{"image":1}`,
+		Delimiter:      "|",
+		CaseSensitive:  true,
+		JsonSchema:     `{"DEPLOY":` + deploySchema + `}`,
+		ValidationMode: ValidationModeCollect,
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		_, err = SetEnv(cfg)
+	})
+	if err == nil {
+		t.Fatal("SetEnv() expected error")
+	}
+	if !strings.Contains(out, "::error::DEPLOY: json_schema DEPLOY: #/image: expected string, got integer") {
+		t.Errorf("SetEnv() output %q missing annotation", out)
+	}
+}
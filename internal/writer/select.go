@@ -0,0 +1,200 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/jsonpath"
+	"github.com/somaz94/env-output-setter/internal/printer"
+	"github.com/somaz94/env-output-setter/internal/structured"
+)
+
+// Error messages for json_select
+const (
+	errSelectSyntax   = "json_select line %d: expected TARGET=SOURCE.path, got %q"
+	errSelectEmptyKey = "json_select line %d: empty target or source key"
+	errSelectPath     = "json_select line %d: %v"
+	errSelectDecode   = "json_select %s: value of %s is not valid %s: %v"
+	errSelectNoMatch  = "json_select %s: %v in the value of %s"
+	errSelectUnknown  = "json_select %s: source key %s is not set by any env, output or state input"
+)
+
+// Selection extracts part of a structured value into its own key.
+type Selection struct {
+	Key    string         // Key that receives the selected value
+	Source string         // Key whose value is queried
+	Path   *jsonpath.Path // Path evaluated against the decoded source value
+}
+
+// ParseSelections parses the json_select input. Each non-blank line has the
+// form TARGET=SOURCE<path>, e.g. "POD=RESPONSE.items[0].metadata.name": the
+// source key ends at the first '.' or '[' and the rest is the path. Lines
+// starting with '#' are comments.
+func ParseSelections(input string) ([]Selection, error) {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+
+	var selections []Selection
+	for i, rawLine := range lines {
+		lineNo := i + 1
+		line := strings.TrimSpace(rawLine)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		target, expr, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf(errSelectSyntax, lineNo, line)
+		}
+		target = strings.TrimSpace(target)
+		expr = strings.TrimSpace(expr)
+
+		source, pathExpr := expr, ""
+		if idx := strings.IndexAny(expr, ".["); idx >= 0 {
+			source, pathExpr = strings.TrimSpace(expr[:idx]), expr[idx:]
+		}
+		if target == "" || source == "" {
+			return nil, fmt.Errorf(errSelectEmptyKey, lineNo)
+		}
+
+		path, err := jsonpath.Compile(pathExpr)
+		if err != nil {
+			return nil, fmt.Errorf(errSelectPath, lineNo, err)
+		}
+		selections = append(selections, Selection{Key: target, Source: source, Path: path})
+	}
+
+	return selections, nil
+}
+
+// checkSelectionSources reports the first json_select entry whose source key
+// none of the env, output and state inputs sets, so a misspelled source fails
+// the step before anything is written instead of leaving its target unset.
+func checkSelectionSources(cfg *config.Config) error {
+	selections, err := ParseSelections(cfg.JsonSelect)
+	if err != nil || len(selections) == 0 {
+		return err
+	}
+
+	p := NewProcessor(cfg)
+	var keys []string
+	for _, in := range []Inputs{
+		{Keys: cfg.EnvKeys, Pairs: cfg.EnvPairs, EnvFile: cfg.EnvFile},
+		{Keys: cfg.OutputKeys, Pairs: cfg.OutputPairs},
+		{Keys: cfg.StateKeys},
+	} {
+		inputKeys, err := p.inputKeys(in)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, inputKeys...)
+	}
+
+	for _, sel := range selections {
+		found := false
+		for _, key := range keys {
+			if len(p.selectionsFor([]Selection{sel}, key)) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf(errSelectUnknown, sel.Key, sel.Source)
+		}
+	}
+	return nil
+}
+
+// applySelections replaces every key that is the source of a json_select entry
+// with the keys selected from its value, in selection order. Keys that no
+// selection refers to pass through unchanged, as do selections whose source is
+// not part of this destination. Singular paths produce the matched value
+// itself; wildcard and filter paths produce a compact JSON array of matches.
+func (p *Processor) applySelections(selections []Selection, keyList, valueList, hints []string) ([]string, []string, []string, error) {
+	var keys, values, outHints []string
+	for i, key := range keyList {
+		hint := ""
+		if i < len(hints) {
+			hint = hints[i]
+		}
+
+		matched := p.selectionsFor(selections, key)
+		if len(matched) == 0 {
+			keys = append(keys, key)
+			values = append(values, valueList[i])
+			outHints = append(outHints, hint)
+			continue
+		}
+
+		data, err := p.decodeSelectionSource(matched[0], valueList[i], hint)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		for _, sel := range matched {
+			results, err := sel.Path.Eval(data)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf(errSelectNoMatch, sel.Key, err, key)
+			}
+
+			var selected interface{} = results
+			if sel.Path.IsSingular() {
+				selected = results[0]
+			}
+			value := formatScalar(selected, strings.ToLower(p.cfg.JsonNullValue))
+
+			if p.cfg.DebugMode {
+				// The value is logged with the processed values, once masks are registered
+				printer.PrintDebugInfo("Selected %s from %s%s\n", sel.Key, key, sel.Path)
+			}
+			keys = append(keys, sel.Key)
+			values = append(values, value)
+			outHints = append(outHints, "")
+		}
+	}
+
+	return keys, values, outHints, nil
+}
+
+// selectionsFor returns the selections whose source is key.
+func (p *Processor) selectionsFor(selections []Selection, key string) []Selection {
+	var matched []Selection
+	for _, sel := range selections {
+		if sel.Source == key || (!p.cfg.CaseSensitive && strings.EqualFold(sel.Source, key)) {
+			matched = append(matched, sel)
+		}
+	}
+	return matched
+}
+
+// decodeSelectionSource parses a source value as JSON or, with json_support
+// enabled, in the configured structured format.
+func (p *Processor) decodeSelectionSource(sel Selection, value, hint string) (interface{}, error) {
+	data, format, err := p.decodeDocument(value, hint)
+	if err != nil {
+		return nil, fmt.Errorf(errSelectDecode, sel.Key, sel.Source, strings.ToUpper(format), err)
+	}
+	return data, nil
+}
+
+// decodeDocument parses value as JSON or, with json_support on, in the
+// configured structured format (hint is the format implied by a file
+// reference). It also returns the format that was tried.
+func (p *Processor) decodeDocument(value, hint string) (interface{}, string, error) {
+	mode := structured.FormatJSON
+	if p.cfg.JsonSupport && p.cfg.StructuredFormat != "" {
+		mode = strings.ToLower(p.cfg.StructuredFormat)
+	}
+
+	format := structured.Detect(value, mode, hint)
+	if format == "" {
+		// Let the parser explain why the value is not a document
+		format = structured.FormatJSON
+		if mode == structured.FormatYAML || mode == structured.FormatTOML {
+			format = mode
+		}
+	}
+
+	data, err := structured.Parse(value, format)
+	return data, format, err
+}
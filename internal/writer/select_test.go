@@ -0,0 +1,196 @@
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+func TestParseSelections(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string // "KEY<-SOURCE:path"
+		errPart string
+	}{
+		{
+			name:  "Single selection",
+			input: "POD=RESPONSE.items[0].metadata.name",
+			want:  []string{"POD<-RESPONSE:.items[0].metadata.name"},
+		},
+		{
+			name:  "Comments, blanks and spacing",
+			input: "# pick fields\n\n  TAGS = RESPONSE.tags[*]  \r\nFIRST=LIST[0]\n",
+			want:  []string{"TAGS<-RESPONSE:.tags[*]", "FIRST<-LIST:[0]"},
+		},
+		{
+			name:  "Whole document",
+			input: "COPY=SOURCE",
+			want:  []string{"COPY<-SOURCE:"},
+		},
+		{
+			name:  "Filter with equals sign",
+			input: `PROD=ENVS[?(@.env=="prod")].url`,
+			want:  []string{`PROD<-ENVS:[?(@.env=="prod")].url`},
+		},
+		{
+			name:    "Missing equals",
+			input:   "POD RESPONSE.name",
+			errPart: "json_select line 1: expected TARGET=SOURCE.path",
+		},
+		{
+			name:    "Empty source",
+			input:   "POD=.name",
+			errPart: "json_select line 1: empty target or source key",
+		},
+		{
+			name:    "Empty target",
+			input:   "\n=RESPONSE.name",
+			errPart: "json_select line 2: empty target or source key",
+		},
+		{
+			name:    "Invalid path",
+			input:   "POD=RESPONSE.items[",
+			errPart: "json_select line 1: invalid path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selections, err := ParseSelections(tt.input)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Fatalf("ParseSelections() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSelections() unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, sel := range selections {
+				got = append(got, sel.Key+"<-"+sel.Source+":"+sel.Path.String())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ParseSelections() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessInputsWithJSONSelect(t *testing.T) {
+	response := `{"items":[{"metadata":{"name":"web"},"env":"prod","replicas":1000000},{"metadata":{"name":"worker"},"env":"dev","replicas":2}],"tags":["a","b"],"owner":null}`
+
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		selections string
+		expected   []string // KEY=value in order
+		errPart    string
+	}{
+		{
+			name:       "Singular paths",
+			cfg:        &config.Config{Delimiter: "|"},
+			selections: "POD=RESPONSE.items[0].metadata.name\nREPLICAS=RESPONSE.items[0].replicas",
+			expected:   []string{"OTHER=x", "POD=web", "REPLICAS=1000000"},
+		},
+		{
+			name:       "Wildcards produce a JSON array",
+			cfg:        &config.Config{Delimiter: "|"},
+			selections: "TAGS=RESPONSE.tags[*]",
+			expected:   []string{"OTHER=x", `TAGS=["a","b"]`},
+		},
+		{
+			name:       "Filter",
+			cfg:        &config.Config{Delimiter: "|"},
+			selections: `PROD=RESPONSE.items[?(@.env=="prod")].metadata.name`,
+			expected:   []string{"OTHER=x", `PROD=["web"]`},
+		},
+		{
+			name:       "Null honors json_null_value",
+			cfg:        &config.Config{Delimiter: "|", JsonNullValue: "null"},
+			selections: "OWNER=RESPONSE.owner",
+			expected:   []string{"OTHER=x", "OWNER=null"},
+		},
+		{
+			name:       "Selected arrays are flattened with json_support",
+			cfg:        &config.Config{Delimiter: "|", JsonSupport: true},
+			selections: "TAGS=RESPONSE.tags",
+			expected:   []string{"OTHER=x", `TAGS=["a","b"]`, "TAGS_0=a", "TAGS_1=b"},
+		},
+		{
+			name:       "Case-insensitive source match",
+			cfg:        &config.Config{Delimiter: "|", CaseSensitive: false},
+			selections: "POD=response.items[1].metadata.name",
+			expected:   []string{"OTHER=x", "POD=worker"},
+		},
+		{
+			name:       "Selection for an absent source is ignored",
+			cfg:        &config.Config{Delimiter: "|", CaseSensitive: true},
+			selections: "POD=OTHER_RESPONSE.name",
+			expected:   []string{"OTHER=x", "RESPONSE=" + response},
+		},
+		{
+			name:       "Path matches nothing",
+			cfg:        &config.Config{Delimiter: "|"},
+			selections: "NS=RESPONSE.items[0].metadata.namespace",
+			errPart:    `json_select NS: path ".items[0].metadata.namespace" matched nothing: no value at ".items[0].metadata.namespace" in the value of RESPONSE`,
+		},
+		{
+			name:       "Source is not JSON",
+			cfg:        &config.Config{Delimiter: "|"},
+			selections: "X=OTHER.name",
+			errPart:    "json_select X: value of OTHER is not valid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.JsonSelect = tt.selections
+			keys, values, err := NewProcessor(tt.cfg).ProcessInputs(Inputs{Keys: "OTHER|RESPONSE", Values: "x|" + response})
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Fatalf("ProcessInputs() error = %v, want containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessInputs() unexpected error: %v", err)
+			}
+
+			var got []string
+			for i, key := range keys {
+				got = append(got, key+"="+values[i])
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("ProcessInputs() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProcessInputsJSONSelectFromYAMLFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "values.yaml")
+	if err := os.WriteFile(file, []byte("image:\n  repository: nginx\n  tag: \"1.25\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Delimiter:        ",",
+		JsonSupport:      true,
+		StructuredFormat: "auto",
+		JsonSelect:       "IMAGE_TAG=VALUES.image.tag",
+	}
+	keys, values, err := NewProcessor(cfg).ProcessInputs(Inputs{Keys: "VALUES", Values: "file://" + file})
+	if err != nil {
+		t.Fatalf("ProcessInputs() unexpected error: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "IMAGE_TAG" || values[0] != "1.25" {
+		t.Errorf("ProcessInputs() = %v %v, want [IMAGE_TAG] [1.25]", keys, values)
+	}
+}
@@ -0,0 +1,188 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/somaz94/env-output-setter/internal/keyname"
+	"github.com/somaz94/env-output-setter/internal/printer"
+)
+
+// Supported size_limit_policy values
+const (
+	SizeLimitFail     = "fail"     // stop before writing anything
+	SizeLimitWarn     = "warn"     // write the value anyway and print a warning
+	SizeLimitTruncate = "truncate" // cut the value down to what still fits
+	SizeLimitSpill    = "spill"    // write the value to a file under $RUNNER_TEMP and set its path
+)
+
+// Runner limits on the command files
+const (
+	maxValueSize = 1 << 20  // Bytes allowed in a single value
+	maxFileSize  = 50 << 20 // Bytes allowed in one command file
+)
+
+// Error messages for size limits
+const (
+	errInvalidSizePolicy = "unsupported size_limit_policy %q (expected fail, warn, truncate or spill)"
+	errValueTooLarge     = "value of %s is %d bytes, over the %d byte limit for a single value"
+	errFileTooLarge      = "writing %s would grow %s to %d bytes, over the %d byte limit"
+	errSpillFailed       = "failed to spill value of %s: %v"
+)
+
+// sizeLimitError reports a value or file over the size limits, a value that
+// could not be spilled, or an invalid size_limit_policy. Retrying the write
+// cannot fix it.
+type sizeLimitError struct {
+	msg string
+}
+
+func (e *sizeLimitError) Error() string {
+	return e.msg
+}
+
+// isSizeLimitError reports whether err is a *sizeLimitError.
+func isSizeLimitError(err error) bool {
+	var sizeErr *sizeLimitError
+	return errors.As(err, &sizeErr)
+}
+
+// runnerTempVar names the runner's per-job temporary directory.
+const runnerTempVar = "RUNNER_TEMP"
+
+// entryOverhead is the size of an entry in the command file besides its value:
+// the key, "<<", the delimiter twice and three line breaks.
+func entryOverhead(key string) int64 {
+	return int64(len(key) + len("<<") + 2*len("EOF_") + 2*32 + 3)
+}
+
+// resizedValue is a value performWrite replaced to stay within the limits.
+type resizedValue struct {
+	value  string // Value as written
+	action string // "truncated" or "spilled"
+}
+
+// sizeBudget tracks how much a command file may still grow during one write.
+type sizeBudget struct {
+	policy   string
+	filePath string
+	used     int64 // Bytes in the file plus those accepted so far
+}
+
+// sizeLimitPolicy returns the normalized size_limit_policy, fail if unset.
+func sizeLimitPolicy(policy string) (string, error) {
+	switch normalized := strings.ToLower(policy); normalized {
+	case "":
+		return SizeLimitFail, nil
+	case SizeLimitFail, SizeLimitWarn, SizeLimitTruncate, SizeLimitSpill:
+		return normalized, nil
+	}
+	return "", &sizeLimitError{fmt.Sprintf(errInvalidSizePolicy, policy)}
+}
+
+// newSizeBudget starts a budget for filePath from its current size.
+func newSizeBudget(policy, filePath string) (*sizeBudget, error) {
+	policy, err := sizeLimitPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &sizeBudget{policy: policy, filePath: filePath}
+	if info, err := os.Stat(filePath); err == nil {
+		b.used = info.Size()
+	}
+	return b, nil
+}
+
+// fit checks the entry for key against the value and file limits and returns
+// the value to write: value itself, or under the truncate and spill policies a
+// replacement, in which case resized describes it.
+func (b *sizeBudget) fit(key, value string) (written string, resized *resizedValue, err error) {
+	overhead := entryOverhead(key)
+	size := int64(len(value))
+
+	var problem string
+	switch {
+	case size > maxValueSize:
+		problem = fmt.Sprintf(errValueTooLarge, key, size, maxValueSize)
+	case b.used+overhead+size > maxFileSize:
+		problem = fmt.Sprintf(errFileTooLarge, key, b.filePath, b.used+overhead+size, maxFileSize)
+	default:
+		b.used += overhead + size
+		return value, nil, nil
+	}
+
+	switch b.policy {
+	case SizeLimitWarn:
+		printer.PrintWarning("Warning: " + problem)
+	case SizeLimitTruncate:
+		limit := maxFileSize - b.used - overhead
+		if limit > maxValueSize {
+			limit = maxValueSize
+		}
+		value = truncateUTF8(value, limit)
+		resized = &resizedValue{value: value, action: "truncated"}
+		printer.PrintWarning(fmt.Sprintf("Warning: %s; truncated it to %d bytes", problem, len(value)))
+	case SizeLimitSpill:
+		path, err := spillValue(key, value)
+		if err != nil {
+			return "", nil, err
+		}
+		if b.used+overhead+int64(len(path)) > maxFileSize {
+			os.Remove(path)
+			return "", nil, &sizeLimitError{problem}
+		}
+		value = path
+		resized = &resizedValue{value: path, action: "spilled"}
+		printer.PrintWarning(fmt.Sprintf("Warning: %s; wrote it to %s and set its path instead", problem, path))
+	default:
+		return "", nil, &sizeLimitError{problem}
+	}
+
+	b.used += overhead + int64(len(value))
+	return value, resized, nil
+}
+
+// truncateUTF8 cuts s to at most limit bytes without splitting a character.
+func truncateUTF8(s string, limit int64) string {
+	if limit <= 0 {
+		return ""
+	}
+	if int64(len(s)) <= limit {
+		return s
+	}
+	cut := int(limit)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// spillValue writes value to a new file under $RUNNER_TEMP, or the system
+// temporary directory outside the runner, readable only by the owner, and
+// returns its path. A file it fails to write is removed, so no partial copy of
+// the value is left behind.
+func spillValue(key, value string) (string, error) {
+	dir := os.Getenv(runnerTempVar)
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	file, err := os.CreateTemp(dir, keyname.Convert(key, keyname.StyleSanitize)+"-*.txt")
+	if err != nil {
+		return "", &sizeLimitError{fmt.Sprintf(errSpillFailed, key, err)}
+	}
+	_, err = file.WriteString(value)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", &sizeLimitError{fmt.Sprintf(errSpillFailed, key, err)}
+	}
+	return filepath.Clean(file.Name()), nil
+}
@@ -0,0 +1,235 @@
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/summary"
+)
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit int64
+		want  string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"},
+		{"héllo", 3, "hé"},
+		{"hello", 0, ""},
+		{"hello", -5, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateUTF8(tt.s, tt.limit); got != tt.want {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.s, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSizeBudgetFit(t *testing.T) {
+	big := strings.Repeat("x", maxValueSize+1)
+
+	t.Run("Small values fit", func(t *testing.T) {
+		b, err := newSizeBudget("", filepath.Join(t.TempDir(), "missing"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, resized, err := b.fit("KEY", "value")
+		if err != nil || value != "value" || resized != nil {
+			t.Errorf("fit() = %q, %v, %v", value, resized, err)
+		}
+		if want := entryOverhead("KEY") + 5; b.used != want {
+			t.Errorf("used = %d, want %d", b.used, want)
+		}
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		b, _ := newSizeBudget(SizeLimitFail, "out")
+		_, _, err := b.fit("BIG", big)
+		if !isSizeLimitError(err) || !strings.Contains(err.Error(), "value of BIG is 1048577 bytes, over the 1048576 byte limit") {
+			t.Errorf("fit() error = %v", err)
+		}
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		b, _ := newSizeBudget(SizeLimitWarn, "out")
+		var value string
+		out := captureStdout(t, func() {
+			value, _, _ = b.fit("BIG", big)
+		})
+		if value != big || !strings.Contains(out, "Warning: value of BIG is 1048577 bytes") {
+			t.Errorf("fit() kept %d bytes, output %q", len(value), out)
+		}
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		b, _ := newSizeBudget("TRUNCATE", "out")
+		var value string
+		var resized *resizedValue
+		captureStdout(t, func() {
+			value, resized, _ = b.fit("BIG", big)
+		})
+		if len(value) != maxValueSize || resized == nil || resized.action != "truncated" {
+			t.Errorf("fit() = %d bytes, %+v", len(value), resized)
+		}
+	})
+
+	t.Run("Spill", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv(runnerTempVar, dir)
+		b, _ := newSizeBudget(SizeLimitSpill, "out")
+		var value string
+		var resized *resizedValue
+		var err error
+		captureStdout(t, func() {
+			value, resized, err = b.fit("app.config", big)
+		})
+		if err != nil {
+			t.Fatalf("fit() unexpected error: %v", err)
+		}
+		if filepath.Dir(value) != dir || !strings.HasPrefix(filepath.Base(value), "app_config-") {
+			t.Errorf("fit() path = %q, want a file in %q", value, dir)
+		}
+		content, err := os.ReadFile(value)
+		if err != nil || string(content) != big {
+			t.Errorf("spilled file has %d bytes, err %v", len(content), err)
+		}
+		if info, _ := os.Stat(value); info.Mode().Perm() != 0600 {
+			t.Errorf("spilled file mode = %v, want 0600", info.Mode().Perm())
+		}
+		if resized == nil || resized.action != "spilled" || resized.value != value {
+			t.Errorf("resized = %+v", resized)
+		}
+	})
+
+	t.Run("Spill failure", func(t *testing.T) {
+		t.Setenv(runnerTempVar, filepath.Join(t.TempDir(), "missing"))
+		b, _ := newSizeBudget(SizeLimitSpill, "out")
+		_, _, err := b.fit("BIG", big)
+		if err == nil || !strings.Contains(err.Error(), "failed to spill value of BIG") {
+			t.Fatalf("fit() error = %v, want the spill error", err)
+		}
+		if isRetryable(err) {
+			t.Errorf("fit() error %v is retryable", err)
+		}
+	})
+
+	t.Run("Existing file contents count", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "github_output")
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Truncate(path, maxFileSize-300); err != nil {
+			t.Fatal(err)
+		}
+		b, err := newSizeBudget(SizeLimitTruncate, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := b.fit("A", "ok"); err != nil {
+			t.Fatalf("fit() unexpected error: %v", err)
+		}
+
+		var value string
+		var out string
+		out = captureStdout(t, func() {
+			value, _, err = b.fit("B", strings.Repeat("y", 200))
+		})
+		if err != nil {
+			t.Fatalf("fit() unexpected error: %v", err)
+		}
+		if b.used != maxFileSize || len(value) == 0 || len(value) >= 200 {
+			t.Errorf("fit() kept %d bytes, used %d", len(value), b.used)
+		}
+		if !strings.Contains(out, "over the 52428800 byte limit") {
+			t.Errorf("fit() output %q missing file limit warning", out)
+		}
+	})
+
+	t.Run("Invalid policy", func(t *testing.T) {
+		if _, err := newSizeBudget("drop", "out"); !isSizeLimitError(err) || !strings.Contains(err.Error(), `unsupported size_limit_policy "drop"`) {
+			t.Errorf("newSizeBudget() error = %v", err)
+		}
+	})
+}
+
+func TestSetOutputSizeLimit(t *testing.T) {
+	big := strings.Repeat("z", maxValueSize+10)
+
+	t.Run("Fail writes nothing and does not retry", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "github_output")
+		t.Setenv(githubOutputVar, outputFile)
+		cfg := &config.Config{OutputKeys: "SMALL,BIG", OutputValues: "ok," + big, Delimiter: ","}
+
+		start := time.Now()
+		var err error
+		captureStdout(t, func() {
+			_, err = SetOutput(cfg)
+		})
+		if err == nil || !strings.Contains(err.Error(), "value of BIG is 1048586 bytes") {
+			t.Fatalf("SetOutput() error = %v, want size limit error", err)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("SetOutput() took %v, want no retries", elapsed)
+		}
+		content, _ := os.ReadFile(outputFile)
+		if strings.Contains(string(content), "SMALL<<") {
+			t.Errorf("SetOutput() wrote values despite the size error: %q", content)
+		}
+	})
+
+	t.Run("Spill sets the path and reports it", func(t *testing.T) {
+		dir := t.TempDir()
+		outputFile := filepath.Join(dir, "github_output")
+		t.Setenv(githubOutputVar, outputFile)
+		t.Setenv(runnerTempVar, dir)
+		cfg := &config.Config{OutputKeys: "BIG", OutputValues: big, Delimiter: ",", SizeLimitPolicy: SizeLimitSpill}
+
+		report := summary.NewReport()
+		step := NewStep(cfg)
+		step.Record(report, nil)
+		captureStdout(t, func() {
+			if _, err := step.SetOutput(); err != nil {
+				t.Fatalf("SetOutput() unexpected error: %v", err)
+			}
+		})
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(content) > 1000 || !strings.Contains(string(content), dir+string(filepath.Separator)+"BIG-") {
+			t.Errorf("SetOutput() file content = %.200q, want the spill path", content)
+		}
+
+		entries := report.Entries()
+		if len(entries) != 1 || !strings.HasPrefix(entries[0].Value, dir) || !strings.Contains(strings.Join(entries[0].Transformations, ","), "spilled") {
+			t.Errorf("report entries = %.300v", entries)
+		}
+	})
+
+	t.Run("Failed write removes spilled values", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv(githubOutputVar, filepath.Join(dir, "missing", "github_output"))
+		t.Setenv(runnerTempVar, dir)
+		cfg := &config.Config{OutputKeys: "BIG", OutputValues: big, Delimiter: ",", SizeLimitPolicy: SizeLimitSpill}
+
+		var err error
+		captureStdout(t, func() {
+			_, err = SetOutput(cfg)
+		})
+		if err == nil {
+			t.Fatal("SetOutput() expected a write error")
+		}
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), "BIG-") {
+				t.Errorf("spilled file %s left behind after the failed write", entry.Name())
+			}
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package writer
+
+import (
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/jsonschema"
+	"github.com/somaz94/env-output-setter/internal/summary"
+)
+
+// Step sets every destination of one action run from a single configuration,
+// sharing what CheckConfig prepared (such as the compiled json_schema) between
+// them so it is not rebuilt for each destination.
+type Step struct {
+	cfg       *config.Config
+	schemas   map[string]*jsonschema.Schema // Compiled json_schema; nil when unset or not compiled yet
+	report    *summary.Report               // Optional record of every key written, for the step summary
+	exported  *ExportValues                 // Optional record of the env and output values written, for export_file
+	processed map[string]processedInputs    // Inputs processed by destination variable, for every writer of the step
+}
+
+// NewStep returns a Step for cfg without checking it first; configuration
+// errors are then reported when the destination they affect is set. Use
+// CheckConfig to check cfg before anything is written.
+func NewStep(cfg *config.Config) *Step {
+	return &Step{cfg: cfg, processed: make(map[string]processedInputs)}
+}
+
+// Record makes the step record every key it sets in report and the env and
+// output values it writes in exported, either of which may be nil.
+func (s *Step) Record(report *summary.Report, exported *ExportValues) {
+	s.report = report
+	s.exported = exported
+}
+
+// newWriter returns a Writer for one destination of the step.
+func (s *Step) newWriter() *Writer {
+	w := NewWriter(s.cfg)
+	w.report = s.report
+	w.exported = s.exported
+	w.processor.schemas = s.schemas
+	w.processed = s.processed
+	return w
+}
+
+// CheckExpressions evaluates validation_expressions before anything is
+// written, against the final values of every env, output and state key the
+// step sets. Values are trimmed and transformed the way they are written and
+// keys renamed as key_autofix renames them; when a key is set in several
+// places the env value wins, then the output value. Inputs that fail to
+// process are skipped here and reported when their destination is set.
+func (s *Step) CheckExpressions() error {
+	if strings.TrimSpace(s.cfg.ValidationExpr) == "" {
+		return nil
+	}
+
+	w := s.newWriter()
+	values := make(map[string]string)
+	w.addFinalValues(values, githubEnvVar, envFileType)
+	w.addFinalValues(values, githubOutputVar, outputFileType)
+	w.addFinalValues(values, githubStateVar, stateFileType)
+
+	err := w.validator.ValidateExpressions(values)
+	annotateValidationErrors(err)
+	return err
+}
+
+// SetEnv writes the env_key and env_value inputs to the GITHUB_ENV file.
+func (s *Step) SetEnv() (int, error) {
+	return s.newWriter().setVariables(githubEnvVar, envFileType)
+}
+
+// SetOutput writes the output_key and output_value inputs to the
+// GITHUB_OUTPUT file and, with export_as_env, to the GITHUB_ENV file as well.
+func (s *Step) SetOutput() (int, error) {
+	w := s.newWriter()
+
+	// Set output variables
+	count, err := w.setVariables(githubOutputVar, outputFileType)
+	if err != nil {
+		return count, err
+	}
+
+	// Export output variables as environment variables if enabled
+	if s.cfg.ExportAsEnv {
+		return w.exportOutputAsEnv(count)
+	}
+
+	return count, nil
+}
+
+// ExportFile writes the env and output values the step recorded to
+// export_file (see ExportFile).
+func (s *Step) ExportFile() (int, error) {
+	return ExportFile(s.cfg, s.exported)
+}
+
+// SetPath prepends the path_entries directories to PATH via the GITHUB_PATH
+// file.
+func (s *Step) SetPath() (int, error) {
+	if strings.TrimSpace(s.cfg.PathEntries) == "" {
+		return 0, nil
+	}
+	return s.newWriter().setPathEntries()
+}
+
+// SetState writes the state_key and state_value inputs to the GITHUB_STATE
+// file, where post-step actions read them back.
+func (s *Step) SetState() (int, error) {
+	if strings.TrimSpace(s.cfg.StateKeys) == "" && strings.TrimSpace(s.cfg.StateValues) == "" {
+		return 0, nil
+	}
+	return s.newWriter().setVariables(githubStateVar, stateFileType)
+}
@@ -0,0 +1,56 @@
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/somaz94/env-output-setter/internal/config"
+)
+
+func TestStepProcessesInputsOnce(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "github_env")
+	outputFile := filepath.Join(dir, "github_output")
+	t.Setenv(githubEnvVar, envFile)
+	t.Setenv(githubOutputVar, outputFile)
+
+	cfg := &config.Config{
+		EnvKeys:             "RESP",
+		EnvValues:           `{"registry":"ghcr.io/acme"}`,
+		JsonSelect:          "REGISTRY=RESP.registry",
+		OutputKeys:          "IMAGE",
+		OutputValues:        "${REGISTRY}/app",
+		Delimiter:           "|",
+		EnableInterpolation: true,
+		InterpolateEnvKeys:  true,
+		ExportAsEnv:         true,
+		ValidationExpr:      `REGISTRY == "ghcr.io/acme"`,
+		DebugMode:           true,
+	}
+
+	step := NewStep(cfg)
+	output := captureStdout(t, func() {
+		if err := step.CheckExpressions(); err != nil {
+			t.Fatalf("CheckExpressions() unexpected error: %v", err)
+		}
+		if _, err := step.SetEnv(); err != nil {
+			t.Fatalf("SetEnv() unexpected error: %v", err)
+		}
+		if _, err := step.SetOutput(); err != nil {
+			t.Fatalf("SetOutput() unexpected error: %v", err)
+		}
+	})
+
+	if got := strings.Count(output, "Selected REGISTRY from RESP"); got != 1 {
+		t.Errorf("env inputs processed %d times, want 1; output:\n%s", got, output)
+	}
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(content), "\nghcr.io/acme/app\n") {
+		t.Errorf("output file = %q, want the interpolated image", content)
+	}
+}
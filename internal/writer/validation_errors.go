@@ -0,0 +1,183 @@
+package writer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/somaz94/env-output-setter/internal/printer"
+)
+
+// Supported validation_mode values
+const (
+	ValidationModeFailFast = "fail_fast"
+	ValidationModeCollect  = "collect"
+)
+
+const errInvalidValidationMode = "unsupported validation_mode %q (expected fail_fast or collect)"
+
+// Checks that can fail for a key
+const (
+	CheckEmpty         = "empty"
+	CheckDuplicate     = "duplicate"
+	CheckRequired      = "required"
+	CheckType          = "type"
+	CheckRange         = "range"
+	CheckLength        = "length"
+	CheckPattern       = "pattern"
+	CheckAllowedValues = "allowed_values"
+)
+
+// ValidationError is a single validation problem of one key.
+type ValidationError struct {
+	Key     string `json:"key"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// Error returns the message of the problem.
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors holds every problem found in collect mode, in the order
+// they were found.
+type ValidationErrors struct {
+	Errors []*ValidationError
+}
+
+// Error renders the problems as a list grouped by key.
+func (e *ValidationErrors) Error() string {
+	var b strings.Builder
+	if len(e.Errors) == 1 {
+		b.WriteString("1 validation error:")
+	} else {
+		fmt.Fprintf(&b, "%d validation errors:", len(e.Errors))
+	}
+	for _, group := range e.byKey() {
+		fmt.Fprintf(&b, "\n  %s:", displayKey(group[0].Key))
+		for _, verr := range group {
+			fmt.Fprintf(&b, "\n    - %s", verr.Message)
+		}
+	}
+	return b.String()
+}
+
+// JSON renders the problems as a single-line JSON document for the
+// error_message output. message describes where validation failed.
+func (e *ValidationErrors) JSON(message string) string {
+	data, err := json.Marshal(struct {
+		Message string             `json:"message"`
+		Count   int                `json:"count"`
+		Errors  []*ValidationError `json:"errors"`
+	}{message, len(e.Errors), e.Errors})
+	if err != nil {
+		return message
+	}
+	return string(data)
+}
+
+// Annotate prints one ::error:: workflow command per key so every failing key
+// shows up as an annotation on the run.
+func (e *ValidationErrors) Annotate() {
+	for _, group := range e.byKey() {
+		messages := make([]string, len(group))
+		for i, verr := range group {
+			messages[i] = verr.Message
+		}
+		printer.PrintWorkflowCommand("error", displayKey(group[0].Key)+": "+strings.Join(messages, "; "))
+	}
+}
+
+// annotateValidationErrors annotates the failing keys when err holds
+// collected validation errors.
+func annotateValidationErrors(err error) {
+	var collected *ValidationErrors
+	if errors.As(err, &collected) {
+		collected.Annotate()
+	}
+}
+
+// mergeValidationErrors combines the results of several validation steps.
+// The first error that is not *ValidationErrors is returned as is; otherwise
+// every collected problem is returned together, or nil if there are none.
+func mergeValidationErrors(errs ...error) error {
+	var all []*ValidationError
+	for _, err := range errs {
+		var collected *ValidationErrors
+		if errors.As(err, &collected) {
+			all = append(all, collected.Errors...)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	return &ValidationErrors{Errors: all}
+}
+
+// byKey groups the problems by key, keeping the order in which keys first failed.
+func (e *ValidationErrors) byKey() [][]*ValidationError {
+	index := make(map[string]int)
+	var groups [][]*ValidationError
+	for _, verr := range e.Errors {
+		i, ok := index[verr.Key]
+		if !ok {
+			i = len(groups)
+			index[verr.Key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], verr)
+	}
+	return groups
+}
+
+// displayKey names a key in reports, including the empty key.
+func displayKey(key string) string {
+	if key == "" {
+		return "(empty key)"
+	}
+	return key
+}
+
+// errorCollector records validation problems. In fail_fast mode add returns
+// the problem at once; in collect mode it keeps it for result.
+type errorCollector struct {
+	collect bool
+	errs    []*ValidationError
+}
+
+// newErrorCollector creates a collector for the given validation_mode.
+func newErrorCollector(mode string) (*errorCollector, error) {
+	switch strings.ToLower(mode) {
+	case "", ValidationModeFailFast:
+		return &errorCollector{}, nil
+	case ValidationModeCollect:
+		return &errorCollector{collect: true}, nil
+	default:
+		return nil, fmt.Errorf(errInvalidValidationMode, mode)
+	}
+}
+
+// add records a problem of key. It returns a non-nil error only when
+// validation must stop now.
+func (c *errorCollector) add(key, check, message string) error {
+	verr := &ValidationError{Key: key, Check: check, Message: message}
+	if !c.collect {
+		return verr
+	}
+	c.errs = append(c.errs, verr)
+	return nil
+}
+
+// result returns the collected problems as *ValidationErrors, or nil.
+func (c *errorCollector) result() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return &ValidationErrors{Errors: c.errs}
+}
@@ -0,0 +1,99 @@
+package writer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func sampleValidationErrors() *ValidationErrors {
+	return &ValidationErrors{Errors: []*ValidationError{
+		{Key: "PORT", Check: CheckPattern, Message: `validation failed for key "PORT": PORT must be numeric`},
+		{Key: "", Check: CheckEmpty, Message: "empty value found for key: "},
+		{Key: "PORT", Check: CheckDuplicate, Message: "duplicate key found: PORT"},
+	}}
+}
+
+func TestValidationErrorsError(t *testing.T) {
+	want := `3 validation errors:
+  PORT:
+    - validation failed for key "PORT": PORT must be numeric
+    - duplicate key found: PORT
+  (empty key):
+    - empty value found for key: `
+	if got := sampleValidationErrors().Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	single := &ValidationErrors{Errors: []*ValidationError{{Key: "A", Check: CheckEmpty, Message: "empty value found for key: A"}}}
+	if got := single.Error(); !strings.HasPrefix(got, "1 validation error:\n  A:") {
+		t.Errorf("Error() = %q, want singular heading", got)
+	}
+}
+
+func TestValidationErrorsJSON(t *testing.T) {
+	got := sampleValidationErrors().JSON("Error setting output variables")
+	if strings.Contains(got, "\n") {
+		t.Errorf("JSON() = %q, want a single line", got)
+	}
+
+	var decoded struct {
+		Message string            `json:"message"`
+		Count   int               `json:"count"`
+		Errors  []ValidationError `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+		t.Fatalf("JSON() produced invalid JSON %q: %v", got, err)
+	}
+	if decoded.Message != "Error setting output variables" || decoded.Count != 3 || len(decoded.Errors) != 3 {
+		t.Errorf("JSON() decoded = %+v", decoded)
+	}
+	if decoded.Errors[0].Key != "PORT" || decoded.Errors[0].Check != CheckPattern {
+		t.Errorf("JSON() first error = %+v", decoded.Errors[0])
+	}
+}
+
+func TestValidationErrorsAnnotate(t *testing.T) {
+	out := captureStdout(t, func() {
+		sampleValidationErrors().Annotate()
+	})
+	want := "::error::PORT: validation failed for key \"PORT\": PORT must be numeric; duplicate key found: PORT\n" +
+		"::error::(empty key): empty value found for key: \n"
+	if out != want {
+		t.Errorf("Annotate() output = %q, want %q", out, want)
+	}
+}
+
+func TestNewErrorCollector(t *testing.T) {
+	tests := []struct {
+		mode    string
+		collect bool
+		wantErr bool
+	}{
+		{mode: "", collect: false},
+		{mode: "fail_fast", collect: false},
+		{mode: "COLLECT", collect: true},
+		{mode: "all", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			c, err := newErrorCollector(tt.mode)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "unsupported validation_mode") {
+					t.Errorf("newErrorCollector() error = %v, want unsupported validation_mode", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newErrorCollector() unexpected error: %v", err)
+			}
+			if c.collect != tt.collect {
+				t.Errorf("newErrorCollector() collect = %v, want %v", c.collect, tt.collect)
+			}
+			if c.result() != nil {
+				t.Error("result() of an empty collector should be nil")
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/somaz94/env-output-setter/internal/config"
 )
@@ -15,6 +17,7 @@ const (
 	errEmptyValue       = "empty value found for key: %s"
 	errDuplicateKey     = "duplicate key found: %s"
 	errValidationFailed = "validation failed for key %q: %s"
+	errInvalidRule      = "invalid validation rule for key %q: %s"
 )
 
 // Validator handles input validation logic.
@@ -36,10 +39,21 @@ func (v *Validator) ValidatePairs(keys, values []string) error {
 	return nil
 }
 
+// Validate runs ValidateInputs and ValidateOutputs. In collect mode the
+// problems found by both are returned together as *ValidationErrors.
+func (v *Validator) Validate(keys, values []string) error {
+	return mergeValidationErrors(v.ValidateInputs(keys, values), v.ValidateOutputs(keys, values))
+}
+
 // ValidateInputs checks for empty values and duplicate keys based on configuration.
 // It does not mutate the caller's slices; whitespace trimming is applied to local
 // copies only (the processor pipeline already trims before this point).
+// In collect mode every problem is returned as *ValidationErrors.
 func (v *Validator) ValidateInputs(keys, values []string) error {
+	collector, err := newErrorCollector(v.cfg.ValidationMode)
+	if err != nil {
+		return err
+	}
 	seenKeys := make(map[string]struct{})
 
 	for i, key := range keys {
@@ -56,19 +70,23 @@ func (v *Validator) ValidateInputs(keys, values []string) error {
 
 		// Check for empty values if configured to fail
 		if v.cfg.FailOnEmpty && !v.cfg.AllowEmpty && (key == "" || values[i] == "") {
-			return fmt.Errorf(errEmptyValue, key)
+			if err := collector.add(key, CheckEmpty, fmt.Sprintf(errEmptyValue, key)); err != nil {
+				return err
+			}
 		}
 
 		// Check for duplicate keys if configured
 		if v.cfg.ErrorOnDuplicate {
 			if _, ok := seenKeys[lookupKey]; ok {
-				return fmt.Errorf(errDuplicateKey, key)
+				if err := collector.add(key, CheckDuplicate, fmt.Sprintf(errDuplicateKey, key)); err != nil {
+					return err
+				}
 			}
 			seenKeys[lookupKey] = struct{}{}
 		}
 	}
 
-	return nil
+	return collector.result()
 }
 
 // ValidationRule defines a validation rule for a specific key.
@@ -76,9 +94,17 @@ type ValidationRule struct {
 	Pattern       string   `json:"pattern"`        // Regex pattern the value must match
 	AllowedValues []string `json:"allowed_values"` // List of allowed values
 	Message       string   `json:"message"`        // Custom error message
+	Type          string   `json:"type"`           // Value type, e.g. int, url or semver
+	Min           *float64 `json:"min"`            // Smallest allowed number
+	Max           *float64 `json:"max"`            // Largest allowed number
+	MinLength     *int     `json:"min_length"`     // Fewest allowed characters
+	MaxLength     *int     `json:"max_length"`     // Most allowed characters
+	Required      bool     `json:"required"`       // Value must not be blank, even with allow_empty
 }
 
 // ParseValidationRules parses a JSON string into a map of validation rules.
+// Rules that cannot be applied, such as an unknown type or min above max,
+// are reported here rather than when a value is checked.
 func ParseValidationRules(rulesJSON string) (map[string]ValidationRule, error) {
 	if rulesJSON == "" {
 		return nil, nil
@@ -88,58 +114,153 @@ func ParseValidationRules(rulesJSON string) (map[string]ValidationRule, error) {
 	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
 		return nil, fmt.Errorf("failed to parse validation rules: %w", err)
 	}
+
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if msg := rules[key].definitionError(); msg != "" {
+			return nil, fmt.Errorf(errInvalidRule, key, msg)
+		}
+	}
 	return rules, nil
 }
 
+// definitionError describes why the rule cannot be applied, or returns "".
+func (r ValidationRule) definitionError() string {
+	if r.Type != "" {
+		if _, ok := valueTypes[r.Type]; !ok {
+			return fmt.Sprintf("unsupported type %q (expected one of %s)", r.Type, supportedTypes())
+		}
+	}
+	if (r.Min != nil || r.Max != nil) && r.Type != "" && r.Type != TypeInt && r.Type != TypeFloat {
+		return fmt.Sprintf("min and max require type int or float, not %q", r.Type)
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Sprintf("min %s is greater than max %s", formatNumber(*r.Min), formatNumber(*r.Max))
+	}
+	if (r.MinLength != nil && *r.MinLength < 0) || (r.MaxLength != nil && *r.MaxLength < 0) {
+		return "min_length and max_length must not be negative"
+	}
+	if r.MinLength != nil && r.MaxLength != nil && *r.MinLength > *r.MaxLength {
+		return fmt.Sprintf("min_length %d is greater than max_length %d", *r.MinLength, *r.MaxLength)
+	}
+	return ""
+}
+
 // ValidateOutputs validates key-value pairs against the configured validation rules.
+// Each key is checked against the single rule RuleSet.Match selects for it.
+// In collect mode every failed rule is returned as *ValidationErrors; invalid
+// rules are reported at once in either mode.
 func (v *Validator) ValidateOutputs(keys, values []string) error {
 	if v.cfg.ValidationRules == "" {
 		return nil
 	}
 
-	rules, err := ParseValidationRules(v.cfg.ValidationRules)
+	collector, err := newErrorCollector(v.cfg.ValidationMode)
+	if err != nil {
+		return err
+	}
+	rules, err := ParseRuleSet(v.cfg.ValidationRules)
 	if err != nil {
 		return err
 	}
 
 	for i, key := range keys {
-		rule, exists := rules[key]
+		rule, _, _, exists := rules.Match(key)
 		if !exists {
 			continue
 		}
+		if err := checkRule(collector, key, values[i], rule); err != nil {
+			return err
+		}
+	}
+
+	return collector.result()
+}
+
+// checkRule applies every check of rule to value, reporting failures to
+// collector. Type and range checks are skipped for empty values; use
+// required to reject those.
+func checkRule(collector *errorCollector, key, value string, rule ValidationRule) error {
+	fail := func(check, msg string) error {
+		if rule.Message != "" {
+			msg = rule.Message
+		}
+		return collector.add(key, check, fmt.Sprintf(errValidationFailed, key, msg))
+	}
 
-		value := values[i]
+	// Check required
+	if rule.Required && strings.TrimSpace(value) == "" {
+		return fail(CheckRequired, "value is required")
+	}
 
-		// Check regex pattern
-		if rule.Pattern != "" {
-			matched, err := regexp.MatchString(rule.Pattern, value)
-			if err != nil {
-				return fmt.Errorf("invalid regex pattern for key %q: %w", key, err)
+	// Check type and range
+	if value != "" {
+		if rule.Type != "" {
+			vt := valueTypes[rule.Type]
+			if !vt.valid(value) {
+				return fail(CheckType, fmt.Sprintf("value %q is not a valid %s (expected %s)", value, rule.Type, vt.expects))
 			}
-			if !matched {
-				msg := rule.Message
-				if msg == "" {
-					msg = fmt.Sprintf("value %q does not match pattern %q", value, rule.Pattern)
+		}
+		if rule.Min != nil || rule.Max != nil {
+			n, ok := parseFloat(value)
+			if !ok {
+				if err := fail(CheckType, fmt.Sprintf("value %q is not a number", value)); err != nil {
+					return err
+				}
+			} else if rule.Min != nil && n < *rule.Min {
+				if err := fail(CheckRange, fmt.Sprintf("value %s is less than min %s", value, formatNumber(*rule.Min))); err != nil {
+					return err
+				}
+			} else if rule.Max != nil && n > *rule.Max {
+				if err := fail(CheckRange, fmt.Sprintf("value %s is greater than max %s", value, formatNumber(*rule.Max))); err != nil {
+					return err
 				}
-				return fmt.Errorf(errValidationFailed, key, msg)
 			}
 		}
+	}
 
-		// Check allowed values
-		if len(rule.AllowedValues) > 0 {
-			found := false
-			for _, allowed := range rule.AllowedValues {
-				if value == allowed {
-					found = true
-					break
-				}
+	// Check length
+	length := utf8.RuneCountInString(value)
+	if rule.MinLength != nil && length < *rule.MinLength {
+		if err := fail(CheckLength, fmt.Sprintf("value has %d characters, fewer than min_length %d", length, *rule.MinLength)); err != nil {
+			return err
+		}
+	}
+	if rule.MaxLength != nil && length > *rule.MaxLength {
+		if err := fail(CheckLength, fmt.Sprintf("value has %d characters, more than max_length %d", length, *rule.MaxLength)); err != nil {
+			return err
+		}
+	}
+
+	// Check regex pattern
+	if rule.Pattern != "" {
+		matched, err := regexp.MatchString(rule.Pattern, value)
+		if err != nil {
+			return fmt.Errorf("invalid regex pattern for key %q: %w", key, err)
+		}
+		if !matched {
+			if err := fail(CheckPattern, fmt.Sprintf("value %q does not match pattern %q", value, rule.Pattern)); err != nil {
+				return err
 			}
-			if !found {
-				msg := rule.Message
-				if msg == "" {
-					msg = fmt.Sprintf("value %q is not in allowed values %v", value, rule.AllowedValues)
-				}
-				return fmt.Errorf(errValidationFailed, key, msg)
+		}
+	}
+
+	// Check allowed values
+	if len(rule.AllowedValues) > 0 {
+		found := false
+		for _, allowed := range rule.AllowedValues {
+			if value == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if err := fail(CheckAllowedValues, fmt.Sprintf("value %q is not in allowed values %v", value, rule.AllowedValues)); err != nil {
+				return err
 			}
 		}
 	}
@@ -507,3 +507,235 @@ func BenchmarkValidateInputs(b *testing.B) {
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestValidateCollectMode(t *testing.T) {
+	rules := `{"PORT":{"pattern":"^[0-9]+$","message":"PORT must be numeric"},"ENV":{"allowed_values":["prod","staging"]}}`
+	keys := []string{"PORT", "NAME", "ENV", "NAME"}
+	values := []string{"abc", "", "dev", "x"}
+
+	t.Run("Collect gathers every problem", func(t *testing.T) {
+		cfg := &config.Config{
+			FailOnEmpty:      true,
+			ErrorOnDuplicate: true,
+			CaseSensitive:    true,
+			ValidationRules:  rules,
+			ValidationMode:   ValidationModeCollect,
+		}
+		err := NewValidator(cfg).Validate(keys, values)
+		collected, ok := err.(*ValidationErrors)
+		if !ok {
+			t.Fatalf("Validate() error = %T %v, want *ValidationErrors", err, err)
+		}
+
+		var got []string
+		for _, verr := range collected.Errors {
+			got = append(got, verr.Key+":"+verr.Check)
+		}
+		want := "NAME:empty,NAME:duplicate,PORT:pattern,ENV:allowed_values"
+		if strings.Join(got, ",") != want {
+			t.Errorf("Validate() collected %v, want %s", got, want)
+		}
+	})
+
+	t.Run("Fail fast stops at the first problem", func(t *testing.T) {
+		cfg := &config.Config{
+			FailOnEmpty:      true,
+			ErrorOnDuplicate: true,
+			CaseSensitive:    true,
+			ValidationRules:  rules,
+		}
+		err := NewValidator(cfg).Validate(keys, values)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Fatalf("Validate() error = %T %v, want *ValidationError", err, err)
+		}
+		if verr.Key != "NAME" || verr.Error() != "empty value found for key: NAME" {
+			t.Errorf("Validate() error = %+v", verr)
+		}
+	})
+
+	t.Run("Invalid rules are not collected", func(t *testing.T) {
+		cfg := &config.Config{ValidationRules: `{"PORT":{"pattern":"[invalid"}}`, ValidationMode: ValidationModeCollect}
+		err := NewValidator(cfg).Validate([]string{"PORT"}, []string{"1"})
+		if err == nil || !strings.Contains(err.Error(), "invalid regex") {
+			t.Errorf("Validate() error = %v, want invalid regex", err)
+		}
+	})
+
+	t.Run("Invalid mode", func(t *testing.T) {
+		cfg := &config.Config{ValidationMode: "lenient"}
+		err := NewValidator(cfg).Validate([]string{"A"}, []string{"1"})
+		if err == nil || !strings.Contains(err.Error(), `unsupported validation_mode "lenient"`) {
+			t.Errorf("Validate() error = %v, want unsupported validation_mode", err)
+		}
+	})
+
+	t.Run("No problems", func(t *testing.T) {
+		cfg := &config.Config{FailOnEmpty: true, ErrorOnDuplicate: true, ValidationMode: ValidationModeCollect}
+		if err := NewValidator(cfg).Validate([]string{"A", "B"}, []string{"1", "2"}); err != nil {
+			t.Errorf("Validate() unexpected error: %v", err)
+		}
+	})
+}
+
+func TestValidateOutputsTypedRules(t *testing.T) {
+	tests := []struct {
+		name            string
+		value           string
+		validationRules string
+		errorContains   string
+	}{
+		{
+			name:            "Port in range",
+			value:           "8080",
+			validationRules: `{"KEY":{"type":"int","min":1,"max":65535}}`,
+		},
+		{
+			name:            "Port above max",
+			value:           "70000",
+			validationRules: `{"KEY":{"type":"int","min":1,"max":65535}}`,
+			errorContains:   `validation failed for key "KEY": value 70000 is greater than max 65535`,
+		},
+		{
+			name:            "Float below min",
+			value:           "0.05",
+			validationRules: `{"KEY":{"type":"float","min":0.1}}`,
+			errorContains:   "value 0.05 is less than min 0.1",
+		},
+		{
+			name:            "Range without type",
+			value:           "abc",
+			validationRules: `{"KEY":{"max":10}}`,
+			errorContains:   `value "abc" is not a number`,
+		},
+		{
+			name:            "Wrong type",
+			value:           "1.2",
+			validationRules: `{"KEY":{"type":"semver"}}`,
+			errorContains:   `value "1.2" is not a valid semver (expected a semantic version such as 1.2.3)`,
+		},
+		{
+			name:            "Valid URL",
+			value:           "https://example.com/api",
+			validationRules: `{"KEY":{"type":"url"}}`,
+		},
+		{
+			name:            "Empty value skips type check",
+			value:           "",
+			validationRules: `{"KEY":{"type":"int"}}`,
+		},
+		{
+			name:            "Required rejects blank value",
+			value:           "  ",
+			validationRules: `{"KEY":{"required":true,"type":"int"}}`,
+			errorContains:   "value is required",
+		},
+		{
+			name:            "Too short",
+			value:           "ab",
+			validationRules: `{"KEY":{"min_length":3}}`,
+			errorContains:   "value has 2 characters, fewer than min_length 3",
+		},
+		{
+			name:            "Too long counts characters",
+			value:           "héllo",
+			validationRules: `{"KEY":{"max_length":4}}`,
+			errorContains:   "value has 5 characters, more than max_length 4",
+		},
+		{
+			name:            "Custom message",
+			value:           "abc",
+			validationRules: `{"KEY":{"type":"duration","message":"KEY must be a duration"}}`,
+			errorContains:   "KEY must be a duration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ValidationRules: tt.validationRules}
+			err := NewValidator(cfg).ValidateOutputs([]string{"KEY"}, []string{tt.value})
+			if tt.errorContains == "" {
+				if err != nil {
+					t.Errorf("ValidateOutputs() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("ValidateOutputs() error = %v, want to contain %q", err, tt.errorContains)
+			}
+		})
+	}
+}
+
+func TestValidateOutputsTypedRulesCollect(t *testing.T) {
+	cfg := &config.Config{
+		ValidationRules: `{"PORT":{"type":"int","max":65535},"NAME":{"min_length":3,"pattern":"^[a-z]+$"}}`,
+		ValidationMode:  ValidationModeCollect,
+	}
+	err := NewValidator(cfg).ValidateOutputs([]string{"PORT", "NAME"}, []string{"70000", "A"})
+	collected, ok := err.(*ValidationErrors)
+	if !ok {
+		t.Fatalf("ValidateOutputs() error = %T %v, want *ValidationErrors", err, err)
+	}
+
+	var got []string
+	for _, verr := range collected.Errors {
+		got = append(got, verr.Key+":"+verr.Check)
+	}
+	if want := "PORT:range,NAME:length,NAME:pattern"; strings.Join(got, ",") != want {
+		t.Errorf("ValidateOutputs() collected %v, want %s", got, want)
+	}
+}
+
+func TestParseValidationRulesDefinitions(t *testing.T) {
+	tests := []struct {
+		name          string
+		rulesJSON     string
+		errorContains string
+	}{
+		{
+			name:      "Typed rule",
+			rulesJSON: `{"PORT":{"type":"int","min":1,"max":65535,"min_length":1,"max_length":5,"required":true}}`,
+		},
+		{
+			name:          "Unknown type",
+			rulesJSON:     `{"PORT":{"type":"integer"}}`,
+			errorContains: `invalid validation rule for key "PORT": unsupported type "integer" (expected one of base64, bool,`,
+		},
+		{
+			name:          "Range on a non-numeric type",
+			rulesJSON:     `{"VERSION":{"type":"semver","min":1}}`,
+			errorContains: `min and max require type int or float, not "semver"`,
+		},
+		{
+			name:          "Min above max",
+			rulesJSON:     `{"PORT":{"min":10,"max":1}}`,
+			errorContains: "min 10 is greater than max 1",
+		},
+		{
+			name:          "Negative length",
+			rulesJSON:     `{"NAME":{"min_length":-1}}`,
+			errorContains: "must not be negative",
+		},
+		{
+			name:          "Min length above max length",
+			rulesJSON:     `{"NAME":{"min_length":5,"max_length":2}}`,
+			errorContains: "min_length 5 is greater than max_length 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseValidationRules(tt.rulesJSON)
+			if tt.errorContains == "" {
+				if err != nil {
+					t.Errorf("ParseValidationRules() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("ParseValidationRules() error = %v, want to contain %q", err, tt.errorContains)
+			}
+		})
+	}
+}
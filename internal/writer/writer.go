@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/somaz94/env-output-setter/internal/config"
 	"github.com/somaz94/env-output-setter/internal/printer"
+	"github.com/somaz94/env-output-setter/internal/summary"
 	"github.com/somaz94/env-output-setter/internal/transformer"
 )
 
@@ -25,12 +27,16 @@ const (
 const (
 	envFileType    = "env"
 	outputFileType = "output"
+	pathFileType   = "path"
+	stateFileType  = "state"
 )
 
 // GitHub environment variables (aliased to the config package to avoid literal divergence)
 const (
 	githubEnvVar    = config.GithubEnvVar
 	githubOutputVar = config.GithubOutputVar
+	githubPathVar   = config.GithubPathVar
+	githubStateVar  = config.GithubStateVar
 )
 
 // Writer handles writing environment variables and outputs to GitHub Actions files.
@@ -38,50 +44,86 @@ type Writer struct {
 	cfg       *config.Config
 	processor *Processor
 	validator *Validator
+	masker    *Masker
+	report    *summary.Report            // Optional record of every key written, for the step summary
+	exported  *ExportValues              // Optional record of the env and output values written, for export_file
+	resized   map[string]resizedValue    // Values replaced by size_limit_policy, by key
+	processed map[string]processedInputs // Inputs processed by destination variable, shared by the writers of a step
+}
+
+// processedInputs is the result of processing the inputs of one destination.
+type processedInputs struct {
+	inputs  Inputs
+	keys    []string
+	values  []string
+	sources []string
+	err     error
 }
 
 // NewWriter creates a new Writer instance.
 func NewWriter(cfg *config.Config) *Writer {
+	masker := NewMasker(cfg, newTransformer(cfg))
+	processor := NewProcessor(cfg)
+	processor.masker = masker
 	return &Writer{
 		cfg:       cfg,
-		processor: NewProcessor(cfg),
+		processor: processor,
 		validator: NewValidator(cfg),
+		masker:    masker,
+		processed: make(map[string]processedInputs),
 	}
 }
 
+// newTransformer builds the value transformer from the configured options.
+func newTransformer(cfg *config.Config) *transformer.Transformer {
+	return transformer.New(transformer.Options{
+		MaskSecrets:    cfg.MaskSecrets,
+		MaskPattern:    cfg.MaskPattern,
+		MaskAll:        cfg.MaskAll,
+		ToUpper:        cfg.ToUpper,
+		ToLower:        cfg.ToLower,
+		EncodeURL:      cfg.EncodeURL,
+		EscapeNewlines: cfg.EscapeNewlines,
+		MaxLength:      cfg.MaxLength,
+		Pipeline:       cfg.GlobalTransforms,
+		Transforms:     cfg.Transforms,
+	})
+}
+
 // SetEnv sets environment variables in GitHub Actions environment file.
 // It processes the env_key and env_value inputs and writes them to the GITHUB_ENV file.
 func SetEnv(cfg *config.Config) (int, error) {
-	w := NewWriter(cfg)
-	return w.setVariables(githubEnvVar, envFileType)
+	return NewStep(cfg).SetEnv()
+}
+
+// SetState saves state values in GitHub Actions state file.
+// It processes the state_key and state_value inputs and writes them to the
+// GITHUB_STATE file, where post-step actions read them back.
+func SetState(cfg *config.Config) (int, error) {
+	return NewStep(cfg).SetState()
 }
 
 // SetOutput sets output variables in GitHub Actions output file.
 // It processes the output_key and output_value inputs and writes them to the GITHUB_OUTPUT file.
 // If export_as_env is enabled, it also exports the output variables as environment variables.
 func SetOutput(cfg *config.Config) (int, error) {
-	w := NewWriter(cfg)
-
-	// Set output variables
-	count, err := w.setVariables(githubOutputVar, outputFileType)
-	if err != nil {
-		return count, err
-	}
-
-	// Export output variables as environment variables if enabled
-	if cfg.ExportAsEnv {
-		return w.exportOutputAsEnv(count)
-	}
-
-	return count, nil
+	return NewStep(cfg).SetOutput()
 }
 
 // exportOutputAsEnv exports output variables as environment variables.
 // It reads the output variables and writes them to the environment file.
 func (w *Writer) exportOutputAsEnv(outputCount int) (int, error) {
-	keys, values := w.getInputValues(githubOutputVar)
-	keyList, valueList, err := w.processor.ProcessInputValues(keys, values)
+	processed := w.processInputs(githubOutputVar)
+	if processed.err != nil {
+		return outputCount, processed.err
+	}
+	keyList, valueList, sources := processed.keys, processed.values, processed.sources
+	w.masker.RegisterPairs(keyList, valueList)
+
+	// Exported outputs become env names, so they follow the env key rules
+	keyList, err := w.validator.CheckKeyNames(keyList, envFileType)
 	if err != nil {
+		annotateValidationErrors(err)
 		return outputCount, err
 	}
 
@@ -95,6 +137,7 @@ func (w *Writer) exportOutputAsEnv(outputCount int) (int, error) {
 	if err != nil {
 		return outputCount, err
 	}
+	w.recordWritten(summary.DestinationEnv, keyList, valueList, sources)
 
 	return outputCount + envCount, nil
 }
@@ -102,19 +145,20 @@ func (w *Writer) exportOutputAsEnv(outputCount int) (int, error) {
 // setVariables handles setting variables for both env and output files.
 // It's the core function that processes inputs and writes them to the appropriate file.
 func (w *Writer) setVariables(envVar, varType string) (int, error) {
-	// Get input values based on the variable type
-	keys, values := w.getInputValues(envVar)
-
-	// Log input values if debug mode is enabled
-	w.processor.LogInputValues(varType, keys, values)
-
 	// Process and validate input values
-	keyList, valueList, err := w.processor.ProcessInputValues(keys, values)
-	if err != nil {
-		return 0, err
+	processed := w.processInputs(envVar)
+	if processed.err != nil {
+		annotateValidationErrors(processed.err)
+		return 0, processed.err
 	}
+	inputs, keyList, valueList, sources := processed.inputs, processed.keys, processed.values, processed.sources
+
+	// Register sensitive values with the runner before anything is logged or
+	// written, so the debug output below is masked too
+	w.masker.RegisterPairs(keyList, valueList)
 
-	// Log processed values if debug mode is enabled
+	// Log input and processed values if debug mode is enabled
+	w.processor.LogInputValues(varType, inputs.Keys, inputs.Values)
 	w.processor.LogProcessedValues(keyList, valueList)
 
 	// Validate pairs match
@@ -122,13 +166,14 @@ func (w *Writer) setVariables(envVar, varType string) (int, error) {
 		return 0, err
 	}
 
-	// Validate input constraints (empty values, duplicates, etc.)
-	if err := w.validator.ValidateInputs(keyList, valueList); err != nil {
-		return 0, err
-	}
+	// Check key names against key_policy and key_denylist, renaming them when
+	// key_autofix is on
+	keyList, nameErr := w.validator.CheckKeyNames(keyList, varType)
 
-	// Validate output values against rules if configured
-	if err := w.validator.ValidateOutputs(keyList, valueList); err != nil {
+	// Validate input constraints (empty values, duplicates, etc.) and the
+	// configured rules; in collect mode every failing key is annotated
+	if err := mergeValidationErrors(nameErr, w.validator.Validate(keyList, valueList)); err != nil {
+		annotateValidationErrors(err)
 		return 0, err
 	}
 
@@ -136,30 +181,192 @@ func (w *Writer) setVariables(envVar, varType string) (int, error) {
 	filePath := os.Getenv(envVar)
 
 	// Handle local execution (not in GitHub Actions)
+	var count int
+	var err error
 	if filePath == "" {
-		return w.handleLocalExecution(envVar, varType, keyList, valueList)
+		count, err = w.handleLocalExecution(envVar, varType, keyList, valueList)
+	} else {
+		// Write variables to the file
+		count, err = w.writeToFile(filePath, keyList, valueList, varType)
+	}
+	if err != nil {
+		return count, err
 	}
 
-	// Write variables to the file
-	return w.writeToFile(filePath, keyList, valueList, varType)
+	w.recordWritten(reportDestination(varType), keyList, valueList, sources)
+	return count, nil
+}
+
+// reportDestination maps a file type to its step summary destination.
+func reportDestination(varType string) string {
+	switch varType {
+	case outputFileType:
+		return summary.DestinationOutput
+	case stateFileType:
+		return summary.DestinationState
+	case pathFileType:
+		return summary.DestinationPath
+	default:
+		return summary.DestinationEnv
+	}
+}
+
+// recordWritten adds the written keys to the report and, for env and output
+// values, to the export values, if either is attached. Values are recorded the
+// way performWrite writes them: trimmed, transformed and replaced as
+// size_limit_policy replaced them. The report masks them, so it never shows
+// more than the log does.
+func (w *Writer) recordWritten(destination string, keys, values, sources []string) {
+	exported := w.exported
+	if destination != summary.DestinationEnv && destination != summary.DestinationOutput {
+		exported = nil
+	}
+	if w.report == nil && exported == nil {
+		return
+	}
+
+	valueTransformer := newTransformer(w.cfg)
+	for i, key := range keys {
+		if (key == "" && !w.cfg.AllowEmpty) || i >= len(values) {
+			continue
+		}
+
+		k, v := key, values[i]
+		if w.cfg.TrimWhitespace {
+			k = strings.TrimSpace(k)
+			v = strings.TrimSpace(v)
+		}
+		source := summary.SourceLiteral
+		if i < len(sources) {
+			source = sources[i]
+		}
+
+		// PATH entries are written as given, without value transformations
+		var transformations []string
+		written := v
+		if destination != summary.DestinationPath {
+			transformations = valueTransformer.AppliedKeyTransformations(k, v, w.cfg.JsonSupport)
+			var err error
+			if written, err = valueTransformer.TransformKeyValue(k, v, w.cfg.JsonSupport); err != nil {
+				continue
+			}
+		}
+		if resized, ok := w.resized[k]; ok {
+			transformations = append(transformations, resized.action)
+			written = resized.value
+		}
+
+		if exported != nil {
+			exported.add(k, written)
+		}
+		if w.report != nil {
+			w.report.Add(summary.Entry{
+				Key:             k,
+				Destination:     destination,
+				Source:          source,
+				Transformations: transformations,
+				Value:           valueTransformer.MaskValue(written),
+			})
+		}
+	}
 }
 
 // getInputValues returns the appropriate keys and values based on the variable type.
-// It selects between env_key/env_value and output_key/output_value based on the envVar parameter.
+// It selects between env_key/env_value, output_key/output_value and
+// state_key/state_value based on the envVar parameter.
 func (w *Writer) getInputValues(envVar string) (string, string) {
 	switch envVar {
 	case githubEnvVar:
 		return w.cfg.EnvKeys, w.cfg.EnvValues
 	case githubOutputVar:
 		return w.cfg.OutputKeys, w.cfg.OutputValues
+	case githubStateVar:
+		return w.cfg.StateKeys, w.cfg.StateValues
 	default:
 		return "", ""
 	}
 }
 
+// processInputs processes the inputs of the destination selected by envVar.
+// The result is kept for every later check or write of that destination, so
+// its files are read and its warnings printed once. Callers must not modify
+// the returned slices.
+func (w *Writer) processInputs(envVar string) processedInputs {
+	if processed, ok := w.processed[envVar]; ok {
+		return processed
+	}
+	inputs := w.getInputs(envVar)
+	keys, values, sources, err := w.processor.ProcessInputsWithSources(inputs)
+	processed := processedInputs{inputs: inputs, keys: keys, values: values, sources: sources, err: err}
+	w.processed[envVar] = processed
+	return processed
+}
+
+// getInputs returns every input source for the destination selected by envVar.
+// The dotenv file (env_file) only feeds the environment variables; with
+// interpolate_env_keys, the final environment variables are interpolation
+// variables for the outputs.
+func (w *Writer) getInputs(envVar string) Inputs {
+	keys, values := w.getInputValues(envVar)
+	inputs := Inputs{Keys: keys, Values: values}
+	switch envVar {
+	case githubEnvVar:
+		inputs.Pairs = w.cfg.EnvPairs
+		inputs.EnvFile = w.cfg.EnvFile
+	case githubOutputVar:
+		inputs.Pairs = w.cfg.OutputPairs
+		if w.cfg.EnableInterpolation && w.cfg.InterpolateEnvKeys {
+			inputs.Variables = make(map[string]string)
+			w.addFinalValues(inputs.Variables, githubEnvVar, envFileType)
+		}
+	}
+	return inputs
+}
+
+// addFinalValues adds to values the keys the destination selected by envVar
+// will set, with the values as written: trimmed and transformed, with keys
+// renamed as key_autofix renames them. Keys already in values are kept. If the
+// inputs fail to process nothing is added; the error is reported when the
+// destination is set.
+func (w *Writer) addFinalValues(values map[string]string, envVar, varType string) {
+	processed := w.processInputs(envVar)
+	keys, vals := processed.keys, processed.values
+	if processed.err != nil || len(keys) != len(vals) {
+		return
+	}
+
+	valueTransformer := newTransformer(w.cfg)
+	for i, key := range w.validator.fixedKeyNames(keys, varType) {
+		value := vals[i]
+		if w.cfg.TrimWhitespace {
+			key = strings.TrimSpace(key)
+			value = strings.TrimSpace(value)
+		}
+		if _, seen := values[key]; key == "" || seen {
+			continue
+		}
+		if written, err := valueTransformer.TransformKeyValue(key, value, w.cfg.JsonSupport); err == nil {
+			values[key] = written
+		}
+	}
+}
+
 // handleLocalExecution handles variable setting when not running in GitHub Actions.
-// It prints values to the console instead of writing to a file.
+// It prints values to the console instead of writing to a file, failing like
+// performWrite if a value cannot be transformed.
 func (w *Writer) handleLocalExecution(envVar, varType string, keyList, valueList []string) (int, error) {
+	valueTransformer := newTransformer(w.cfg)
+	for i, key := range keyList {
+		k, v := key, valueList[i]
+		if w.cfg.TrimWhitespace {
+			k = strings.TrimSpace(k)
+			v = strings.TrimSpace(v)
+		}
+		if _, err := valueTransformer.TransformKeyValue(k, v, w.cfg.JsonSupport); err != nil {
+			return 0, err
+		}
+	}
+
 	fmt.Printf(localExecMsg, envVar, varType)
 	for i, key := range keyList {
 		printer.PrintSuccess(varType, key, valueList[i])
@@ -167,6 +374,12 @@ func (w *Writer) handleLocalExecution(envVar, varType string, keyList, valueList
 	return len(keyList), nil
 }
 
+// Write retries, for the failures a later attempt may not hit
+const (
+	maxWriteRetries = 3
+	writeRetryDelay = time.Second
+)
+
 // Status key names written to $GITHUB_ENV/$GITHUB_OUTPUT.
 const (
 	statusKey  = "action_status"
@@ -178,60 +391,101 @@ const (
 // writeToFile writes key-value pairs to a file with retry logic.
 // It builds the full payload in a buffer first and appends atomically per attempt,
 // so a failed attempt never leaves partial lines behind for the next retry to duplicate.
+// The status keys are not written to the state file, which post steps read back as-is.
 func (w *Writer) writeToFile(filePath string, keys, values []string, varType string) (int, error) {
-	maxRetries := 3
-	retryDelay := time.Second
-	var lastError error
+	writeStatus := varType != stateFileType
 
-	for retry := 0; retry < maxRetries; retry++ {
-		count, err := w.performWrite(filePath, keys, values, varType)
-		if err == nil {
-			// Success - write action status (best-effort)
-			if _, statusErr := w.performWrite(filePath, []string{statusKey}, []string{statusOK}, varType); statusErr != nil {
-				printer.PrintWarning(fmt.Sprintf("Warning: failed to write success status: %v", statusErr))
-			}
+	count, lastError := withRetries(func() (int, error) {
+		return w.performWrite(filePath, keys, values, varType)
+	})
+	if lastError == nil {
+		if !writeStatus {
 			return count, nil
 		}
-
-		lastError = err
-		if retry < maxRetries-1 {
-			printer.PrintError(fmt.Sprintf("Retry %d/%d: Failed to write to file: %v",
-				retry+1, maxRetries, err))
-			time.Sleep(retryDelay)
+		// Success - write action status (best-effort)
+		if _, statusErr := w.performWrite(filePath, []string{statusKey}, []string{statusOK}, varType); statusErr != nil {
+			printer.PrintWarning(fmt.Sprintf("Warning: failed to write success status: %v", statusErr))
 		}
+		return count, nil
 	}
 
-	// Write failure status after exhausting retries (best-effort)
-	failMsg := ""
-	if lastError != nil {
-		failMsg = lastError.Error()
+	if !writeStatus {
+		return 0, writeError(lastError, maxWriteRetries)
 	}
+
+	// Write failure status after exhausting retries (best-effort)
 	if _, err := w.performWrite(filePath,
 		[]string{statusKey, errMsgKey},
-		[]string{statusFail, failMsg},
+		[]string{statusFail, lastError.Error()},
 		varType); err != nil {
 		printer.PrintWarning(fmt.Sprintf("Warning: failed to write failure status: %v", err))
 	}
 
-	return 0, fmt.Errorf(errMaxRetries, maxRetries)
+	return 0, writeError(lastError, maxWriteRetries)
+}
+
+// withRetries runs write up to maxWriteRetries times, until it succeeds or
+// fails with an error retrying cannot fix, and returns the last result.
+func withRetries(write func() (int, error)) (int, error) {
+	var lastError error
+	for retry := 0; retry < maxWriteRetries; retry++ {
+		count, err := write()
+		if err == nil {
+			return count, nil
+		}
+
+		lastError = err
+		if !isRetryable(err) {
+			break
+		}
+		if retry < maxWriteRetries-1 {
+			printer.PrintError(fmt.Sprintf("Retry %d/%d: Failed to write to file: %v",
+				retry+1, maxWriteRetries, err))
+			time.Sleep(writeRetryDelay)
+		}
+	}
+	return 0, lastError
+}
+
+// isRetryable reports whether a failed write may succeed when retried. Size
+// limit and transform errors depend only on the values, so they never do.
+func isRetryable(err error) bool {
+	var transformErr *transformer.TransformationError
+	return !isSizeLimitError(err) && !errors.As(err, &transformErr)
+}
+
+// writeError returns the error of a failed writeToFile: errors that retrying
+// cannot fix as they are, anything else as a retry failure.
+func writeError(lastError error, maxRetries int) error {
+	if lastError != nil && !isRetryable(lastError) {
+		return lastError
+	}
+	return fmt.Errorf(errMaxRetries, maxRetries)
 }
 
 // performWrite writes key-value pairs to a file in GitHub Actions format.
 // All lines are serialized into an in-memory buffer first and flushed in a single
-// WriteString call so a partial failure leaves the file untouched (atomicity per call).
-// The file's Close error is propagated via the named return so disk-full / NFS errors
-// surface to the caller instead of being silently discarded.
+// write via appendPayload so a partial failure leaves the file untouched
+// (atomicity per call). Every value is checked against the runner's size
+// limits, counting what the file already holds, and handled according to
+// size_limit_policy. Values spilled by a failed call are removed again, so a
+// retry never leaves copies of them behind.
 func (w *Writer) performWrite(filePath string, keys, values []string, varType string) (count int, err error) {
 	// Build the full payload in memory before opening the file.
-	valueTransformer := transformer.New(transformer.Options{
-		MaskSecrets:    w.cfg.MaskSecrets,
-		MaskPattern:    w.cfg.MaskPattern,
-		ToUpper:        w.cfg.ToUpper,
-		ToLower:        w.cfg.ToLower,
-		EncodeURL:      w.cfg.EncodeURL,
-		EscapeNewlines: w.cfg.EscapeNewlines,
-		MaxLength:      w.cfg.MaxLength,
-	})
+	valueTransformer := newTransformer(w.cfg)
+	budget, err := newSizeBudget(w.cfg.SizeLimitPolicy, filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	var spilled []string
+	defer func() {
+		if err != nil {
+			for _, path := range spilled {
+				os.Remove(path)
+			}
+		}
+	}()
 
 	if w.cfg.DebugMode {
 		fmt.Printf("Writing Values:\n")
@@ -255,7 +509,23 @@ func (w *Writer) performWrite(filePath string, keys, values []string, varType st
 			v = strings.TrimSpace(v)
 		}
 
-		transformedValue := valueTransformer.TransformValue(v, w.cfg.JsonSupport)
+		transformedValue, terr := valueTransformer.TransformKeyValue(k, v, w.cfg.JsonSupport)
+		if terr != nil {
+			return 0, terr
+		}
+		transformedValue, resized, ferr := budget.fit(k, transformedValue)
+		if ferr != nil {
+			return 0, ferr
+		}
+		if resized != nil {
+			if resized.action == "spilled" {
+				spilled = append(spilled, resized.value)
+			}
+			if w.resized == nil {
+				w.resized = make(map[string]resizedValue)
+			}
+			w.resized[k] = *resized
+		}
 		if werr := appendGitHubActionsFormat(&buf, k, transformedValue); werr != nil {
 			return 0, werr
 		}
@@ -267,33 +537,42 @@ func (w *Writer) performWrite(filePath string, keys, values []string, varType st
 	}
 
 	// Now open the file and flush the buffer in one write.
+	if err := appendPayload(filePath, buf.Bytes()); err != nil {
+		return 0, err
+	}
+
+	for _, s := range successes {
+		printer.PrintSuccess(varType, s.key, s.masked)
+	}
+
+	if w.cfg.DebugMode {
+		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	}
+
+	return count, nil
+}
+
+// appendPayload appends payload to the file at filePath in a single write and
+// syncs it. The Close error is propagated so disk-full / NFS errors surface to
+// the caller instead of being silently discarded.
+func appendPayload(filePath string, payload []byte) (err error) {
 	file, openErr := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 	if openErr != nil {
-		return 0, fmt.Errorf("failed to open file: %w", openErr)
+		return fmt.Errorf("failed to open file: %w", openErr)
 	}
 	defer func() {
 		if cerr := file.Close(); cerr != nil && err == nil {
 			err = fmt.Errorf("failed to close file: %w", cerr)
-			count = 0
 		}
 	}()
 
-	if _, werr := file.Write(buf.Bytes()); werr != nil {
-		return 0, fmt.Errorf("failed to write payload: %w", werr)
+	if _, werr := file.Write(payload); werr != nil {
+		return fmt.Errorf("failed to write payload: %w", werr)
 	}
 	if serr := file.Sync(); serr != nil {
-		return 0, fmt.Errorf("failed to sync file: %w", serr)
+		return fmt.Errorf("failed to sync file: %w", serr)
 	}
-
-	for _, s := range successes {
-		printer.PrintSuccess(varType, s.key, s.masked)
-	}
-
-	if w.cfg.DebugMode {
-		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
-	}
-
-	return count, nil
+	return nil
 }
 
 // appendGitHubActionsFormat appends a key-value pair to buf in GitHub Actions multiline format.
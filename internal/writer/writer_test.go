@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/somaz94/env-output-setter/internal/config"
+	"github.com/somaz94/env-output-setter/internal/summary"
 )
 
 func TestNewWriter(t *testing.T) {
@@ -394,6 +396,104 @@ func TestSetEnvWithTransformations(t *testing.T) {
 	}
 }
 
+func TestSetEnvWithTransforms(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "github_env")
+	t.Setenv(githubEnvVar, envFile)
+
+	cfg := &config.Config{
+		EnvKeys:        "PREVIEW_NAME,OTHER",
+		EnvValues:      "Feature/Login Page,Feature/Login Page",
+		Delimiter:      ",",
+		TrimWhitespace: true,
+		Transforms:     "PREVIEW_*: lower | slugify | truncate(13)",
+	}
+
+	report := summary.NewReport()
+	step := NewStep(cfg)
+	step.Record(report, nil)
+	if _, err := step.SetEnv(); err != nil {
+		t.Fatalf("SetEnv() unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("Failed to read env file: %v", err)
+	}
+	if !strings.Contains(string(content), "\nfeature-login\n") || !strings.Contains(string(content), "\nFeature/Login Page\n") {
+		t.Errorf("env file = %q, want PREVIEW_NAME transformed and OTHER untouched", content)
+	}
+
+	entries := report.Entries()
+	if len(entries) != 2 || !reflect.DeepEqual(entries[0].Transformations, []string{"lower", "slugify", "truncate(13)"}) {
+		t.Errorf("report entries = %+v", entries)
+	}
+}
+
+func TestSetEnvTransformError(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "github_env")
+	t.Setenv(githubEnvVar, envFile)
+
+	cfg := &config.Config{
+		EnvKeys:    "TOKEN",
+		EnvValues:  "not base64!",
+		Delimiter:  ",",
+		Transforms: "TOKEN: base64_decode",
+	}
+
+	var err error
+	output := captureStdout(t, func() {
+		_, err = SetEnv(cfg)
+	})
+	if err == nil || !strings.Contains(err.Error(), "transform base64_decode failed for TOKEN") {
+		t.Fatalf("SetEnv() error = %v, want the failed transform", err)
+	}
+	if strings.Contains(output, "Retry") {
+		t.Errorf("SetEnv() retried a transform error: %q", output)
+	}
+	content, _ := os.ReadFile(envFile)
+	if strings.Contains(string(content), "TOKEN<<") {
+		t.Errorf("env file = %q, want TOKEN not written", content)
+	}
+}
+
+func TestSetOutputInterpolateEnvKeys(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "github_output")
+	t.Setenv(githubOutputVar, outputFile)
+
+	for _, tt := range []struct {
+		name     string
+		enabled  bool
+		expected string
+	}{
+		{"Enabled", true, "\nghcr.io/acme/app\n"},
+		{"Disabled", false, "\n/app\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(outputFile)
+			cfg := &config.Config{
+				EnvKeys:             "TEST_REGISTRY",
+				EnvValues:           "ghcr.io/acme",
+				OutputKeys:          "IMAGE",
+				OutputValues:        "${TEST_REGISTRY}/app",
+				Delimiter:           ",",
+				EnableInterpolation: true,
+				InterpolateEnvKeys:  tt.enabled,
+			}
+			if _, err := SetOutput(cfg); err != nil {
+				t.Fatalf("SetOutput() unexpected error: %v", err)
+			}
+			content, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			if !strings.Contains(string(content), tt.expected) {
+				t.Errorf("output file = %q, want containing %q", content, tt.expected)
+			}
+		})
+	}
+}
+
 func TestSetEnvEmptyInput(t *testing.T) {
 	tmpDir := t.TempDir()
 	envFile := filepath.Join(tmpDir, "github_env")
@@ -509,6 +609,31 @@ func TestSetEnvWithJsonSupport(t *testing.T) {
 	}
 }
 
+func TestSetEnvJsonNullWithDefaults(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "github_env")
+	t.Setenv(githubEnvVar, envFile)
+	t.Setenv(config.EnvPairsInput, `CFG={"name":"web","none":null}`)
+	t.Setenv(config.JsonSupportInput, "true")
+
+	// Null leaves must not trip the default fail_on_empty
+	cfg := config.Load()
+	var err error
+	captureStdout(t, func() {
+		_, err = SetEnv(cfg)
+	})
+	if err != nil {
+		t.Fatalf("SetEnv() unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("Failed to read env file: %v", err)
+	}
+	if !strings.Contains(string(content), "CFG_none<<") || !strings.Contains(string(content), "\nnull\n") {
+		t.Errorf("SetEnv() file = %q, want CFG_none set to null", content)
+	}
+}
+
 func TestSetEnvWithAllowEmpty(t *testing.T) {
 	tmpDir := t.TempDir()
 	envFile := filepath.Join(tmpDir, "github_env")
@@ -725,3 +850,39 @@ func BenchmarkSetOutput(b *testing.B) {
 		SetOutput(cfg)
 	}
 }
+
+func TestSetStateWithFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "github_state")
+	t.Setenv(githubStateVar, stateFile)
+
+	cfg := &config.Config{
+		StateKeys:   "CACHE_KEY,STARTED_AT",
+		StateValues: "deps-abc123,1700000000",
+		Delimiter:   ",",
+	}
+	count, err := SetState(cfg)
+	if err != nil {
+		t.Fatalf("SetState() unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("SetState() count = %d, want 2", count)
+	}
+
+	content, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatalf("Failed to read state file: %v", err)
+	}
+	contentStr := string(content)
+	if !contains(contentStr, "CACHE_KEY") || !contains(contentStr, "deps-abc123") {
+		t.Errorf("SetState() file missing CACHE_KEY: %q", contentStr)
+	}
+	// Status keys are not saved as state
+	if contains(contentStr, statusKey) {
+		t.Errorf("SetState() file unexpectedly contains %s: %q", statusKey, contentStr)
+	}
+
+	count, err = SetState(&config.Config{Delimiter: ","})
+	if err != nil || count != 0 {
+		t.Errorf("SetState() with empty inputs = %d, %v, want 0, nil", count, err)
+	}
+}